@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/store"
+	"github.com/urfave/cli"
+)
+
+var apiKeyNameFlag = cli.StringFlag{
+	Name:  "name",
+	Usage: "name of the api key, used in logs and rate limiting",
+}
+
+var apiKeyCommand = cli.Command{
+	Name:  "apikey",
+	Usage: "manage the api keys of the restful server",
+	Subcommands: []cli.Command{
+		{
+			Name:   "create",
+			Usage:  "create an api key, the key is printed once and only its hash is stored",
+			Flags:  []cli.Flag{apiKeyNameFlag},
+			Action: createApiKey,
+		},
+		{
+			Name:   "list",
+			Usage:  "list the names of the api keys",
+			Action: listApiKeys,
+		},
+		{
+			Name:   "delete",
+			Usage:  "delete an api key, running servers drop it within a minute",
+			Flags:  []cli.Flag{apiKeyNameFlag},
+			Action: deleteApiKey,
+		},
+	},
+}
+
+func openStore(ctx *cli.Context) (*store.Client, error) {
+	configPath := ctx.GlobalString(config.GetFlagName(config.ConfigPathFlag))
+	if configPath != "" {
+		ConfigPath = configPath
+	}
+	servConfig, err := config.NewConfig(ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("parse config failed, err: %s", err)
+	}
+	return store.ConnectToDb(servConfig.DatabaseURL)
+}
+
+func createApiKey(ctx *cli.Context) error {
+	name := ctx.String(apiKeyNameFlag.Name)
+	if name == "" {
+		return fmt.Errorf("--%s is required", apiKeyNameFlag.Name)
+	}
+	db, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("rand.Read error: %s", err)
+	}
+	key := "wing_" + hex.EncodeToString(raw)
+	err = db.SaveApiKey(&store.ApiKey{
+		Hash:       restful.HashApiKey(key),
+		Name:       name,
+		CreateTime: uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		return fmt.Errorf("db.SaveApiKey error: %s", err)
+	}
+	fmt.Println(key)
+	return nil
+}
+
+func listApiKeys(ctx *cli.Context) error {
+	db, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	apiKeys, err := db.LoadApiKeys()
+	if err != nil {
+		return fmt.Errorf("db.LoadApiKeys error: %s", err)
+	}
+	for _, v := range apiKeys {
+		fmt.Printf("%s\t%s\n", v.Name, time.Unix(int64(v.CreateTime), 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func deleteApiKey(ctx *cli.Context) error {
+	name := ctx.String(apiKeyNameFlag.Name)
+	if name == "" {
+		return fmt.Errorf("--%s is required", apiKeyNameFlag.Name)
+	}
+	db, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	deleted, err := db.DeleteApiKey(name)
+	if err != nil {
+		return fmt.Errorf("db.DeleteApiKey error: %s", err)
+	}
+	if !deleted {
+		return fmt.Errorf("api key %s not found", name)
+	}
+	return nil
+}
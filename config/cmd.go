@@ -12,6 +12,18 @@ var (
 		Value: DEFAULT_LOG_LEVEL,
 	}
 
+	LogFormatFlag = cli.StringFlag{
+		Name:  "logformat",
+		Usage: "Write the logs as `<format>`, text: colored lines, json: json lines for log aggregators",
+		Value: "text",
+	}
+
+	ModeFlag = cli.StringFlag{
+		Name:  "mode",
+		Usage: "Run as `<mode>`, all: api and indexer, api: api only, indexer: indexer and scheduler only",
+		Value: MODE_ALL,
+	}
+
 	ConfigPathFlag = cli.StringFlag{
 		Name:  "cliconfig",
 		Usage: "Server config file `<path>`",
@@ -4,11 +4,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
 	DEFAULT_LOG_LEVEL        = 2
 	DEFAULT_CONFIG_FILE_NAME = "./config.json"
+
+	DEFAULT_MARKET_REFRESH_INTERVAL = 600
+	DEFAULT_HEALTH_MAX_LAG          = 30
+	DEFAULT_HEALTH_MAX_PRICE_AGE    = 3600
+	DEFAULT_SHUTDOWN_TIMEOUT        = 30
+	DEFAULT_LEADER_LEASE            = 15
+	DEFAULT_JOB_HISTORY_DAYS        = 7
+
+	// MODE_ALL runs the api and the indexer, MODE_API only serves the api from the database and MODE_INDEXER
+	// only runs the indexer and the scheduler
+	MODE_ALL     = "all"
+	MODE_API     = "api"
+	MODE_INDEXER = "indexer"
 )
 
 //Config object used by ontology-instance
@@ -28,6 +45,125 @@ type Config struct {
 	TokenDecimal       map[string]uint64 `json:"token_decimal"`
 	ScanInterval       uint64            `json:"scan_interval"`
 	SnapshotInterval   uint64            `json:"snapshot_interval"`
+	// seconds between market registry reloads, which is how newly listed markets are found
+	MarketRefreshInterval uint64           `json:"market_refresh_interval"`
+	WingLockAddress       []string         `json:"wing_lock_address"`
+	AdminToken            string           `json:"admin_token"`
+	AdminPort             uint64           `json:"admin_port"`
+	MetricsPort           uint64           `json:"metrics_port"`
+	Webhook               *WebhookConfig   `json:"webhook"`
+	Cache                 *CacheConfig     `json:"cache"`
+	RateLimit             *RateLimitConfig `json:"rate_limit"`
+	Health                *HealthConfig    `json:"health"`
+	// seconds to drain the http servers and to wait for the jobs and their writes on shutdown
+	ShutdownTimeout uint64            `json:"shutdown_timeout"`
+	Scheduler       *SchedulerConfig  `json:"scheduler"`
+	Leader          *LeaderConfig     `json:"leader"`
+	Log             *LogConfig        `json:"log"`
+	Events          *EventsConfig     `json:"events"`
+	Governance      *GovernanceConfig `json:"governance"`
+	Websocket       *WebsocketConfig  `json:"websocket"`
+}
+
+// CacheConfig holds the response cache TTL in seconds of each route, as registered like /api/v2/markets/:asset
+type CacheConfig struct {
+	Routes map[string]uint64 `json:"routes"`
+}
+
+// RateLimitConfig enables api keys and token bucket rate limiting per key and per ip. Routes map a route,
+// as registered like /api/v2/users/:address/overview, to its class, other routes are of the default class
+type RateLimitConfig struct {
+	RequireApiKey bool `json:"require_api_key"`
+	// take the client ip from X-Forwarded-For, only when running behind a trusted proxy. The client is the
+	// rightmost address which is not in trusted_proxies, the ips or cidrs of the proxies in front of the proxy
+	// connecting to the server
+	TrustProxy     bool                       `json:"trust_proxy"`
+	TrustedProxies []string                   `json:"trusted_proxies"`
+	Routes         map[string]string          `json:"routes"`
+	Classes        map[string]*RateLimitClass `json:"classes"`
+}
+
+// RateLimitClass holds the requests per second and burst of a route class, a zero rate is unlimited
+type RateLimitClass struct {
+	IpRate   float64 `json:"ip_rate"`
+	IpBurst  uint64  `json:"ip_burst"`
+	KeyRate  float64 `json:"key_rate"`
+	KeyBurst uint64  `json:"key_burst"`
+}
+
+type WebhookConfig struct {
+	Workers      uint64 `json:"workers"`
+	MaxAttempts  uint32 `json:"max_attempts"`
+	RetryBackoff uint64 `json:"retry_backoff"`
+	Timeout      uint64 `json:"timeout"`
+}
+
+// HealthConfig holds the readiness thresholds, the blocks the indexer may be behind the chain and the
+// seconds since the last successful price feed, zero values fall back to the defaults
+type HealthConfig struct {
+	MaxLag      uint32 `json:"max_lag"`
+	MaxPriceAge uint64 `json:"max_price_age"`
+}
+
+// EventsConfig holds the minimum amount in token units of the supply events indexed per asset name, so that
+// only large supplies are streamed. Supplies of assets without minimum are all indexed
+type EventsConfig struct {
+	MinSupply map[string]float64 `json:"min_supply"`
+}
+
+// WebsocketConfig holds the origins of the browser pages allowed to connect to the websocket, like
+// https://app.ont.io, or * for any. Without allowed origins only pages of the same host may connect
+type WebsocketConfig struct {
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// GovernanceConfig lays out the proposal notifications of the governance contract by notified name, as the
+// contract ABI defines them. Notifications without a layout are not indexed
+type GovernanceConfig struct {
+	Events map[string]*GovernanceEvent `json:"events"`
+}
+
+// GovernanceEvent holds the kind of a governance notification, propose, vote, execute or cancel, and its
+// fields in the order notified after the name: proposal_id, proposer, title, description, start_height,
+// end_height, voter, option or weight. Fields the indexer does not use are left empty
+type GovernanceEvent struct {
+	Kind   string   `json:"kind"`
+	Fields []string `json:"fields"`
+}
+
+// LogConfig rotates the log file ./Log/wing_LOG.log above max_size MB, gzips the rotated files when compress
+// is set and removes those older than max_age days or beyond the newest max_files, zero keeps them. The files
+// renamed by an external logrotate, which sends SIGHUP to reopen the log file, are pruned as well
+type LogConfig struct {
+	MaxSize  uint64 `json:"max_size"`
+	MaxAge   uint64 `json:"max_age"`
+	MaxFiles uint64 `json:"max_files"`
+	Compress bool   `json:"compress"`
+}
+
+// SchedulerConfig overrides the schedule of the scheduler jobs by name. The runs of the jobs are kept for
+// history_days days, 7 by default
+type SchedulerConfig struct {
+	Jobs        map[string]*JobConfig `json:"jobs"`
+	HistoryDays uint64                `json:"history_days"`
+}
+
+// JobConfig holds a cron expression of five fields, a descriptor like @daily or @every <duration>, all
+// evaluated in UTC. Each scheduled run is delayed by a random jitter of up to Jitter seconds. Disabled jobs
+// only run when triggered
+type JobConfig struct {
+	Spec       string `json:"spec"`
+	Jitter     uint64 `json:"jitter"`
+	Disabled   bool   `json:"disabled"`
+	RunOnStart bool   `json:"run_on_start"`
+}
+
+// LeaderConfig enables the leader election of replicas sharing the database, only the leader runs the
+// indexer and the scheduler. The lease in seconds bounds the failover time, the writers of a leader drain
+// within half of it instead of the shutdown timeout. The id defaults to host-pid
+type LeaderConfig struct {
+	Lease uint64 `json:"lease"`
+	Id    string `json:"id"`
 }
 
 func NewConfig(fileName string) (*Config, error) {
@@ -42,3 +178,57 @@ func NewConfig(fileName string) (*Config, error) {
 	}
 	return cfg, nil
 }
+
+// settings applied by Reload, the other ones need a restart
+var reloadable = map[string]bool{
+	"asset_map":               true,
+	"icon_map":                true,
+	"oracle_map":              true,
+	"token_decimal":           true,
+	"scan_interval":           true,
+	"market_refresh_interval": true,
+	"wing_lock_address":       true,
+}
+
+// Holder publishes the config to the components, which read it with Get. A published config is never
+// modified: Reload publishes a new one, so that readers see either the old or the new config as a whole
+type Holder struct {
+	value atomic.Value
+	// one reload at a time
+	lock sync.Mutex
+}
+
+func NewHolder(cfg *Config) *Holder {
+	holder := &Holder{}
+	holder.value.Store(cfg)
+	return holder
+}
+
+func (this *Holder) Get() *Config {
+	return this.value.Load().(*Config)
+}
+
+// Reload reads fileName again and publishes the current config with its reloadable settings, it returns
+// the other settings which changed and need a restart
+func (this *Holder) Reload(fileName string) ([]string, error) {
+	loaded, err := NewConfig(fileName)
+	if err != nil {
+		return nil, err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	cfg := *this.Get()
+	restart := make([]string, 0)
+	next := reflect.ValueOf(&cfg).Elem()
+	source := reflect.ValueOf(loaded).Elem()
+	for i := 0; i < next.NumField(); i++ {
+		name := strings.Split(next.Type().Field(i).Tag.Get("json"), ",")[0]
+		if reloadable[name] {
+			next.Field(i).Set(source.Field(i))
+		} else if !reflect.DeepEqual(next.Field(i).Interface(), source.Field(i).Interface()) {
+			restart = append(restart, name)
+		}
+	}
+	this.value.Store(&cfg)
+	return restart, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	file, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestHolderReload(t *testing.T) {
+	first := writeConfig(t, `{"port": 10337, "asset_map": {"a": "pUSDT"}, "scan_interval": 2}`)
+	defer os.Remove(first)
+	second := writeConfig(t, `{"port": 10338, "asset_map": {"a": "pUSDT", "b": "pDAI"}, "scan_interval": 3}`)
+	defer os.Remove(second)
+
+	cfg, err := NewConfig(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := NewHolder(cfg)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			current := holder.Get()
+			for range current.AssetMap {
+			}
+			if (len(current.AssetMap) == 2) != (current.ScanInterval == 3) {
+				t.Errorf("config seen half reloaded: %+v", current)
+				return
+			}
+		}
+	}()
+	restart, err := holder.Reload(second)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restart) != 1 || restart[0] != "port" {
+		t.Errorf("unexpected restart list %v", restart)
+	}
+	current := holder.Get()
+	if len(current.AssetMap) != 2 || current.ScanInterval != 3 || current.Port != 10337 {
+		t.Errorf("unexpected config %+v", current)
+	}
+	if len(cfg.AssetMap) != 1 || cfg.ScanInterval != 2 {
+		t.Errorf("published config was modified: %+v", cfg)
+	}
+}
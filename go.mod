@@ -3,10 +3,14 @@ module github.com/siovanus/wingServer
 go 1.14
 
 require (
+	github.com/gorilla/websocket v1.4.2
+	github.com/graph-gophers/graphql-go v1.3.0
 	github.com/jinzhu/gorm v1.9.16
 	github.com/ontio/ontology v1.11.0
 	github.com/ontio/ontology-go-sdk v1.11.8
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.7.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/urfave/cli v1.22.4
 	gopkg.in/gormigrate.v1 v1.6.0
 )
@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/scheduler"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	DEFAULT_RUNS_LIMIT = 20
+	MAX_RUNS_LIMIT     = 500
+)
+
+// Jobs is implemented by scheduler.Scheduler
+type Jobs interface {
+	Jobs() []*common.ScheduledJob
+	Trigger(name string) error
+	Runs(name string, limit int) ([]store.JobRun, error)
+}
+
+// HandleJobs mounts the endpoints listing, triggering and showing the history of the scheduler jobs
+func (this *Server) HandleJobs(jobs Jobs) {
+	this.router.Get(common.ADMINJOBS, func(w http.ResponseWriter, r *http.Request) {
+		restful.WriteResponse(w, http.StatusOK, restful.SUCCESS, "", jobs.Jobs())
+	})
+	this.router.Post(common.ADMINJOBRUN, func(w http.ResponseWriter, r *http.Request) {
+		name := restful.Param(r, "name")
+		log.FromContext(r.Context()).WithFields(log.Fields{log.FIELD_ACTION: name}).Infof("admin: trigger job %s", name)
+		switch err := jobs.Trigger(name); err {
+		case nil:
+			restful.WriteResponse(w, http.StatusAccepted, restful.SUCCESS, "", nil)
+		case scheduler.ErrUnknownJob:
+			restful.WriteResponse(w, http.StatusNotFound, restful.INVALID_PARAMS, err.Error(), nil)
+		case scheduler.ErrRunning, scheduler.ErrNotRunning:
+			restful.WriteResponse(w, http.StatusConflict, restful.FAILED, err.Error(), nil)
+		default:
+			this.respond(w, r, nil, err)
+		}
+	})
+	this.router.Get(common.ADMINJOBRUNS, func(w http.ResponseWriter, r *http.Request) {
+		limit := DEFAULT_RUNS_LIMIT
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > MAX_RUNS_LIMIT {
+				restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, "invalid limit "+v, nil)
+				return
+			}
+			limit = n
+		}
+		runs, err := jobs.Runs(restful.Param(r, "name"), limit)
+		if err == scheduler.ErrUnknownJob {
+			restful.WriteResponse(w, http.StatusNotFound, restful.INVALID_PARAMS, err.Error(), nil)
+			return
+		}
+		this.respond(w, r, runs, err)
+	})
+}
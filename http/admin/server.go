@@ -0,0 +1,181 @@
+// Package admin serves the operational endpoints on their own port, every request needs the admin token
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+)
+
+// Admin is implemented by service.Service
+type Admin interface {
+	RunSnapshotDaily() error
+	RunSnapshotMinute() error
+	RefreshAddress(address string) error
+	SetTrackHeight(height uint32) error
+	RewindTrackHeight(blocks uint32) error
+	ReloadConfig(fileName string) (*common.ConfigReload, error)
+	IndexerStatus() (*common.IndexerStatus, error)
+}
+
+type Server struct {
+	admin      Admin
+	port       uint64
+	configPath string
+	router     *restful.Router
+	listener   net.Listener
+	server     *http.Server
+}
+
+// NewServer creates the admin server, requests have to carry "Authorization: Bearer <token>"
+func NewServer(admin Admin, port uint64, token string, configPath string) *Server {
+	server := NewReadOnlyServer(port, token)
+	server.admin = admin
+	server.configPath = configPath
+	server.router.Post(common.ADMINSNAPSHOTDAILY, server.snapshotDaily)
+	server.router.Post(common.ADMINSNAPSHOTMINUTE, server.snapshotMinute)
+	server.router.Post(common.ADMINACCOUNTREFRESH, server.refreshAccount)
+	server.router.Put(common.ADMINTRACKHEIGHT, server.setTrackHeight)
+	server.router.Post(common.ADMINCONFIGRELOAD, server.reloadConfig)
+	server.router.Get(common.ADMININDEXER, server.indexerStatus)
+	return server
+}
+
+// NewReadOnlyServer creates an admin server without the endpoints writing the database, for the api
+// replicas. The read endpoints of other components are mounted with Handle
+func NewReadOnlyServer(port uint64, token string) *Server {
+	server := &Server{
+		port:   port,
+		router: restful.NewRouter(),
+	}
+	server.router.Use(restful.RequestLog, authorize(token))
+	return server
+}
+
+// Handle mounts the admin endpoints of other components
+func (this *Server) Handle(method string, path string, handler http.HandlerFunc) {
+	this.router.Handle(method, path, handler)
+}
+
+func (this *Server) Start() error {
+	var err error
+	this.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", this.port))
+	if err != nil {
+		log.Errorf("admin server net.Listen error: %s", err)
+		return err
+	}
+	log.Infof("admin server start, listen 0.0.0.0:%d", this.port)
+	this.server = &http.Server{Handler: this.router}
+	err = this.server.Serve(this.listener)
+	if err != nil && err != http.ErrServerClosed {
+		log.Errorf("admin server Serve error: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (this *Server) Stop() {
+	this.Shutdown(context.Background())
+}
+
+// Shutdown stops accepting connections and waits for the running requests until ctx is done
+func (this *Server) Shutdown(ctx context.Context) error {
+	if this.server == nil {
+		return nil
+	}
+	return this.server.Shutdown(ctx)
+}
+
+func (this *Server) snapshotDaily(w http.ResponseWriter, r *http.Request) {
+	log.FromContext(r.Context()).Infof("admin: snapshot daily")
+	this.respond(w, r, nil, this.admin.RunSnapshotDaily())
+}
+
+func (this *Server) snapshotMinute(w http.ResponseWriter, r *http.Request) {
+	log.FromContext(r.Context()).Infof("admin: snapshot minute")
+	this.respond(w, r, nil, this.admin.RunSnapshotMinute())
+}
+
+func (this *Server) refreshAccount(w http.ResponseWriter, r *http.Request) {
+	address := restful.Param(r, "address")
+	log.FromContext(r.Context()).WithFields(log.Fields{log.FIELD_ACCOUNT: address}).Infof("admin: refresh account %s",
+		address)
+	this.respond(w, r, nil, this.admin.RefreshAddress(address))
+}
+
+func (this *Server) setTrackHeight(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	req := &common.TrackHeightRequest{}
+	if err == nil {
+		err = json.Unmarshal(body, req)
+	}
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.ILLEGAL_DATAFORMAT, err.Error(), nil)
+		return
+	}
+	if (req.Height == 0) == (req.Rewind == 0) {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, "one of Height and Rewind is required", nil)
+		return
+	}
+	if req.Rewind != 0 {
+		log.FromContext(r.Context()).Infof("admin: rewind track height by %d blocks", req.Rewind)
+		err = this.admin.RewindTrackHeight(req.Rewind)
+	} else {
+		log.FromContext(r.Context()).Infof("admin: set track height to %d", req.Height)
+		err = this.admin.SetTrackHeight(req.Height)
+	}
+	if err != nil {
+		this.respond(w, r, nil, err)
+		return
+	}
+	restful.WriteResponse(w, http.StatusAccepted, restful.SUCCESS, "", nil)
+}
+
+func (this *Server) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	log.FromContext(r.Context()).Infof("admin: reload config %s", this.configPath)
+	reload, err := this.admin.ReloadConfig(this.configPath)
+	this.respond(w, r, reload, err)
+}
+
+func (this *Server) indexerStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := this.admin.IndexerStatus()
+	this.respond(w, r, status, err)
+}
+
+// respond answers 400 for a *common.ApiError and 500 for other errors
+func (this *Server) respond(w http.ResponseWriter, r *http.Request, result interface{}, err error) {
+	if err == nil {
+		restful.WriteResponse(w, http.StatusOK, restful.SUCCESS, "", result)
+		return
+	}
+	if apiErr, ok := err.(*common.ApiError); ok {
+		restful.WriteResponse(w, apiErr.Status, restful.INVALID_PARAMS, apiErr.Detail, nil)
+		return
+	}
+	log.FromContext(r.Context()).Errorf("admin request error: %s", err)
+	restful.WriteResponse(w, http.StatusInternalServerError, restful.INTERNAL_ERROR, err.Error(), nil)
+}
+
+func authorize(token string) restful.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") ||
+				subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+				restful.WriteResponse(w, http.StatusUnauthorized, restful.FAILED, "unauthorized", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
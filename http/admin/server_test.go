@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/scheduler"
+	"github.com/siovanus/wingServer/store"
+)
+
+type fakeAdmin struct {
+	height uint32
+	rewind uint32
+}
+
+func (this *fakeAdmin) RunSnapshotDaily() error  { return nil }
+func (this *fakeAdmin) RunSnapshotMinute() error { return nil }
+
+func (this *fakeAdmin) RefreshAddress(address string) error {
+	return common.BadRequest("invalid address " + address)
+}
+
+func (this *fakeAdmin) SetTrackHeight(height uint32) error {
+	this.height = height
+	return nil
+}
+
+func (this *fakeAdmin) RewindTrackHeight(blocks uint32) error {
+	this.rewind = blocks
+	return nil
+}
+
+func (this *fakeAdmin) ReloadConfig(fileName string) (*common.ConfigReload, error) {
+	return &common.ConfigReload{}, nil
+}
+
+func (this *fakeAdmin) IndexerStatus() (*common.IndexerStatus, error) {
+	return &common.IndexerStatus{TrackHeight: 10, ChainHeight: 12, Lag: 2}, nil
+}
+
+func request(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, r)
+	return w
+}
+
+func TestAuthorization(t *testing.T) {
+	server := NewServer(&fakeAdmin{}, 0, "secret", "")
+	if w := request(server, http.MethodGet, common.ADMININDEXER, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("request without token answered %d", w.Code)
+	}
+	if w := request(server, http.MethodGet, common.ADMININDEXER, "wrong", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("request with wrong token answered %d", w.Code)
+	}
+	w := request(server, http.MethodGet, common.ADMININDEXER, "secret", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Lag":2`) {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReadOnlyServer(t *testing.T) {
+	server := NewReadOnlyServer(0, "secret")
+	server.Handle(http.MethodGet, common.ADMINSTATUS, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	if w := request(server, http.MethodGet, common.ADMINSTATUS, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("request without token answered %d", w.Code)
+	}
+	if w := request(server, http.MethodGet, common.ADMINSTATUS, "secret", ""); w.Code != http.StatusOK {
+		t.Errorf("status answered %d", w.Code)
+	}
+	if w := request(server, http.MethodPost, common.ADMINSNAPSHOTDAILY, "secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("write endpoint answered %d", w.Code)
+	}
+}
+
+func TestTrackHeight(t *testing.T) {
+	admin := &fakeAdmin{}
+	server := NewServer(admin, 0, "secret", "")
+	if w := request(server, http.MethodPut, common.ADMINTRACKHEIGHT, "secret", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty request answered %d", w.Code)
+	}
+	if w := request(server, http.MethodPut, common.ADMINTRACKHEIGHT, "secret", `{"Height":5,"Rewind":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("ambiguous request answered %d", w.Code)
+	}
+	if w := request(server, http.MethodPut, common.ADMINTRACKHEIGHT, "secret", `{"Height":5}`); w.Code != http.StatusAccepted || admin.height != 5 {
+		t.Errorf("unexpected response %d, height %d", w.Code, admin.height)
+	}
+	if w := request(server, http.MethodPut, common.ADMINTRACKHEIGHT, "secret", `{"Rewind":3}`); w.Code != http.StatusAccepted || admin.rewind != 3 {
+		t.Errorf("unexpected response %d, rewind %d", w.Code, admin.rewind)
+	}
+}
+
+func TestApiErrorStatus(t *testing.T) {
+	server := NewServer(&fakeAdmin{}, 0, "secret", "")
+	w := request(server, http.MethodPost, "/api/v1/admin/accounts/bad/refresh", "secret", "")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid address bad") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+type fakeJobs struct{}
+
+func (fakeJobs) Jobs() []*common.ScheduledJob {
+	return []*common.ScheduledJob{{Name: "snapshot_daily", Spec: "@daily"}}
+}
+
+func (fakeJobs) Trigger(name string) error {
+	switch name {
+	case "snapshot_daily":
+		return nil
+	case "snapshot_minute":
+		return scheduler.ErrRunning
+	}
+	return scheduler.ErrUnknownJob
+}
+
+func (fakeJobs) Runs(name string, limit int) ([]store.JobRun, error) {
+	return []store.JobRun{{Job: name, Trigger: scheduler.TRIGGER_MANUAL}}, nil
+}
+
+func TestJobs(t *testing.T) {
+	server := NewServer(&fakeAdmin{}, 0, "secret", "")
+	server.HandleJobs(fakeJobs{})
+	if w := request(server, http.MethodGet, common.ADMINJOBS, "secret", ""); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `"Spec":"@daily"`) {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	cases := map[string]int{"snapshot_daily": http.StatusAccepted, "snapshot_minute": http.StatusConflict,
+		"unknown": http.StatusNotFound}
+	for name, status := range cases {
+		if w := request(server, http.MethodPost, "/api/v1/admin/jobs/"+name+"/run", "secret", ""); w.Code != status {
+			t.Errorf("trigger %s answered %d, expected %d", name, w.Code, status)
+		}
+	}
+	if w := request(server, http.MethodGet, "/api/v1/admin/jobs/snapshot_daily/runs?limit=0", "secret", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit answered %d", w.Code)
+	}
+	if w := request(server, http.MethodGet, "/api/v1/admin/jobs/snapshot_daily/runs", "secret", ""); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `"Trigger":"manual"`) {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
@@ -19,6 +19,38 @@ const (
 	CLAIMWING       = "/api/v1/claimwing"
 	LIQUIDATIONLIST = "/api/v1/liquidationlist"
 	WINGAPYS        = "/api/v1/wingapys"
+
+	EMISSIONPROJECTION = "/api/v1/emissionprojection"
+	WINGSUPPLY         = "/api/v1/wingsupply"
+	WINGHOLDERS        = "/api/v1/wingholders"
+	WINGEARNINGS       = "/api/v1/wingearnings"
+	WINGCLAIMHISTORY   = "/api/v1/wingclaimhistory"
+	GOVPROPOSALS       = "/api/v1/govproposals"
+	GOVPROPOSALDETAIL  = "/api/v1/govproposaldetail"
+
+	WEBSOCKET   = "/api/v1/ws"
+	EVENTSTREAM = "/api/v1/events"
+	BATCH       = "/api/v1/batch"
+	GRAPHQL     = "/api/graphql"
+
+	HEALTHZ = "/healthz"
+	READYZ  = "/readyz"
+	METRICS = "/metrics"
+
+	ADMINWEBHOOKS           = "/api/v1/admin/webhooks"
+	ADMINWEBHOOK            = "/api/v1/admin/webhooks/:id"
+	ADMINWEBHOOKDEADLETTERS = "/api/v1/admin/webhookdeadletters"
+	ADMINWEBHOOKDEADLETTER  = "/api/v1/admin/webhookdeadletters/:id"
+	ADMINSNAPSHOTDAILY      = "/api/v1/admin/snapshots/daily"
+	ADMINSNAPSHOTMINUTE     = "/api/v1/admin/snapshots/minute"
+	ADMINACCOUNTREFRESH     = "/api/v1/admin/accounts/:address/refresh"
+	ADMINTRACKHEIGHT        = "/api/v1/admin/trackheight"
+	ADMINCONFIGRELOAD       = "/api/v1/admin/config/reload"
+	ADMININDEXER            = "/api/v1/admin/indexer"
+	ADMINSTATUS             = "/api/v1/admin/status"
+	ADMINJOBS               = "/api/v1/admin/jobs"
+	ADMINJOBRUN             = "/api/v1/admin/jobs/:name/run"
+	ADMINJOBRUNS            = "/api/v1/admin/jobs/:name/runs"
 )
 
 const (
@@ -40,6 +72,55 @@ const (
 	ACTION_CLAIMWING       = "claimwing"
 	ACTION_LIQUIDATIONLIST = "liquidationlist"
 	ACTION_WINGAPYS        = "wingapys"
+
+	ACTION_EMISSIONPROJECTION = "emissionprojection"
+	ACTION_WINGSUPPLY         = "wingsupply"
+	ACTION_WINGHOLDERS        = "wingholders"
+	ACTION_WINGEARNINGS       = "wingearnings"
+	ACTION_WINGCLAIMHISTORY   = "wingclaimhistory"
+	ACTION_GOVPROPOSALS       = "govproposals"
+	ACTION_GOVPROPOSALDETAIL  = "govproposaldetail"
+)
+
+const (
+	PROPOSAL_STATUS_ACTIVE   = "active"
+	PROPOSAL_STATUS_EXECUTED = "executed"
+	PROPOSAL_STATUS_CANCELED = "canceled"
+)
+
+const (
+	TOPIC_MARKET    = "market"
+	TOPIC_PRICE     = "price"
+	TOPIC_ACCOUNT   = "account"
+	TOPIC_EVENT     = "event"
+	TOPIC_FLASHPOOL = "flashpool"
+)
+
+const (
+	EVENT_TYPE_SUPPLY      = "supply"
+	EVENT_TYPE_WITHDRAW    = "withdraw"
+	EVENT_TYPE_BORROW      = "borrow"
+	EVENT_TYPE_REPAY       = "repay"
+	EVENT_TYPE_LIQUIDATION = "liquidation"
+)
+
+const (
+	WEBHOOK_TRIGGER_BORROW_LIMIT = "borrow_limit"
+	WEBHOOK_TRIGGER_PRICE_MOVE   = "price_move"
+	WEBHOOK_TRIGGER_LIQUIDATION  = "liquidation"
+)
+
+const (
+	PROJECTION_INTERVAL_DAY   = "day"
+	PROJECTION_INTERVAL_MONTH = "month"
+)
+
+const (
+	// the unclaimed wing of a market is split by the wing portion of each side times the share of the side
+	// held by the account now, not by what each side accrued
+	WING_SPLIT_BALANCE_SHARE = "balance_share"
+	// only the claims indexed by this server are counted, not those made before it started indexing
+	WING_CLAIMED_INDEXED = "indexed"
 )
 
 type Response struct {
@@ -49,6 +130,12 @@ type Response struct {
 	Result interface{} `json:"result"`
 }
 
+// BatchRequest is an entry of a batch call, Params are the parameters the action takes on its own route
+type BatchRequest struct {
+	Action string                 `json:"action"`
+	Params map[string]interface{} `json:"params"`
+}
+
 type AssetPriceRequest struct {
 	Id    string
 	Asset string
@@ -79,6 +166,14 @@ type GovBanner struct {
 	Distributed string
 }
 
+// GovPool is the WING governance pool, GovBanner and GovBannerOverview in one
+type GovPool struct {
+	Daily       string
+	Distributed string
+	Remain20    string
+	Remain80    string
+}
+
 type FlashPoolMarketDistribution struct {
 	FlashPoolMarketDistribution []*Distribution
 }
@@ -121,8 +216,10 @@ type UserFlashPoolOverviewResponse struct {
 }
 
 type UserFlashPoolOverview struct {
-	BorrowLimit string
-	NetApy      string
+	BorrowLimit       string
+	NetApy            string
+	NetApyWithRewards string
+	YearlyYield       string
 
 	CurrentSupply    []*Supply
 	CurrentBorrow    []*Borrow
@@ -136,6 +233,7 @@ type Supply struct {
 	Name                  string
 	SupplyBalance         string
 	Apy                   string
+	RewardApy             string
 	CollateralFactor      string
 	SupplyDistribution    string
 	BorrowDistribution    string
@@ -149,6 +247,7 @@ type Borrow struct {
 	Name                  string
 	BorrowBalance         string
 	Apy                   string
+	RewardApy             string
 	Limit                 string
 	CollateralFactor      string
 	SupplyDistribution    string
@@ -162,6 +261,7 @@ type Insurance struct {
 	Name                  string
 	InsuranceBalance      string
 	Apy                   string
+	RewardApy             string
 	CollateralFactor      string
 	SupplyDistribution    string
 	BorrowDistribution    string
@@ -263,3 +363,280 @@ type Reserve struct {
 	ReserveBalance string
 	ReserveDollar  string
 }
+
+type EmissionProjectionRequest struct {
+	Interval  string
+	PerMarket string
+}
+
+type EmissionProjection struct {
+	Interval    string
+	EndTime     uint64
+	TotalAmount string
+	Projection  []*EmissionPoint
+}
+
+type EmissionPoint struct {
+	Timestamp   uint64
+	Distributed string
+	DailyRate   string
+	Remaining   string
+	Markets     []*MarketEmission `json:",omitempty"`
+}
+
+type MarketEmission struct {
+	Name           string
+	Daily          string
+	SupplyDaily    string
+	BorrowDaily    string
+	InsuranceDaily string
+}
+
+type WingSpeed struct {
+	Name             string
+	WingSpeed        string
+	SupplyPortion    string
+	BorrowPortion    string
+	InsurancePortion string
+}
+
+type WingSupply struct {
+	TotalSupply       string
+	CirculatingSupply string
+	LockedSupply      string
+	LockedAddress     []*WingHolder
+}
+
+type WingHoldersRequest struct {
+	Limit string
+}
+
+type WingHolders struct {
+	HolderCount uint64
+	Holders     []*WingHolder
+}
+
+type WingHolder struct {
+	Rank    uint64 `json:",omitempty"`
+	Address string
+	Balance string
+	Share   string
+}
+
+type WingEarningsRequest struct {
+	Id      string
+	Address string
+}
+
+type WingEarningsResponse struct {
+	Id           string
+	Address      string
+	WingEarnings *WingEarnings
+}
+
+type WingEarnings struct {
+	Earned    string
+	Claimed   string
+	Unclaimed string
+	Markets   []*MarketWingEarned
+	// SplitMethod tells how the unclaimed wing of a market is split between its sides
+	SplitMethod string
+	// ClaimedScope tells which claims Claimed counts
+	ClaimedScope string
+}
+
+type MarketWingEarned struct {
+	Icon            string
+	Name            string
+	SupplyEarned    string
+	BorrowEarned    string
+	InsuranceEarned string
+	Total           string
+}
+
+type WingClaimHistoryRequest struct {
+	Id      string
+	Address string
+	Offset  string
+	Limit   string
+}
+
+type WingClaimHistoryResponse struct {
+	Id               string
+	Address          string
+	WingClaimHistory *WingClaimHistory
+}
+
+type WingClaimHistory struct {
+	Total  uint64
+	Claims []*WingClaim
+}
+
+type WingClaim struct {
+	TxHash    string
+	Height    uint32
+	Timestamp uint32
+	Amount    string
+}
+
+type GovProposalsRequest struct {
+	Status string
+	Offset string
+	Limit  string
+}
+
+type GovProposals struct {
+	Total     uint64
+	Proposals []*GovProposal
+}
+
+type GovProposal struct {
+	ProposalId   string
+	Proposer     string
+	Title        string
+	Description  string
+	StartHeight  uint32
+	EndHeight    uint32
+	Status       string
+	CreateHeight uint32
+	CreateTxHash string
+	UpdateHeight uint32
+	UpdateTxHash string
+}
+
+type GovProposalDetailRequest struct {
+	ProposalId string
+}
+
+type GovProposalDetail struct {
+	Proposal    *GovProposal
+	TotalWeight string
+	Tallies     []*VoteTally
+	Votes       []*GovVote
+}
+
+type VoteTally struct {
+	Option    string
+	VoteCount uint64
+	Weight    string
+	Share     string
+}
+
+type GovVote struct {
+	Voter  string
+	Option string
+	Weight string
+	Height uint32
+	TxHash string
+}
+
+// PricePoint is the price of an asset read from the oracle after its update at a block height
+type PricePoint struct {
+	Height uint32
+	TxHash string
+	Price  string
+}
+
+type Price struct {
+	Name  string
+	Price string
+}
+
+type AccountUpdate struct {
+	Address   string
+	Positions []*Position
+}
+
+type Position struct {
+	Name             string
+	Icon             string
+	SupplyBalance    string
+	BorrowBalance    string
+	InsuranceBalance string
+	IfCollateral     bool
+}
+
+// TrackHeightRequest moves the indexer to Height, or Rewind blocks back, blocks after it are indexed again
+type TrackHeightRequest struct {
+	Height uint32
+	Rewind uint32
+}
+
+// IndexerStatus shows how far the indexer is behind the chain, QueueDepth counts the writes still running
+// in background
+type IndexerStatus struct {
+	TrackHeight uint32
+	ChainHeight uint32
+	Lag         uint32
+	QueueDepth  int64
+}
+
+// background jobs of the service
+const (
+	JOB_SNAPSHOT_DAILY    = "snapshot_daily"
+	JOB_FLASHPOOL_MARKETS = "flashpool_markets"
+	JOB_WING_APY          = "wing_apy"
+	JOB_PRICE_FEED        = "price_feed"
+	JOB_TRACK_EVENT       = "track_event"
+	JOB_MARKET_REFRESH    = "market_refresh"
+)
+
+// ScheduledJob is a job of the scheduler, times are unix seconds and the last duration is in seconds
+type ScheduledJob struct {
+	Name         string
+	Spec         string
+	Jitter       uint64
+	Disabled     bool
+	Running      bool
+	NextRun      uint64
+	LastRun      uint64
+	LastDuration float64
+	LastError    string
+}
+
+// JobStatus is the outcome of the runs of a background job, times are unix seconds
+type JobStatus struct {
+	Runs          uint64
+	Failures      uint64
+	LastRun       uint64
+	LastDuration  float64 // seconds
+	LastSuccess   uint64
+	LastError     string
+	LastErrorTime uint64
+}
+
+// ConfigReload lists the changed settings which are only applied after a restart
+type ConfigReload struct {
+	Restart []string
+}
+
+type WebhookRequest struct {
+	Url       string
+	Secret    string
+	Trigger   string
+	Account   string
+	Asset     string
+	Threshold string
+}
+
+type WebhookPayload struct {
+	DeliveryId string
+	WebhookId  uint64
+	Trigger    string
+	Timestamp  uint64
+	Data       interface{}
+}
+
+type BorrowLimitAlert struct {
+	Address         string
+	BorrowLimitUsed string
+	Threshold       string
+}
+
+type PriceMoveAlert struct {
+	Asset          string
+	Price          string
+	ReferencePrice string
+	Change         string
+	Threshold      string
+}
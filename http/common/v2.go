@@ -0,0 +1,209 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	V2MARKETS           = "/api/v2/markets"
+	V2MARKET            = "/api/v2/markets/:asset"
+	V2PRICES            = "/api/v2/prices"
+	V2PRICE             = "/api/v2/prices/:asset"
+	V2RESERVES          = "/api/v2/reserves"
+	V2USEROVERVIEW      = "/api/v2/users/:address/overview"
+	V2USERLIQUIDATIONS  = "/api/v2/users/:address/liquidations"
+	V2USERWINGEARNINGS  = "/api/v2/users/:address/wing"
+	V2USERWINGCLAIMS    = "/api/v2/users/:address/claims"
+	V2GOVERNANCE        = "/api/v2/governance"
+	V2GOVPROPOSALS      = "/api/v2/governance/proposals"
+	V2GOVPROPOSALDETAIL = "/api/v2/governance/proposals/:id"
+
+	V2PREFIX = "/api/v2/"
+
+	OPENAPI = "/api/openapi.json"
+)
+
+// ApiError is returned by v2 handlers to answer with a specific HTTP status
+type ApiError struct {
+	Status int
+	Detail string
+}
+
+func (this *ApiError) Error() string {
+	return this.Detail
+}
+
+func BadRequest(detail string) *ApiError {
+	return &ApiError{Status: http.StatusBadRequest, Detail: detail}
+}
+
+func Conflict(detail string) *ApiError {
+	return &ApiError{Status: http.StatusConflict, Detail: detail}
+}
+
+func NotFound(detail string) *ApiError {
+	return &ApiError{Status: http.StatusNotFound, Detail: detail}
+}
+
+// Problem is an RFC 7807 error body
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+type ApiWingClaimPage struct {
+	Total  uint64          `json:"total"`
+	Offset uint64          `json:"offset"`
+	Items  []*ApiWingClaim `json:"items"`
+}
+
+type ApiProposalPage struct {
+	Total  uint64         `json:"total"`
+	Offset uint64         `json:"offset"`
+	Items  []*ApiProposal `json:"items"`
+}
+
+type ApiMarket struct {
+	Asset            string         `json:"asset"`
+	Icon             string         `json:"icon"`
+	CollateralFactor *json.Number   `json:"collateralFactor"`
+	Supply           *ApiMarketSide `json:"supply"`
+	Borrow           *ApiMarketSide `json:"borrow"`
+	Insurance        *ApiMarketSide `json:"insurance"`
+}
+
+type ApiMarketSide struct {
+	Total            *json.Number `json:"total"`
+	TotalUsd         *json.Number `json:"totalUsd"`
+	Apy              *json.Number `json:"apy"`
+	WingApy          *json.Number `json:"wingApy"`
+	WingDistribution *json.Number `json:"wingDistribution"`
+}
+
+type ApiPrice struct {
+	Asset string       `json:"asset"`
+	Price *json.Number `json:"price"`
+}
+
+type ApiReserves struct {
+	Total    *json.Number  `json:"total"`
+	Reserves []*ApiReserve `json:"reserves"`
+}
+
+type ApiReserve struct {
+	Asset         string       `json:"asset"`
+	Icon          string       `json:"icon"`
+	ReserveFactor *json.Number `json:"reserveFactor"`
+	Reserve       *json.Number `json:"reserve"`
+	ReserveUsd    *json.Number `json:"reserveUsd"`
+}
+
+type ApiUserOverview struct {
+	Address           string         `json:"address"`
+	BorrowLimit       *json.Number   `json:"borrowLimit"`
+	NetApy            *json.Number   `json:"netApy"`
+	NetApyWithRewards *json.Number   `json:"netApyWithRewards"`
+	YearlyYield       *json.Number   `json:"yearlyYield"`
+	Supplies          []*ApiPosition `json:"supplies"`
+	Borrows           []*ApiPosition `json:"borrows"`
+	Insurances        []*ApiPosition `json:"insurances"`
+}
+
+type ApiPosition struct {
+	Asset      string       `json:"asset"`
+	Icon       string       `json:"icon"`
+	Balance    *json.Number `json:"balance"`
+	Apy        *json.Number `json:"apy"`
+	RewardApy  *json.Number `json:"rewardApy"`
+	WingEarned *json.Number `json:"wingEarned"`
+	Limit      *json.Number `json:"limit,omitempty"`
+	Collateral bool         `json:"collateral,omitempty"`
+}
+
+type ApiLiquidation struct {
+	Asset           string           `json:"asset"`
+	Icon            string           `json:"icon"`
+	BorrowLimitUsed *json.Number     `json:"borrowLimitUsed"`
+	Borrow          *json.Number     `json:"borrow"`
+	BorrowUsd       *json.Number     `json:"borrowUsd"`
+	CollateralUsd   *json.Number     `json:"collateralUsd"`
+	Collateral      []*ApiCollateral `json:"collateral"`
+}
+
+type ApiCollateral struct {
+	Asset      string       `json:"asset"`
+	Icon       string       `json:"icon"`
+	Balance    *json.Number `json:"balance"`
+	BalanceUsd *json.Number `json:"balanceUsd"`
+}
+
+type ApiWingEarnings struct {
+	Earned       *json.Number           `json:"earned"`
+	Claimed      *json.Number           `json:"claimed"`
+	Unclaimed    *json.Number           `json:"unclaimed"`
+	Markets      []*ApiMarketWingEarned `json:"markets"`
+	SplitMethod  string                 `json:"splitMethod"`
+	ClaimedScope string                 `json:"claimedScope"`
+}
+
+type ApiMarketWingEarned struct {
+	Asset     string       `json:"asset"`
+	Supply    *json.Number `json:"supply"`
+	Borrow    *json.Number `json:"borrow"`
+	Insurance *json.Number `json:"insurance"`
+	Total     *json.Number `json:"total"`
+}
+
+type ApiWingClaim struct {
+	TxHash    string       `json:"txHash"`
+	Height    uint32       `json:"height"`
+	Timestamp uint32       `json:"timestamp"`
+	Amount    *json.Number `json:"amount"`
+}
+
+type ApiGovernance struct {
+	Remain20    *json.Number `json:"remain20"`
+	Remain80    *json.Number `json:"remain80"`
+	Daily       *json.Number `json:"daily"`
+	Distributed *json.Number `json:"distributed"`
+}
+
+type ApiProposal struct {
+	Id           string `json:"id"`
+	Proposer     string `json:"proposer"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	StartHeight  uint32 `json:"startHeight"`
+	EndHeight    uint32 `json:"endHeight"`
+	Status       string `json:"status"`
+	CreateHeight uint32 `json:"createHeight"`
+	CreateTxHash string `json:"createTxHash"`
+	UpdateHeight uint32 `json:"updateHeight,omitempty"`
+	UpdateTxHash string `json:"updateTxHash,omitempty"`
+}
+
+type ApiProposalDetail struct {
+	*ApiProposal
+	TotalWeight *json.Number `json:"totalWeight"`
+	Tallies     []*ApiTally  `json:"tallies"`
+	Votes       []*ApiVote   `json:"votes"`
+}
+
+type ApiTally struct {
+	Option string       `json:"option"`
+	Votes  uint64       `json:"votes"`
+	Weight *json.Number `json:"weight"`
+	Share  *json.Number `json:"share"`
+}
+
+type ApiVote struct {
+	Voter  string       `json:"voter"`
+	Option string       `json:"option"`
+	Weight *json.Number `json:"weight"`
+	Height uint32       `json:"height"`
+	TxHash string       `json:"txHash"`
+}
@@ -0,0 +1,311 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// MAX_FIELDS caps the fields a query selects, counting the fields of a fragment at each spread
+const MAX_FIELDS = 500
+
+// maxCount saturates the counts of queries spreading fragments into fragments
+const maxCount = 1 << 30
+
+// selection counts the fields of a selection set and the fields among them selecting fields in turn, which
+// are the ones reading the source. Fragment spreads are counted once the fragments are known
+type selection struct {
+	fields  int
+	objects int
+	spreads map[string]int
+}
+
+func newSelection() *selection {
+	return &selection{spreads: make(map[string]int)}
+}
+
+func (this *selection) add(other *selection) {
+	this.fields = saturate(this.fields + other.fields)
+	this.objects = saturate(this.objects + other.objects)
+	for k, v := range other.spreads {
+		this.spreads[k] = saturate(this.spreads[k] + v)
+	}
+}
+
+// queryCost returns the fields a query selects and how many of them select fields in turn. Aliases count as
+// fields of their own and the fields of a fragment are counted at each of its spreads. Every operation of the
+// document is counted, and a malformed query counts what was read before the error, the schema rejects it
+func queryCost(query string) (int, int) {
+	p := &parser{lexer: &lexer{src: query}}
+	p.next()
+	operations := newSelection()
+	fragments := make(map[string]*selection)
+	for p.token.kind != tokenEOF {
+		if p.token.kind == tokenName && p.token.value == "fragment" {
+			p.next()
+			name := p.token.value
+			for p.token.kind != tokenEOF && !p.punct("{") {
+				p.next()
+			}
+			fragments[name] = p.selectionSet()
+			continue
+		}
+		// an operation, its name, variables and directives precede its selection set
+		for p.token.kind != tokenEOF && !p.punct("{") {
+			if p.punct("(") {
+				p.skipBalanced("(", ")")
+				continue
+			}
+			p.next()
+		}
+		if p.token.kind == tokenEOF {
+			break
+		}
+		operations.add(p.selectionSet())
+	}
+	resolved := make(map[string]*selection)
+	fields, objects := expand(operations, fragments, resolved, map[string]bool{})
+	return fields, objects
+}
+
+// expand adds the fields of the spread fragments to those of s, cycles count nothing
+func expand(s *selection, fragments map[string]*selection, resolved map[string]*selection,
+	visiting map[string]bool) (int, int) {
+	fields, objects := s.fields, s.objects
+	for name, spreads := range s.spreads {
+		fragment, ok := resolved[name]
+		if !ok {
+			definition, defined := fragments[name]
+			if !defined || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			f, o := expand(definition, fragments, resolved, visiting)
+			delete(visiting, name)
+			fragment = &selection{fields: f, objects: o}
+			resolved[name] = fragment
+		}
+		fields = saturate(fields + multiply(spreads, fragment.fields))
+		objects = saturate(objects + multiply(spreads, fragment.objects))
+	}
+	return fields, objects
+}
+
+func saturate(n int) int {
+	if n > maxCount || n < 0 {
+		return maxCount
+	}
+	return n
+}
+
+func multiply(a, b int) int {
+	if a != 0 && b > maxCount/a {
+		return maxCount
+	}
+	return a * b
+}
+
+// RequestCost is the rate limit cost of a graphql request, a token per field reading the source and at least
+// one. The body of POST is read and put back for the handler
+func RequestCost(r *http.Request) int {
+	var query string
+	switch r.Method {
+	case http.MethodGet:
+		query = r.URL.Query().Get("query")
+	case http.MethodPost:
+		if r.Body == nil {
+			return 1
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, MAX_BODY_SIZE))
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req := &request{}
+		if err != nil || json.Unmarshal(body, req) != nil {
+			return 1
+		}
+		query = req.Query
+	default:
+		return 1
+	}
+	if _, objects := queryCost(query); objects > 1 {
+		return objects
+	}
+	return 1
+}
+
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+func (this *parser) next() {
+	this.token = this.lexer.next()
+}
+
+func (this *parser) punct(value string) bool {
+	return this.token.kind == tokenPunct && this.token.value == value
+}
+
+// skipBalanced skips from an open token to its matching close token included
+func (this *parser) skipBalanced(open, close string) {
+	depth := 0
+	for this.token.kind != tokenEOF {
+		if this.punct(open) {
+			depth++
+		} else if this.punct(close) {
+			depth--
+			if depth == 0 {
+				this.next()
+				return
+			}
+		}
+		this.next()
+	}
+}
+
+// skipDirectives skips @name(arguments) directives
+func (this *parser) skipDirectives() {
+	for this.punct("@") {
+		this.next()
+		this.next()
+		if this.punct("(") {
+			this.skipBalanced("(", ")")
+		}
+	}
+}
+
+// selectionSet reads a selection set from its opening brace to its closing brace included
+func (this *parser) selectionSet() *selection {
+	s := newSelection()
+	this.next()
+	for this.token.kind != tokenEOF && !this.punct("}") {
+		switch {
+		case this.punct("..."):
+			this.next()
+			if this.token.kind == tokenName && this.token.value != "on" {
+				s.spreads[this.token.value] = saturate(s.spreads[this.token.value] + 1)
+				this.next()
+				this.skipDirectives()
+				continue
+			}
+			// inline fragment
+			if this.token.kind == tokenName {
+				this.next()
+				this.next()
+			}
+			this.skipDirectives()
+			if this.punct("{") {
+				s.add(this.selectionSet())
+			}
+		case this.token.kind == tokenName:
+			this.next()
+			if this.punct(":") {
+				this.next()
+				this.next()
+			}
+			s.fields = saturate(s.fields + 1)
+			if this.punct("(") {
+				this.skipBalanced("(", ")")
+			}
+			this.skipDirectives()
+			if this.punct("{") {
+				s.objects = saturate(s.objects + 1)
+				s.add(this.selectionSet())
+			}
+		default:
+			this.next()
+		}
+	}
+	this.next()
+	return s
+}
+
+const (
+	tokenEOF = iota
+	tokenName
+	tokenPunct
+	tokenValue
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+// lexer splits a query into names, punctuators and values, skipping white space, commas and comments
+type lexer struct {
+	src string
+	pos int
+}
+
+func (this *lexer) next() token {
+	for this.pos < len(this.src) {
+		c := this.src[this.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			this.pos++
+		case c == '#':
+			for this.pos < len(this.src) && this.src[this.pos] != '\n' && this.src[this.pos] != '\r' {
+				this.pos++
+			}
+		case c == '"':
+			this.skipString()
+			return token{kind: tokenValue}
+		case c == '.':
+			if len(this.src)-this.pos >= 3 && this.src[this.pos:this.pos+3] == "..." {
+				this.pos += 3
+				return token{kind: tokenPunct, value: "..."}
+			}
+			this.pos++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := this.pos
+			for this.pos < len(this.src) && isNameChar(this.src[this.pos]) {
+				this.pos++
+			}
+			return token{kind: tokenName, value: this.src[start:this.pos]}
+		case c == '-' || c >= '0' && c <= '9':
+			for this.pos++; this.pos < len(this.src) && (isNameChar(this.src[this.pos]) ||
+				this.src[this.pos] == '.' || this.src[this.pos] == '+' || this.src[this.pos] == '-'); this.pos++ {
+			}
+			return token{kind: tokenValue}
+		default:
+			this.pos++
+			return token{kind: tokenPunct, value: string(c)}
+		}
+	}
+	return token{kind: tokenEOF}
+}
+
+func (this *lexer) skipString() {
+	if len(this.src)-this.pos >= 3 && this.src[this.pos:this.pos+3] == `"""` {
+		end := this.pos + 3
+		for end < len(this.src) {
+			if this.src[end] == '\\' && len(this.src)-end >= 4 && this.src[end:end+4] == `\"""` {
+				end += 4
+				continue
+			}
+			if len(this.src)-end >= 3 && this.src[end:end+3] == `"""` {
+				this.pos = end + 3
+				return
+			}
+			end++
+		}
+		this.pos = len(this.src)
+		return
+	}
+	for this.pos++; this.pos < len(this.src); this.pos++ {
+		switch this.src[this.pos] {
+		case '\\':
+			this.pos++
+		case '"', '\n':
+			this.pos++
+			return
+		}
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
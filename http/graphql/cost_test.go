@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestQueryCost(t *testing.T) {
+	for _, v := range []struct {
+		query   string
+		fields  int
+		objects int
+	}{
+		{`{ markets { name supplyApy } }`, 3, 1},
+		{`query Q($name: String! = "{ a b }") { market(name: $name) { name } price(name: "pUSDT") { price } }`,
+			4, 2},
+		// every alias is a field of its own
+		{`{ a: price(name: "pUSDT") { price } b: price(name: "pWBTC") { price } }`, 4, 2},
+		// the fields of a fragment count at each spread, also spread from another fragment
+		{`{ a: market(name: "x") { ...M } b: market(name: "y") { ...M } }
+		  fragment M on Market { name ...P }
+		  fragment P on Market { price { price # comment }
+		  } }`, 8, 4},
+		{`{ market(name: "x") { ... on Market @include(if: true) { name } } }`, 2, 1},
+		{`{ user(address: "A") { positions { name } } }`, 3, 2},
+		// cycles and unknown fragments count nothing
+		{`{ markets { ...A ...B } } fragment A on Market { name ...A }`, 2, 1},
+		{`"""block { not a field }""" { markets { name } }`, 2, 1},
+		{`{ markets { name `, 2, 1},
+		{``, 0, 0},
+	} {
+		fields, objects := queryCost(v.query)
+		if fields != v.fields || objects != v.objects {
+			t.Errorf("%s: %d fields, %d objects, expected %d and %d", v.query, fields, objects, v.fields,
+				v.objects)
+		}
+	}
+}
+
+func TestQueryCostSaturates(t *testing.T) {
+	// each fragment spreads the previous one ten times
+	query := `{ markets { ...F19 } } fragment F0 on Market { name }`
+	for i := 1; i < 20; i++ {
+		query += fmt.Sprintf(" fragment F%d on Market { %s}", i, strings.Repeat(fmt.Sprintf("...F%d ", i-1), 10))
+	}
+	if fields, _ := queryCost(query); fields != maxCount {
+		t.Errorf("%d fields", fields)
+	}
+}
+
+func TestRequestCost(t *testing.T) {
+	query := `{ a: price(name: "pUSDT") { price } b: price(name: "pWBTC") { price } c: markets { name } }`
+	get := httptest.NewRequest(http.MethodGet, "/api/graphql?query="+url.QueryEscape(query), nil)
+	if cost := RequestCost(get); cost != 3 {
+		t.Errorf("GET cost %d", cost)
+	}
+	body := `{"query":"{ markets { name } }"}`
+	post := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(body))
+	if cost := RequestCost(post); cost != 1 {
+		t.Errorf("POST cost %d", cost)
+	}
+	if data, _ := ioutil.ReadAll(post.Body); string(data) != body {
+		t.Errorf("body not put back: %s", data)
+	}
+	if cost := RequestCost(httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader("{"))); cost != 1 {
+		t.Errorf("invalid body cost %d", cost)
+	}
+}
+
+func TestHandlerFieldCap(t *testing.T) {
+	handler := NewHandler(fakeSource{})
+	query := "{ " + strings.Repeat("markets { name } ", MAX_FIELDS/2+1) + "}"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/graphql?query="+url.QueryEscape(query), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d", w.Code)
+	}
+}
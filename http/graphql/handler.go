@@ -0,0 +1,86 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	MAX_DEPTH       = 10
+	MAX_PARALLELISM = 8
+	MAX_BODY_SIZE   = 1 << 20
+)
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes queries sent as a JSON body of POST or as the query, operationName and variables
+// parameters of GET, answering with the standard {data, errors} object
+type Handler struct {
+	schema *gql.Schema
+}
+
+func NewHandler(source Source) *Handler {
+	return &Handler{
+		schema: gql.MustParseSchema(schema, &resolver{source: source}, gql.UseFieldResolvers(),
+			gql.MaxDepth(MAX_DEPTH), gql.MaxParallelism(MAX_PARALLELISM), gql.Logger(panicLogger{})),
+	}
+}
+
+func (this *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Api-Key")
+	req := &request{}
+	switch r.Method {
+	case http.MethodOptions:
+		return
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if v := query.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)).Decode(req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	if fields, _ := queryCost(req.Query); fields > MAX_FIELDS {
+		http.Error(w, fmt.Sprintf("query selects %d fields, more than %d", fields, MAX_FIELDS),
+			http.StatusBadRequest)
+		return
+	}
+
+	resp := this.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Errorf("graphql.Handler, json.Marshal error: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+type panicLogger struct{}
+
+func (panicLogger) LogPanic(ctx context.Context, value interface{}) {
+	log.Errorf("graphql resolver panic: %v", value)
+}
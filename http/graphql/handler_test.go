@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) GraphMarkets() ([]*common.Market, error) {
+	return []*common.Market{{Name: "pUSDT", SupplyApy: "0.05"}}, nil
+}
+
+func (this fakeSource) GraphMarket(name string) (*common.Market, error) {
+	if name != "pUSDT" {
+		return nil, nil
+	}
+	markets, _ := this.GraphMarkets()
+	return markets[0], nil
+}
+
+func (fakeSource) GraphPrice(name string) (*common.Price, error) {
+	if name != "pUSDT" {
+		return nil, nil
+	}
+	return &common.Price{Name: name, Price: "1"}, nil
+}
+
+func (fakeSource) GraphPriceHistory(name string, limit uint64) ([]*common.PricePoint, error) {
+	points := []*common.PricePoint{{Height: 2, Price: "1"}, {Height: 1, Price: "0.99"}}
+	if uint64(len(points)) > limit {
+		points = points[:limit]
+	}
+	return points, nil
+}
+
+func (fakeSource) GraphPositions(address string) ([]*common.Position, error) {
+	return []*common.Position{{Name: "pUSDT", SupplyBalance: "10", IfCollateral: true}}, nil
+}
+
+func (fakeSource) GraphWingApy(name string) (*common.WingApy, error) {
+	return &common.WingApy{AssetName: name, SupplyApy: "0.1"}, nil
+}
+
+func (fakeSource) GraphWingApys() ([]common.WingApy, error) {
+	return []common.WingApy{{AssetName: "pUSDT"}}, nil
+}
+
+func (fakeSource) GraphReserves() (*common.Reserves, error) {
+	return &common.Reserves{AssetReserve: []*common.Reserve{{Name: "pUSDT"}}, TotalReserve: "1"}, nil
+}
+
+func (fakeSource) GraphGovPool() (*common.GovPool, error) {
+	return &common.GovPool{Daily: "1"}, nil
+}
+
+func TestNestedQuery(t *testing.T) {
+	handler := NewHandler(fakeSource{})
+	body := `{"query":"query($a: String!) { user(address: $a) { address positions { name ifCollateral market { name price { price history(limit: 1) { height price } } wingApy { supplyApy } } } } }",
+		"variables":{"a":"AXxK3ZW1k8VFsbF8ZpV8Cp7ZTKXNgFVqyS"}}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.GRAPHQL, strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	expected := `{"data":{"user":{"address":"AXxK3ZW1k8VFsbF8ZpV8Cp7ZTKXNgFVqyS","positions":[{"name":"pUSDT","ifCollateral":true,` +
+		`"market":{"name":"pUSDT","price":{"price":"1","history":[{"height":2,"price":"1"}]},"wingApy":{"supplyApy":"0.1"}}}]}}}`
+	if w.Body.String() != expected {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestQueryOverGet(t *testing.T) {
+	handler := NewHandler(fakeSource{})
+	query := url.Values{"query": {"{ markets { name supplyApy } reserves { totalReserve } govPool { daily } market(name: \"x\") { name } }"}}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, common.GRAPHQL+"?"+query.Encode(), nil))
+	resp := struct {
+		Data   json.RawMessage
+		Errors []interface{}
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", resp.Errors)
+	}
+	expected := `{"markets":[{"name":"pUSDT","supplyApy":"0.05"}],"reserves":{"totalReserve":"1"},"govPool":{"daily":"1"},"market":null}`
+	if string(resp.Data) != expected {
+		t.Errorf("unexpected data %s", resp.Data)
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	handler := NewHandler(fakeSource{})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.GRAPHQL, strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
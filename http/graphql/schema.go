@@ -0,0 +1,266 @@
+// Package graphql serves a GraphQL schema over the markets, users, prices and governance of the flash pool
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+const (
+	MAX_HISTORY_LIMIT = 500
+)
+
+// Source is the data behind the schema, implemented by service.Service. Markets and prices are
+// looked up by market name, unknown names return nil without error
+type Source interface {
+	GraphMarkets() ([]*common.Market, error)
+	GraphMarket(name string) (*common.Market, error)
+	GraphPrice(name string) (*common.Price, error)
+	GraphPriceHistory(name string, limit uint64) ([]*common.PricePoint, error)
+	GraphPositions(address string) ([]*common.Position, error)
+	GraphWingApy(name string) (*common.WingApy, error)
+	GraphWingApys() ([]common.WingApy, error)
+	GraphReserves() (*common.Reserves, error)
+	GraphGovPool() (*common.GovPool, error)
+}
+
+// fields are named after the v1 types in http/common, amounts are decimal strings
+const schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	markets: [Market!]!
+	market(name: String!): Market
+	prices: [Price!]!
+	price(name: String!): Price
+	user(address: String!): User!
+	reserves: Reserves!
+	wingApys: [WingApy!]!
+	govPool: GovPool!
+}
+
+type Market {
+	icon: String!
+	name: String!
+	totalSupplyDollar: String!
+	totalSupplyAmount: String!
+	supplyApy: String!
+	totalBorrowDollar: String!
+	totalBorrowAmount: String!
+	borrowApy: String!
+	totalInsuranceDollar: String!
+	totalInsuranceAmount: String!
+	insuranceApy: String!
+	collateralFactor: String!
+	supplyDistribution: String!
+	borrowDistribution: String!
+	insuranceDistribution: String!
+	price: Price
+	wingApy: WingApy
+}
+
+type User {
+	address: String!
+	positions: [UserPosition!]!
+}
+
+type UserPosition {
+	name: String!
+	icon: String!
+	supplyBalance: String!
+	borrowBalance: String!
+	insuranceBalance: String!
+	ifCollateral: Boolean!
+	market: Market
+}
+
+type Price {
+	name: String!
+	price: String!
+	# prices read from the oracle after its updates, newest first
+	history(limit: Int = 20): [PricePoint!]!
+}
+
+type PricePoint {
+	height: Int!
+	txHash: String!
+	price: String!
+}
+
+type Reserves {
+	assetReserve: [Reserve!]!
+	totalReserve: String!
+}
+
+type Reserve {
+	name: String!
+	icon: String!
+	reserveFactor: String!
+	reserveBalance: String!
+	reserveDollar: String!
+}
+
+type WingApy {
+	assetName: String!
+	supplyApy: String!
+	borrowApy: String!
+	insuranceApy: String!
+}
+
+type GovPool {
+	daily: String!
+	distributed: String!
+	remain20: String!
+	remain80: String!
+}
+`
+
+type resolver struct {
+	source Source
+}
+
+func (this *resolver) Markets(ctx context.Context) ([]*marketResolver, error) {
+	markets, err := this.source.GraphMarkets()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*marketResolver, 0, len(markets))
+	for _, v := range markets {
+		resolvers = append(resolvers, &marketResolver{Market: *v, source: this.source})
+	}
+	return resolvers, nil
+}
+
+func (this *resolver) Market(ctx context.Context, args struct{ Name string }) (*marketResolver, error) {
+	return newMarketResolver(this.source, args.Name)
+}
+
+func (this *resolver) Prices(ctx context.Context) ([]*priceResolver, error) {
+	markets, err := this.source.GraphMarkets()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*priceResolver, 0, len(markets))
+	for _, v := range markets {
+		price, err := newPriceResolver(this.source, v.Name)
+		if err != nil {
+			return nil, err
+		}
+		if price != nil {
+			resolvers = append(resolvers, price)
+		}
+	}
+	return resolvers, nil
+}
+
+func (this *resolver) Price(ctx context.Context, args struct{ Name string }) (*priceResolver, error) {
+	return newPriceResolver(this.source, args.Name)
+}
+
+func (this *resolver) User(ctx context.Context, args struct{ Address string }) (*userResolver, error) {
+	positions, err := this.source.GraphPositions(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{address: args.Address, positions: positions, source: this.source}, nil
+}
+
+func (this *resolver) Reserves(ctx context.Context) (*common.Reserves, error) {
+	return this.source.GraphReserves()
+}
+
+func (this *resolver) WingApys(ctx context.Context) ([]common.WingApy, error) {
+	return this.source.GraphWingApys()
+}
+
+func (this *resolver) GovPool(ctx context.Context) (*common.GovPool, error) {
+	return this.source.GraphGovPool()
+}
+
+type marketResolver struct {
+	common.Market
+	source Source
+}
+
+func newMarketResolver(source Source, name string) (*marketResolver, error) {
+	market, err := source.GraphMarket(name)
+	if err != nil || market == nil {
+		return nil, err
+	}
+	return &marketResolver{Market: *market, source: source}, nil
+}
+
+func (this *marketResolver) Price(ctx context.Context) (*priceResolver, error) {
+	return newPriceResolver(this.source, this.Name)
+}
+
+func (this *marketResolver) WingApy(ctx context.Context) (*common.WingApy, error) {
+	return this.source.GraphWingApy(this.Name)
+}
+
+type userResolver struct {
+	address   string
+	positions []*common.Position
+	source    Source
+}
+
+func (this *userResolver) Address() string {
+	return this.address
+}
+
+func (this *userResolver) Positions() []*positionResolver {
+	resolvers := make([]*positionResolver, 0, len(this.positions))
+	for _, v := range this.positions {
+		resolvers = append(resolvers, &positionResolver{Position: *v, source: this.source})
+	}
+	return resolvers
+}
+
+type positionResolver struct {
+	common.Position
+	source Source
+}
+
+func (this *positionResolver) Market(ctx context.Context) (*marketResolver, error) {
+	return newMarketResolver(this.source, this.Name)
+}
+
+type priceResolver struct {
+	common.Price
+	source Source
+}
+
+func newPriceResolver(source Source, name string) (*priceResolver, error) {
+	price, err := source.GraphPrice(name)
+	if err != nil || price == nil {
+		return nil, err
+	}
+	return &priceResolver{Price: *price, source: source}, nil
+}
+
+func (this *priceResolver) History(ctx context.Context, args struct{ Limit int32 }) ([]*pricePointResolver, error) {
+	if args.Limit < 0 || args.Limit > MAX_HISTORY_LIMIT {
+		return nil, fmt.Errorf("limit should be between 0 and %d", MAX_HISTORY_LIMIT)
+	}
+	points, err := this.source.GraphPriceHistory(this.Name, uint64(args.Limit))
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*pricePointResolver, 0, len(points))
+	for _, v := range points {
+		resolvers = append(resolvers, &pricePointResolver{PricePoint: *v})
+	}
+	return resolvers, nil
+}
+
+type pricePointResolver struct {
+	common.PricePoint
+}
+
+func (this *pricePointResolver) Height() int32 {
+	return int32(this.PricePoint.Height)
+}
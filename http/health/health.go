@@ -0,0 +1,224 @@
+// Package health serves the liveness, readiness and status endpoints of the process
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	CHECK_DB      = "db"
+	CHECK_RPC     = "rpc"
+	CHECK_INDEXER = "indexer"
+	CHECK_PRICES  = "prices"
+)
+
+// Source is implemented by service.Service
+type Source interface {
+	PingDB() error
+	IndexerStatus() (*common.IndexerStatus, error)
+	Jobs() map[string]*common.JobStatus
+	Indexing() bool
+	PricesUpdatedAt() (time.Time, error)
+}
+
+// Check is skipped when it does not apply to the replica, e.g. the indexer of a replica which is not the leader
+type Check struct {
+	Ok      bool
+	Skipped bool   `json:",omitempty"`
+	Error   string `json:",omitempty"`
+}
+
+type Readiness struct {
+	Ready  bool
+	Checks map[string]*Check
+}
+
+// ConfigSummary is the part of the config shown by the status, the database url and tokens are left out
+type ConfigSummary struct {
+	JsonRpcAddress        string
+	Port                  uint64
+	GovernanceAddress     string
+	WingAddress           string
+	FlashPoolAddress      string
+	OracleAddress         string
+	Assets                int
+	ScanInterval          uint64
+	SnapshotInterval      uint64
+	MarketRefreshInterval uint64
+	MaxLag                uint32
+	MaxPriceAge           uint64
+	AdminApi              bool
+	Webhook               bool
+	RateLimit             bool
+	RequireApiKey         bool
+}
+
+type Status struct {
+	Version     string
+	Indexing    bool
+	StartTime   uint64
+	Uptime      uint64
+	Config      *ConfigSummary
+	TrackHeight uint32
+	ChainHeight uint32
+	Lag         uint32
+	QueueDepth  int64
+	IndexerErr  string `json:",omitempty"`
+	Jobs        map[string]*common.JobStatus
+}
+
+type Handler struct {
+	source  Source
+	cfg     *config.Holder
+	version string
+	start   time.Time
+	now     func() time.Time
+}
+
+func NewHandler(source Source, cfg *config.Holder, version string) *Handler {
+	return &Handler{
+		source:  source,
+		cfg:     cfg,
+		version: version,
+		start:   time.Now(),
+		now:     time.Now,
+	}
+}
+
+func (this *Handler) thresholds() (uint32, uint64) {
+	cfg := this.cfg.Get()
+	var maxLag uint32 = config.DEFAULT_HEALTH_MAX_LAG
+	var maxPriceAge uint64 = config.DEFAULT_HEALTH_MAX_PRICE_AGE
+	if cfg.Health != nil {
+		if cfg.Health.MaxLag != 0 {
+			maxLag = cfg.Health.MaxLag
+		}
+		if cfg.Health.MaxPriceAge != 0 {
+			maxPriceAge = cfg.Health.MaxPriceAge
+		}
+	}
+	return maxLag, maxPriceAge
+}
+
+// Healthz answers 200 as long as the process serves requests
+func (this *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok"))
+}
+
+// Readyz answers 200 when the database and the node are reachable, the indexer is close to the chain tip
+// and the prices are fresh, 503 otherwise. Replicas which do not index only check the database and the node
+func (this *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	readiness := this.readiness()
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJson(w, status, readiness)
+}
+
+func (this *Handler) readiness() *Readiness {
+	maxLag, maxPriceAge := this.thresholds()
+	checks := map[string]*Check{
+		CHECK_DB:      {Ok: true},
+		CHECK_RPC:     {Ok: true},
+		CHECK_INDEXER: {Ok: true},
+		CHECK_PRICES:  {Ok: true},
+	}
+	if err := this.source.PingDB(); err != nil {
+		checks[CHECK_DB] = &Check{Error: err.Error()}
+	}
+	indexer, err := this.source.IndexerStatus()
+	if err != nil {
+		checks[CHECK_RPC] = &Check{Error: err.Error()}
+		checks[CHECK_INDEXER] = &Check{Error: "chain height unknown"}
+	} else if indexer.Lag > maxLag {
+		checks[CHECK_INDEXER] = &Check{Error: fmt.Sprintf("%d blocks behind the chain, max %d", indexer.Lag, maxLag)}
+	}
+	if !this.source.Indexing() {
+		checks[CHECK_INDEXER] = &Check{Ok: true, Skipped: true}
+		checks[CHECK_PRICES] = &Check{Ok: true, Skipped: true}
+		return ready(checks)
+	}
+	// the stored prices, the price feed of a previous leader counts as well
+	updatedAt, err := this.source.PricesUpdatedAt()
+	if err != nil {
+		checks[CHECK_PRICES] = &Check{Error: err.Error()}
+	} else if updatedAt.IsZero() {
+		checks[CHECK_PRICES] = &Check{Error: "prices not fed yet"}
+	} else if age := uint64(this.now().Sub(updatedAt).Seconds()); age > maxPriceAge {
+		checks[CHECK_PRICES] = &Check{Error: fmt.Sprintf("prices fed %d seconds ago, max %d", age, maxPriceAge)}
+	}
+	return ready(checks)
+}
+
+func ready(checks map[string]*Check) *Readiness {
+	readiness := &Readiness{Ready: true, Checks: checks}
+	for _, v := range checks {
+		readiness.Ready = readiness.Ready && v.Ok
+	}
+	return readiness
+}
+
+// Status shows the version, the config without secrets, the heights and the last runs of the background jobs,
+// it is served on the admin port
+func (this *Handler) Status(w http.ResponseWriter, r *http.Request) {
+	maxLag, maxPriceAge := this.thresholds()
+	cfg := this.cfg.Get()
+	now := this.now()
+	status := &Status{
+		Version:   this.version,
+		Indexing:  this.source.Indexing(),
+		StartTime: uint64(this.start.Unix()),
+		Uptime:    uint64(now.Sub(this.start).Seconds()),
+		Config: &ConfigSummary{
+			JsonRpcAddress:        cfg.JsonRpcAddress,
+			Port:                  cfg.Port,
+			GovernanceAddress:     cfg.GovernanceAddress,
+			WingAddress:           cfg.WingAddress,
+			FlashPoolAddress:      cfg.FlashPoolAddress,
+			OracleAddress:         cfg.OracleAddress,
+			Assets:                len(cfg.AssetMap),
+			ScanInterval:          cfg.ScanInterval,
+			SnapshotInterval:      cfg.SnapshotInterval,
+			MarketRefreshInterval: cfg.MarketRefreshInterval,
+			MaxLag:                maxLag,
+			MaxPriceAge:           maxPriceAge,
+			AdminApi:              cfg.AdminToken != "" && cfg.AdminPort != 0,
+			Webhook:               cfg.Webhook != nil,
+			RateLimit:             cfg.RateLimit != nil,
+			RequireApiKey:         cfg.RateLimit != nil && cfg.RateLimit.RequireApiKey,
+		},
+		Jobs: this.source.Jobs(),
+	}
+	indexer, err := this.source.IndexerStatus()
+	if err != nil {
+		status.IndexerErr = err.Error()
+	} else {
+		status.TrackHeight = indexer.TrackHeight
+		status.ChainHeight = indexer.ChainHeight
+		status.Lag = indexer.Lag
+		status.QueueDepth = indexer.QueueDepth
+	}
+	writeJson(w, http.StatusOK, status)
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("health.writeJson, json.Marshal error: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,289 @@
+// Package openapi generates the OpenAPI 3 document of the restful server from its endpoint table
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	Version = "3.0.3"
+
+	contentJson    = "application/json"
+	contentProblem = "application/problem+json"
+)
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       *Info                            `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationId string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var paramRegexp = regexp.MustCompile(`:(\w+)`)
+
+// Generate builds the document of the given endpoints
+func Generate(endpoints []*restful.Endpoint) *Document {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		types:   make(map[string]reflect.Type),
+	}
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       &Info{Title: "wingServer API", Version: "1.0.0"},
+		Paths:      make(map[string]map[string]*Operation),
+		Components: &Components{Schemas: g.schemas},
+	}
+	for _, e := range endpoints {
+		p := paramRegexp.ReplaceAllString(e.Path, "{$1}")
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]*Operation)
+		}
+		doc.Paths[p][strings.ToLower(e.Method)] = g.operation(e)
+	}
+	return doc
+}
+
+var (
+	once     sync.Once
+	document []byte
+)
+
+// Handler serves the document of the registered endpoints
+func Handler(w http.ResponseWriter, r *http.Request) {
+	once.Do(func() {
+		var err error
+		document, err = json.Marshal(Generate(restful.Endpoints()))
+		if err != nil {
+			log.Errorf("openapi.Handler, json.Marshal error: %s", err)
+		}
+	})
+	w.Header().Set("Content-Type", contentJson)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(document)
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+}
+
+func (this *generator) operation(e *restful.Endpoint) *Operation {
+	op := &Operation{Responses: make(map[string]*Response)}
+	for _, v := range paramRegexp.FindAllStringSubmatch(e.Path, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{Name: v[1], In: "path", Required: true,
+			Schema: &Schema{Type: "string"}})
+	}
+	for _, v := range e.Query {
+		op.Parameters = append(op.Parameters, &Parameter{Name: v, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	if e.Action != "" {
+		op.OperationId = e.Action
+		if e.Request != nil {
+			if e.Method == http.MethodGet {
+				for _, name := range fieldNames(reflect.TypeOf(e.Request)) {
+					op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query",
+						Schema: &Schema{Type: "string"}})
+				}
+			} else {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]*MediaType{contentJson: {Schema: this.schema(reflect.TypeOf(e.Request))}},
+				}
+			}
+		}
+		// v1 answers 200 whatever happens, the outcome is in the error field of the envelope
+		op.Responses["200"] = &Response{
+			Description: "common.Response carrying the result",
+			Content: map[string]*MediaType{contentJson: {Schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"action": {Type: "string"},
+					"desc":   {Type: "string"},
+					"error":  {Type: "integer", Format: "int64"},
+					"result": this.schema(reflect.TypeOf(e.Response)),
+				},
+			}}},
+		}
+		return op
+	}
+	op.OperationId = operationId(e.Method, e.Path)
+	if e.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{contentJson: {Schema: this.schema(reflect.TypeOf(e.Request))}},
+		}
+	}
+	op.Responses["200"] = &Response{
+		Description: "OK",
+		Content:     map[string]*MediaType{contentJson: {Schema: this.schema(reflect.TypeOf(e.Response))}},
+	}
+	op.Responses["default"] = &Response{
+		Description: "RFC 7807 problem",
+		Content:     map[string]*MediaType{contentProblem: {Schema: this.schema(reflect.TypeOf(common.Problem{}))}},
+	}
+	return op
+}
+
+func (this *generator) schema(t reflect.Type) *Schema {
+	// numbers which are not decimals are written as null
+	if t == reflect.TypeOf((*json.Number)(nil)) {
+		return &Schema{Type: "number", Nullable: true}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(json.Number("")) {
+		return &Schema{Type: "number"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: this.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: this.schema(t.Elem())}
+	case reflect.Struct:
+		name := this.name(t)
+		if _, ok := this.schemas[name]; !ok {
+			s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			this.schemas[name] = s
+			this.properties(t, s.Properties)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (this *generator) properties(t reflect.Type, properties map[string]*Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			this.properties(ft, properties)
+			continue
+		}
+		properties[name] = this.schema(f.Type)
+	}
+}
+
+// name is the type name, qualified by its package when another package has a type of the same name
+func (this *generator) name(t reflect.Type) string {
+	name := t.Name()
+	if known, ok := this.types[name]; ok && known != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	this.types[name] = t
+	return name
+}
+
+func fieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+func operationId(method, p string) string {
+	id := strings.ToLower(method)
+	for _, v := range strings.Split(strings.TrimPrefix(p, "/api/"), "/") {
+		v = strings.TrimPrefix(v, ":")
+		if v == "" {
+			continue
+		}
+		id += strings.ToUpper(v[:1]) + v[1:]
+	}
+	return id
+}
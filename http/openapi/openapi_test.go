@@ -0,0 +1,49 @@
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/restful"
+)
+
+const specFile = "openapi.json"
+
+var update = flag.Bool("update", false, "rewrite openapi.json from the endpoint table")
+
+// TestSpecUpToDate fails when routes or their types change without openapi.json being regenerated
+// with go test ./http/openapi -update
+func TestSpecUpToDate(t *testing.T) {
+	data, err := json.MarshalIndent(Generate(restful.Endpoints()), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	if *update {
+		if err := ioutil.WriteFile(specFile, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	spec, err := ioutil.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(spec) != string(data) {
+		t.Fatalf("%s is out of date, run go test ./http/openapi -update", specFile)
+	}
+}
+
+func TestEveryEndpointDocumented(t *testing.T) {
+	doc := Generate(restful.Endpoints())
+	for _, e := range restful.Endpoints() {
+		if e.Response == nil {
+			t.Errorf("%s %s has no response type", e.Method, e.Path)
+		}
+	}
+	if len(doc.Paths) == 0 || len(doc.Components.Schemas) == 0 {
+		t.Fatal("empty document")
+	}
+}
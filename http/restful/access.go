@@ -0,0 +1,66 @@
+package restful
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
+const REQUEST_ID_HEADER = "X-Request-Id"
+
+// ids set by a proxy are kept when they are short and printable
+var requestIdPattern = regexp.MustCompile(`^[\w.:-]{1,64}$`)
+
+// probes and scrapes logged at debug level
+var quiet = map[string]bool{
+	common.HEALTHZ: true,
+	common.READYZ:  true,
+	common.METRICS: true,
+}
+
+// RequestLog gives every request an id, returned in the X-Request-Id header and carried by the log entry of
+// the request context, and writes an access log line once the request is answered. Probes and scrapes are
+// only logged at debug level
+func RequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if !requestIdPattern.MatchString(id) {
+			id = newRequestId()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, id)
+		entry := log.WithFields(log.Fields{log.FIELD_REQUEST_ID: id})
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(log.NewContext(r.Context(), entry)))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		access := entry.WithFields(log.Fields{
+			log.FIELD_COMPONENT: "access",
+			"method":            r.Method,
+			"path":              r.URL.Path,
+			"status":            sw.status,
+			"duration_ms":       time.Since(start).Milliseconds(),
+			"remote":            r.RemoteAddr,
+		})
+		if quiet[r.URL.Path] {
+			access.Debugf("%s %s %d", r.Method, r.URL.Path, sw.status)
+		} else {
+			access.Infof("%s %s %d", r.Method, r.URL.Path, sw.status)
+		}
+	})
+}
+
+func newRequestId() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,64 @@
+package restful
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/siovanus/wingServer/log"
+)
+
+func TestRequestLog(t *testing.T) {
+	file, err := ioutil.TempFile("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	log.InitLog(log.InfoLog, file)
+	log.SetFormat(log.FORMAT_JSON)
+	defer func() {
+		log.SetFormat(log.FORMAT_TEXT)
+		log.InitLog(log.InfoLog, log.Stdout)
+	}()
+
+	router := NewRouter()
+	router.Use(RequestLog)
+	router.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Errorf("test, handler error: %s", "failed")
+		w.WriteHeader(http.StatusTeapot)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	id := w.Header().Get(REQUEST_ID_HEADER)
+	if len(id) != 16 {
+		t.Errorf("unexpected request id %q", id)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	r.Header.Set(REQUEST_ID_HEADER, "proxy-1")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Header().Get(REQUEST_ID_HEADER) != "proxy-1" {
+		t.Errorf("request id of the proxy should be kept, got %q", w.Header().Get(REQUEST_ID_HEADER))
+	}
+
+	data, _ := ioutil.ReadFile(file.Name())
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected log %s", data)
+	}
+	var handler, access map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &handler)
+	json.Unmarshal([]byte(lines[1]), &access)
+	if handler[log.FIELD_REQUEST_ID] != id || handler[log.FIELD_COMPONENT] != "restful" ||
+		handler[log.FIELD_ACTION] != "test" {
+		t.Errorf("unexpected handler line %v", handler)
+	}
+	if access[log.FIELD_REQUEST_ID] != id || access[log.FIELD_COMPONENT] != "access" ||
+		access["status"] != float64(http.StatusTeapot) || access["path"] != "/test" {
+		t.Errorf("unexpected access line %v", access)
+	}
+}
@@ -0,0 +1,97 @@
+package restful
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	BATCH_MAX_SIZE    = 20 // max entries of a batch call
+	BATCH_CONCURRENCY = 4  // max entries of a batch call running at the same time
+)
+
+// initBatchHandler registers the batch route
+func (this *restServer) initBatchHandler() {
+	this.router.Post(common.BATCH, this.batch)
+	this.router.Options(common.BATCH, func(w http.ResponseWriter, r *http.Request) {
+		this.write(w, []byte{})
+	})
+}
+
+// batch runs the v1 actions of a [{action, params}] body concurrently and answers with their
+// common.Response in the order of the entries
+func (this *restServer) batch(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	var reqs []*common.BatchRequest
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		WriteProblem(w, r, http.StatusBadRequest, fmt.Sprintf("body is not an array of {action, params}: %s", err))
+		return
+	}
+	if len(reqs) > BATCH_MAX_SIZE {
+		WriteProblem(w, r, http.StatusBadRequest, fmt.Sprintf("at most %d entries are allowed", BATCH_MAX_SIZE))
+		return
+	}
+
+	resps := make([]map[string]interface{}, len(reqs))
+	sem := make(chan struct{}, BATCH_CONCURRENCY)
+	done := make(chan struct{})
+	for i, req := range reqs {
+		sem <- struct{}{}
+		go func(i int, req *common.BatchRequest) {
+			defer func() {
+				<-sem
+				done <- struct{}{}
+			}()
+			resps[i] = this.runAction(req)
+		}(i, req)
+	}
+	for range reqs {
+		<-done
+	}
+
+	data, err := json.Marshal(resps)
+	if err != nil {
+		log.Errorf("batch json.Marshal error: %s", err)
+		WriteProblem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	this.write(w, data)
+}
+
+func (this *restServer) runAction(req *common.BatchRequest) (resp map[string]interface{}) {
+	if req == nil {
+		req = &common.BatchRequest{}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("batch action %s panic: %v", req.Action, r)
+			resp = PackResponse(INTERNAL_ERROR)
+			resp["action"] = req.Action
+			resp["desc"] = ErrMap[INTERNAL_ERROR]
+		}
+	}()
+	action, ok := this.actions[req.Action]
+	if !ok {
+		resp = PackResponse(INVALID_METHOD)
+	} else {
+		params := req.Params
+		if params == nil {
+			params = make(map[string]interface{})
+		}
+		resp = action.handler(params)
+	}
+	resp["action"] = req.Action
+	resp["desc"] = ErrMap[resp["error"].(uint32)]
+	return
+}
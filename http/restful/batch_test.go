@@ -0,0 +1,75 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+func newBatchServer() *restServer {
+	rt := &restServer{router: NewRouter(), actions: make(map[string]Action)}
+	rt.actions["echo"] = Action{name: "echo", handler: func(params map[string]interface{}) map[string]interface{} {
+		resp := PackResponse(SUCCESS)
+		resp["result"] = params["value"]
+		return resp
+	}}
+	rt.actions["panic"] = Action{name: "panic", handler: func(params map[string]interface{}) map[string]interface{} {
+		panic("boom")
+	}}
+	rt.initBatchHandler()
+	return rt
+}
+
+func TestBatch(t *testing.T) {
+	rt := newBatchServer()
+	body := `[{"action":"echo","params":{"value":"a"}},{"action":"unknown"},{"action":"panic"},
+		{"action":"echo","params":{"value":"b"}}]`
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.BATCH, strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	var resps []*common.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 4 {
+		t.Fatalf("expected 4 responses, got %d", len(resps))
+	}
+	if resps[0].Action != "echo" || resps[0].Error != SUCCESS || resps[0].Result != "a" {
+		t.Errorf("unexpected first response %+v", resps[0])
+	}
+	if resps[1].Action != "unknown" || resps[1].Error != INVALID_METHOD {
+		t.Errorf("unexpected second response %+v", resps[1])
+	}
+	if resps[2].Error != INTERNAL_ERROR || resps[2].Desc != ErrMap[INTERNAL_ERROR] {
+		t.Errorf("unexpected third response %+v", resps[2])
+	}
+	if resps[3].Result != "b" {
+		t.Errorf("unexpected fourth response %+v", resps[3])
+	}
+}
+
+func TestBatchRejectsBadBody(t *testing.T) {
+	rt := newBatchServer()
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.BATCH, strings.NewReader(`{"action":"echo"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+
+	entries := make([]string, BATCH_MAX_SIZE+1)
+	for i := range entries {
+		entries[i] = `{"action":"echo"}`
+	}
+	w = httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.BATCH,
+		strings.NewReader("["+strings.Join(entries, ",")+"]")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
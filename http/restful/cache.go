@@ -0,0 +1,163 @@
+package restful
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+const maxCacheEntries = 10000
+
+// ResponseCache keeps successful GET responses of the routes given a TTL, keyed by path and query.
+// Cached responses carry an ETag and answer If-None-Match with 304.
+type ResponseCache struct {
+	sync.RWMutex
+	ttls    map[string]time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	header http.Header
+	body   []byte
+	etag   string
+	expire time.Time
+}
+
+// NewResponseCache takes the TTL of each route pattern, routes without TTL are not cached
+func NewResponseCache(ttls map[string]time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttls:    ttls,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Notify drops the entries made stale by a snapshot write, account updates only drop the routes of the account
+func (this *ResponseCache) Notify(topic string, data interface{}) {
+	switch topic {
+	case common.TOPIC_ACCOUNT:
+		if update, ok := data.(*common.AccountUpdate); ok {
+			this.InvalidateMatching(update.Address)
+		}
+	case common.TOPIC_MARKET, common.TOPIC_PRICE, common.TOPIC_FLASHPOOL:
+		this.InvalidateAll()
+	}
+}
+
+func (this *ResponseCache) InvalidateAll() {
+	this.Lock()
+	defer this.Unlock()
+	this.entries = make(map[string]*cacheEntry)
+}
+
+// InvalidateMatching drops the entries whose path or query contains s
+func (this *ResponseCache) InvalidateMatching(s string) {
+	this.Lock()
+	defer this.Unlock()
+	for k := range this.entries {
+		if strings.Contains(k, s) {
+			delete(this.entries, k)
+		}
+	}
+}
+
+func (this *ResponseCache) wrap(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	ttl := this.ttls[pattern]
+	if ttl <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		now := time.Now()
+		this.RLock()
+		entry, ok := this.entries[key]
+		this.RUnlock()
+		if !ok || now.After(entry.expire) {
+			recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+			handler(recorder, r)
+			if recorder.status != http.StatusOK || strings.Contains(recorder.header.Get("Cache-Control"), "no-store") {
+				recorder.flush(w)
+				return
+			}
+			sum := sha1.Sum(recorder.body.Bytes())
+			entry = &cacheEntry{
+				header: recorder.header,
+				body:   recorder.body.Bytes(),
+				etag:   `"` + hex.EncodeToString(sum[:]) + `"`,
+				expire: now.Add(ttl),
+			}
+			this.store(key, entry, now)
+		}
+		for k, v := range entry.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", entry.etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(entry.expire.Sub(now).Seconds())))
+		if etagMatch(r.Header.Get("If-None-Match"), entry.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(entry.body)
+	}
+}
+
+func (this *ResponseCache) store(key string, entry *cacheEntry, now time.Time) {
+	this.Lock()
+	defer this.Unlock()
+	if len(this.entries) >= maxCacheEntries {
+		for k, v := range this.entries {
+			if now.After(v.expire) {
+				delete(this.entries, k)
+			}
+		}
+		if len(this.entries) >= maxCacheEntries {
+			return
+		}
+	}
+	this.entries[key] = entry
+}
+
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (this *responseRecorder) Header() http.Header {
+	return this.header
+}
+
+func (this *responseRecorder) WriteHeader(status int) {
+	this.status = status
+}
+
+func (this *responseRecorder) Write(data []byte) (int, error) {
+	return this.body.Write(data)
+}
+
+func (this *responseRecorder) flush(w http.ResponseWriter) {
+	for k, v := range this.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(this.status)
+	w.Write(this.body.Bytes())
+}
@@ -0,0 +1,70 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := NewResponseCache(map[string]time.Duration{"/api/v2/users/:address": time.Minute})
+	calls := 0
+	handler := cache.wrap("/api/v2/users/:address", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("body"))
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/A1", nil))
+	etag := w.Header().Get("ETag")
+	if w.Body.String() != "body" || etag == "" {
+		t.Fatalf("unexpected response %s, etag %s", w.Body.String(), etag)
+	}
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/A1", nil))
+	if calls != 1 || w.Body.String() != "body" {
+		t.Errorf("response not cached, %d calls", calls)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/users/A1", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+
+	cache.Notify(common.TOPIC_ACCOUNT, &common.AccountUpdate{Address: "A2"})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/users/A1", nil))
+	if calls != 1 {
+		t.Errorf("entry of another account invalidated")
+	}
+	cache.Notify(common.TOPIC_ACCOUNT, &common.AccountUpdate{Address: "A1"})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/users/A1", nil))
+	if calls != 2 {
+		t.Errorf("entry of the account not invalidated")
+	}
+}
+
+func TestResponseCacheSkipsFailures(t *testing.T) {
+	cache := NewResponseCache(map[string]time.Duration{"/a": time.Minute, "/b": time.Minute})
+	calls := 0
+	noStore := cache.wrap("/a", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "no-store")
+	})
+	failed := cache.wrap("/b", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	for i := 0; i < 2; i++ {
+		noStore(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+		failed(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+	}
+	if calls != 4 {
+		t.Errorf("failures cached, %d calls", calls)
+	}
+}
@@ -0,0 +1,101 @@
+package restful
+
+import (
+	"net/http"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+// Endpoint describes a JSON route of the restful server, the table below registers the handlers and
+// documents the API in openapi.json, so such a route cannot be served without being described. Routes
+// mounted with Handle are not described: the websocket, the event stream, graphql, which documents itself,
+// openapi.json itself and the health probes
+type Endpoint struct {
+	Method string
+	Path   string
+	// Action is the v1 action name, v1 results are wrapped in common.Response
+	Action string
+	// Request holds the parameters of v1 routes, read from the query of GET or the body of POST
+	Request interface{}
+	// Query lists the query parameters of v2 routes
+	Query    []string
+	Response interface{}
+
+	handler    func(Web, map[string]interface{}) map[string]interface{}
+	apiHandler func(WebV2, *http.Request) (interface{}, error)
+}
+
+func Endpoints() []*Endpoint {
+	return endpoints
+}
+
+var endpoints = []*Endpoint{
+	{Method: http.MethodPost, Path: common.USERFLASHPOOLOVERVIEW, Action: common.ACTION_USERFLASHPOOLOVERVIEW,
+		Request: common.UserFlashPoolOverviewRequest{}, Response: common.UserFlashPoolOverviewResponse{},
+		handler: Web.UserFlashPoolOverview},
+	{Method: http.MethodPost, Path: common.ASSETPRICE, Action: common.ACTION_ASSETPRICE,
+		Request: common.AssetPriceRequest{}, Response: common.AssetPriceResponse{}, handler: Web.AssetPrice},
+	{Method: http.MethodPost, Path: common.ASSETPRICELIST, Action: common.ACTION_ASSETPRICELIST,
+		Request: common.AssetPriceListRequest{}, Response: common.AssetPriceListResponse{}, handler: Web.AssetPriceList},
+	{Method: http.MethodPost, Path: common.CLAIMWING, Action: common.ACTION_CLAIMWING,
+		Request: common.ClaimWingRequest{}, Response: common.ClaimWingResponse{}, handler: Web.ClaimWing},
+	{Method: http.MethodPost, Path: common.LIQUIDATIONLIST, Action: common.ACTION_LIQUIDATIONLIST,
+		Request: common.LiquidationListRequest{}, Response: common.LiquidationListResponse{}, handler: Web.LiquidationList},
+	{Method: http.MethodPost, Path: common.WINGEARNINGS, Action: common.ACTION_WINGEARNINGS,
+		Request: common.WingEarningsRequest{}, Response: common.WingEarningsResponse{}, handler: Web.WingEarnings},
+	{Method: http.MethodPost, Path: common.WINGCLAIMHISTORY, Action: common.ACTION_WINGCLAIMHISTORY,
+		Request: common.WingClaimHistoryRequest{}, Response: common.WingClaimHistoryResponse{}, handler: Web.WingClaimHistory},
+
+	{Method: http.MethodGet, Path: common.FLASHPOOLMARKETDISTRIBUTION, Action: common.ACTION_FLASHPOOLMARKETDISTRIBUTION,
+		Response: common.FlashPoolMarketDistribution{}, handler: Web.FlashPoolMarketDistribution},
+	{Method: http.MethodGet, Path: common.POOLDISTRIBUTION, Action: common.ACTION_POOLDISTRIBUTION,
+		Response: common.PoolDistribution{}, handler: Web.PoolDistribution},
+	{Method: http.MethodGet, Path: common.GOVBANNEROVERVIEW, Action: common.ACTION_GOVBANNEROVERVIEW,
+		Response: common.GovBannerOverview{}, handler: Web.GovBannerOverview},
+	{Method: http.MethodGet, Path: common.GOVBANNER, Action: common.ACTION_GOVBANNER,
+		Response: common.GovBanner{}, handler: Web.GovBanner},
+	{Method: http.MethodGet, Path: common.RESERVES, Action: common.ACTION_RESERVES,
+		Response: common.Reserves{}, handler: Web.Reserves},
+	{Method: http.MethodGet, Path: common.FLASHPOOLDETAIL, Action: common.ACTION_FLASHPOOLDETAIL,
+		Response: common.FlashPoolDetail{}, handler: Web.FlashPoolDetail},
+	{Method: http.MethodGet, Path: common.FLASHPOOLBANNER, Action: common.ACTION_FLASHPOOLBANNER,
+		Response: common.FlashPoolBanner{}, handler: Web.FlashPoolBanner},
+	{Method: http.MethodGet, Path: common.FLASHPOOLALLMARKET, Action: common.ACTION_FLASHPOOLALLMARKET,
+		Response: common.FlashPoolAllMarket{}, handler: Web.FlashPoolAllMarket},
+	{Method: http.MethodGet, Path: common.BORROWADDRESSLIST, Action: common.ACTION_BORROWADDRESSLIST,
+		Response: []store.UserAssetBalance{}, handler: Web.BorrowAddressList},
+	{Method: http.MethodGet, Path: common.WINGAPYS, Action: common.ACTION_WINGAPYS,
+		Response: []common.WingApy{}, handler: Web.WingApys},
+	{Method: http.MethodGet, Path: common.EMISSIONPROJECTION, Action: common.ACTION_EMISSIONPROJECTION,
+		Request: common.EmissionProjectionRequest{}, Response: common.EmissionProjection{}, handler: Web.EmissionProjection},
+	{Method: http.MethodGet, Path: common.WINGSUPPLY, Action: common.ACTION_WINGSUPPLY,
+		Response: common.WingSupply{}, handler: Web.WingSupply},
+	{Method: http.MethodGet, Path: common.WINGHOLDERS, Action: common.ACTION_WINGHOLDERS,
+		Request: common.WingHoldersRequest{}, Response: common.WingHolders{}, handler: Web.WingHolders},
+	{Method: http.MethodGet, Path: common.GOVPROPOSALS, Action: common.ACTION_GOVPROPOSALS,
+		Request: common.GovProposalsRequest{}, Response: common.GovProposals{}, handler: Web.GovProposals},
+	{Method: http.MethodGet, Path: common.GOVPROPOSALDETAIL, Action: common.ACTION_GOVPROPOSALDETAIL,
+		Request: common.GovProposalDetailRequest{}, Response: common.GovProposalDetail{}, handler: Web.GovProposalDetail},
+	// served by restServer.batch, each entry runs one of the actions above
+	{Method: http.MethodPost, Path: common.BATCH, Request: []common.BatchRequest{}, Response: []common.Response{}},
+
+	{Method: http.MethodGet, Path: common.V2MARKETS, Response: []*common.ApiMarket{}, apiHandler: WebV2.V2Markets},
+	{Method: http.MethodGet, Path: common.V2MARKET, Response: common.ApiMarket{}, apiHandler: WebV2.V2Market},
+	{Method: http.MethodGet, Path: common.V2PRICES, Response: []*common.ApiPrice{}, apiHandler: WebV2.V2Prices},
+	{Method: http.MethodGet, Path: common.V2PRICE, Response: common.ApiPrice{}, apiHandler: WebV2.V2Price},
+	{Method: http.MethodGet, Path: common.V2RESERVES, Response: common.ApiReserves{}, apiHandler: WebV2.V2Reserves},
+	{Method: http.MethodGet, Path: common.V2USEROVERVIEW, Response: common.ApiUserOverview{},
+		apiHandler: WebV2.V2UserOverview},
+	{Method: http.MethodGet, Path: common.V2USERLIQUIDATIONS, Response: []*common.ApiLiquidation{},
+		apiHandler: WebV2.V2UserLiquidations},
+	{Method: http.MethodGet, Path: common.V2USERWINGEARNINGS, Response: common.ApiWingEarnings{},
+		apiHandler: WebV2.V2UserWingEarnings},
+	{Method: http.MethodGet, Path: common.V2USERWINGCLAIMS, Query: []string{"offset", "limit"},
+		Response: common.ApiWingClaimPage{}, apiHandler: WebV2.V2UserWingClaims},
+	{Method: http.MethodGet, Path: common.V2GOVERNANCE, Response: common.ApiGovernance{}, apiHandler: WebV2.V2Governance},
+	{Method: http.MethodGet, Path: common.V2GOVPROPOSALS, Query: []string{"status", "offset", "limit"},
+		Response: common.ApiProposalPage{}, apiHandler: WebV2.V2GovProposals},
+	{Method: http.MethodGet, Path: common.V2GOVPROPOSALDETAIL, Response: common.ApiProposalDetail{},
+		apiHandler: WebV2.V2GovProposalDetail},
+}
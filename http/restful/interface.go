@@ -1,6 +1,10 @@
 package restful
 
+import "net/http"
+
 type Web interface {
+	WebV2
+
 	FlashPoolMarketDistribution(map[string]interface{}) map[string]interface{}
 	PoolDistribution(map[string]interface{}) map[string]interface{}
 	GovBannerOverview(map[string]interface{}) map[string]interface{}
@@ -18,4 +22,27 @@ type Web interface {
 	ClaimWing(map[string]interface{}) map[string]interface{}
 	LiquidationList(map[string]interface{}) map[string]interface{}
 	WingApys(map[string]interface{}) map[string]interface{}
+
+	EmissionProjection(map[string]interface{}) map[string]interface{}
+	WingSupply(map[string]interface{}) map[string]interface{}
+	WingHolders(map[string]interface{}) map[string]interface{}
+	WingEarnings(map[string]interface{}) map[string]interface{}
+	WingClaimHistory(map[string]interface{}) map[string]interface{}
+	GovProposals(map[string]interface{}) map[string]interface{}
+	GovProposalDetail(map[string]interface{}) map[string]interface{}
+}
+
+type WebV2 interface {
+	V2Markets(*http.Request) (interface{}, error)
+	V2Market(*http.Request) (interface{}, error)
+	V2Prices(*http.Request) (interface{}, error)
+	V2Price(*http.Request) (interface{}, error)
+	V2Reserves(*http.Request) (interface{}, error)
+	V2UserOverview(*http.Request) (interface{}, error)
+	V2UserLiquidations(*http.Request) (interface{}, error)
+	V2UserWingEarnings(*http.Request) (interface{}, error)
+	V2UserWingClaims(*http.Request) (interface{}, error)
+	V2Governance(*http.Request) (interface{}, error)
+	V2GovProposals(*http.Request) (interface{}, error)
+	V2GovProposalDetail(*http.Request) (interface{}, error)
 }
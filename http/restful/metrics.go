@@ -0,0 +1,74 @@
+package restful
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/siovanus/wingServer/metrics"
+)
+
+// requests of unknown paths share one route label
+const unmatchedRoute = "unmatched"
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (this *statusWriter) WriteHeader(status int) {
+	if this.status == 0 {
+		this.status = status
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusWriter) Write(data []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	return this.ResponseWriter.Write(data)
+}
+
+// Flush keeps the event stream working through the middleware
+func (this *statusWriter) Flush() {
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack keeps the websocket upgrade working through the middleware
+func (this *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := this.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("statusWriter, http.Hijacker not implemented")
+	}
+	this.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// instrument records the requests of the router in the http metrics, labelled by route pattern so that
+// path parameters do not create new series. It is the first middleware so that rejected requests count too
+func (this *Router) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var route string
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+		if route == "" {
+			// answered by a middleware or not found
+			pattern, ok := this.pattern(r.URL.Path)
+			if !ok {
+				pattern = unmatchedRoute
+			}
+			route = pattern
+		}
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		metrics.ObserveHttp(route, r.Method, sw.status, start)
+	})
+}
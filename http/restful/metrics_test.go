@@ -0,0 +1,47 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/siovanus/wingServer/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	router := NewRouter()
+	router.Use(router.instrument, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Reject") != "" {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Get("/test/markets/:asset", func(w http.ResponseWriter, r *http.Request) {})
+
+	route := "/test/markets/:asset"
+	ok := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(route, http.MethodGet, "200"))
+	rejected := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(route, http.MethodGet, "429"))
+	unmatched := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404"))
+
+	for _, asset := range []string{"pUSDT", "pWBTC"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/markets/"+asset, nil))
+	}
+	r := httptest.NewRequest(http.MethodGet, "/test/markets/pUSDT", nil)
+	r.Header.Set("X-Reject", "1")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/unknown", nil))
+
+	if v := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(route, http.MethodGet, "200")) - ok; v != 2 {
+		t.Errorf("route counted %v requests, expected 2", v)
+	}
+	if v := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(route, http.MethodGet, "429")) - rejected; v != 1 {
+		t.Errorf("route counted %v rejected requests, expected 1", v)
+	}
+	if v := testutil.ToFloat64(metrics.HttpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404")) - unmatched; v != 1 {
+		t.Errorf("unmatched counted %v requests, expected 1", v)
+	}
+}
@@ -0,0 +1,348 @@
+package restful
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	RATE_CLASS_DEFAULT = "default"
+
+	API_KEY_HEADER = "X-Api-Key"
+	// browsers cannot set headers on websocket and event stream requests
+	API_KEY_QUERY = "apikey"
+
+	apiKeyCacheTTL     = time.Minute
+	maxApiKeyCacheSize = 10000
+	bucketSweepPeriod  = time.Minute
+	// batch bodies are cut to this size, the batch handler then rejects them as invalid json
+	maxBatchBodySize = 1 << 20
+)
+
+// probes of the orchestrator are neither limited nor asked for an api key
+var unlimited = map[string]bool{
+	common.HEALTHZ: true,
+	common.READYZ:  true,
+}
+
+// KeyStore looks up api keys by the hash of HashApiKey
+type KeyStore interface {
+	LoadApiKey(hash string) (store.ApiKey, error)
+}
+
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+type routeClass struct {
+	path  *regexp.Regexp
+	class string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// apiKeyEntry caches a key lookup, the name of unknown keys is empty
+type apiKeyEntry struct {
+	name   string
+	expire time.Time
+}
+
+// RateLimiter authenticates api keys and limits requests with a token bucket per api key, or per ip for
+// requests without key. Valid and unknown keys are both cached for a minute
+type RateLimiter struct {
+	cfg     *config.RateLimitConfig
+	keys    KeyStore
+	classes []*routeClass
+	proxies []*net.IPNet
+
+	// the cost in tokens of the requests of a path, other requests cost one
+	costs map[string]func(r *http.Request) int
+
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	apiKeys   map[string]*apiKeyEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter(cfg *config.RateLimitConfig, keys KeyStore) *RateLimiter {
+	limiter := &RateLimiter{
+		cfg:     cfg,
+		keys:    keys,
+		costs:   map[string]func(r *http.Request) int{common.BATCH: batchSize},
+		buckets: make(map[string]*bucket),
+		apiKeys: make(map[string]*apiKeyEntry),
+		now:     time.Now,
+	}
+	patterns := make([]string, 0, len(cfg.Routes))
+	for k := range cfg.Routes {
+		patterns = append(patterns, k)
+	}
+	sort.Strings(patterns)
+	for _, v := range patterns {
+		path, _ := compilePath(v)
+		limiter.classes = append(limiter.classes, &routeClass{path: path, class: cfg.Routes[v]})
+	}
+	for _, v := range cfg.TrustedProxies {
+		if !strings.Contains(v, "/") {
+			if strings.Contains(v, ":") {
+				v += "/128"
+			} else {
+				v += "/32"
+			}
+		}
+		_, proxy, err := net.ParseCIDR(v)
+		if err != nil {
+			log.Errorf("NewRateLimiter, invalid trusted proxy %s: %s", v, err)
+			continue
+		}
+		limiter.proxies = append(limiter.proxies, proxy)
+	}
+	limiter.lastSweep = limiter.now()
+	return limiter
+}
+
+// SetCost makes the requests of path cost the tokens returned by cost, which may read the body if it puts it
+// back. It is called before the server starts
+func (this *RateLimiter) SetCost(path string, cost func(r *http.Request) int) {
+	this.costs[path] = cost
+}
+
+// Middleware is added to the restful server with Use
+func (this *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || unlimited[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get(API_KEY_HEADER)
+		if key == "" {
+			key = r.URL.Query().Get(API_KEY_QUERY)
+		}
+		var subject string
+		if key != "" {
+			name, err := this.apiKeyName(key)
+			if err != nil {
+				log.FromContext(r.Context()).Errorf("RateLimiter, this.apiKeyName error: %s", err)
+				writeError(w, r, http.StatusServiceUnavailable, "api key could not be checked")
+				return
+			}
+			if name == "" {
+				writeError(w, r, http.StatusUnauthorized, "invalid api key")
+				return
+			}
+			subject = "key:" + name
+		} else {
+			if this.cfg.RequireApiKey {
+				writeError(w, r, http.StatusUnauthorized,
+					fmt.Sprintf("api key is required in the %s header", API_KEY_HEADER))
+				return
+			}
+			subject = "ip:" + this.clientIp(r)
+		}
+
+		class := this.class(r.URL.Path)
+		limits, ok := this.cfg.Classes[class]
+		if !ok {
+			limits = this.cfg.Classes[RATE_CLASS_DEFAULT]
+		}
+		if limits == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rate, burst := limits.IpRate, limits.IpBurst
+		if key != "" {
+			rate, burst = limits.KeyRate, limits.KeyBurst
+		}
+		if rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if burst == 0 {
+			burst = 1
+		}
+
+		// a batch costs a token per entry, a request costing more than the burst can never pass
+		cost := float64(this.cost(r))
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatUint(burst, 10))
+		if cost > float64(burst) {
+			writeError(w, r, http.StatusTooManyRequests,
+				fmt.Sprintf("request costs %d tokens, more than the burst of %d", uint64(cost), burst))
+			return
+		}
+		allowed, remaining, reset := this.take(class+"|"+subject, rate, float64(burst), cost)
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatUint(uint64(remaining), 10))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(reset.Seconds())), 10))
+		if !allowed {
+			retry := time.Duration(float64(time.Second) * (cost - remaining) / rate)
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retry.Seconds())), 10))
+			writeError(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take removes cost tokens from the bucket, it returns whether the tokens were available, the tokens left
+// and the time until the bucket is full again
+func (this *RateLimiter) take(id string, rate, burst, cost float64) (bool, float64, time.Duration) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := this.now()
+	if now.Sub(this.lastSweep) > bucketSweepPeriod {
+		this.sweep(now)
+	}
+	b, ok := this.buckets[id]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		this.buckets[id] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	allowed := b.tokens >= cost
+	if allowed {
+		b.tokens -= cost
+	}
+	reset := time.Duration(float64(time.Second) * (burst - b.tokens) / rate)
+	return allowed, b.tokens, reset
+}
+
+// sweep drops the buckets idle for a sweep period, which are full again unless their class refills
+// slower than burst per minute
+func (this *RateLimiter) sweep(now time.Time) {
+	for k, v := range this.buckets {
+		if now.Sub(v.last) > bucketSweepPeriod {
+			delete(this.buckets, k)
+		}
+	}
+	this.lastSweep = now
+}
+
+func (this *RateLimiter) cost(r *http.Request) int {
+	cost, ok := this.costs[r.URL.Path]
+	if !ok {
+		return 1
+	}
+	if n := cost(r); n > 1 {
+		return n
+	}
+	return 1
+}
+
+func (this *RateLimiter) class(path string) string {
+	for _, v := range this.classes {
+		if v.path.MatchString(path) {
+			return v.class
+		}
+	}
+	return RATE_CLASS_DEFAULT
+}
+
+// apiKeyName returns the name of a valid key, or an empty string for unknown keys
+func (this *RateLimiter) apiKeyName(key string) (string, error) {
+	hash := HashApiKey(key)
+	now := this.now()
+	this.lock.Lock()
+	entry, ok := this.apiKeys[hash]
+	this.lock.Unlock()
+	if ok && now.Before(entry.expire) {
+		return entry.name, nil
+	}
+	apiKey, err := this.keys.LoadApiKey(hash)
+	if err != nil && !store.IsRecordNotFound(err) {
+		return "", err
+	}
+	entry = &apiKeyEntry{expire: now.Add(apiKeyCacheTTL)}
+	if err == nil {
+		entry.name = apiKey.Name
+	}
+	this.lock.Lock()
+	if len(this.apiKeys) >= maxApiKeyCacheSize {
+		this.apiKeys = make(map[string]*apiKeyEntry)
+	}
+	this.apiKeys[hash] = entry
+	this.lock.Unlock()
+	return entry.name, nil
+}
+
+// clientIp is the address connecting to the server, or behind a trusted proxy the rightmost address of
+// X-Forwarded-For which is not a trusted proxy. The addresses left of it are set by the client
+func (this *RateLimiter) clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !this.cfg.TrustProxy {
+		return host
+	}
+	var forwarded []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(v, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !this.trustedProxy(ip) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+func (this *RateLimiter) trustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, v := range this.proxies {
+		if v.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchSize is the number of entries of a batch call, 1 for other requests. The body is read and put back
+// for the batch handler
+func batchSize(r *http.Request) int {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return 1
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBatchBodySize))
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 1
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(body, &entries); err != nil || len(entries) == 0 {
+		return 1
+	}
+	return len(entries)
+}
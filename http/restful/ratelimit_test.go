@@ -0,0 +1,192 @@
+package restful
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+type fakeKeyStore map[string]string
+
+func (this fakeKeyStore) LoadApiKey(hash string) (store.ApiKey, error) {
+	for key, name := range this {
+		if HashApiKey(key) == hash {
+			return store.ApiKey{Hash: hash, Name: name}, nil
+		}
+	}
+	return store.ApiKey{}, gorm.ErrRecordNotFound
+}
+
+func newTestLimiter(requireApiKey bool) (*RateLimiter, *time.Time, http.Handler) {
+	cfg := &config.RateLimitConfig{
+		RequireApiKey: requireApiKey,
+		Routes:        map[string]string{"/api/v2/users/:address/overview": "rpc"},
+		Classes: map[string]*config.RateLimitClass{
+			RATE_CLASS_DEFAULT: {IpRate: 1, IpBurst: 2, KeyRate: 10, KeyBurst: 5},
+			"rpc":              {IpRate: 0.5, IpBurst: 1},
+		},
+	}
+	limiter := NewRateLimiter(cfg, fakeKeyStore{"secret": "frontend"})
+	now := time.Unix(1600000000, 0)
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	return limiter, &now, handler
+}
+
+func serve(handler http.Handler, path string, key string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		r.Header.Set(API_KEY_HEADER, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitPerIp(t *testing.T) {
+	_, now, handler := newTestLimiter(false)
+	for i := 0; i < 2; i++ {
+		w := serve(handler, "/api/v1/reserves", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: unexpected status %d", i, w.Code)
+		}
+	}
+	w := serve(handler, "/api/v1/reserves", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" ||
+		w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	// the class of the route has its own bucket
+	if w := serve(handler, "/api/v2/users/AXxK3ZW1k8VFsbF8ZpV8Cp7ZTKXNgFVqyS/overview", ""); w.Code != http.StatusOK {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+	if w := serve(handler, "/api/v2/users/AXxK3ZW1k8VFsbF8ZpV8Cp7ZTKXNgFVqyS/overview", ""); w.Code != http.StatusTooManyRequests ||
+		w.Header().Get("Content-Type") != "application/problem+json" || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	*now = now.Add(time.Second)
+	if w := serve(handler, "/api/v1/reserves", ""); w.Code != http.StatusOK {
+		t.Errorf("bucket not refilled, status %d", w.Code)
+	}
+}
+
+func TestRateLimitPerKey(t *testing.T) {
+	_, _, handler := newTestLimiter(true)
+	if w := serve(handler, "/api/v1/reserves", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("request without key answered %d", w.Code)
+	}
+	if w := serve(handler, "/api/v1/reserves", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("request with unknown key answered %d", w.Code)
+	}
+	for i := 0; i < 5; i++ {
+		if w := serve(handler, "/api/v1/reserves", "secret"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: unexpected status %d", i, w.Code)
+		}
+	}
+	if w := serve(handler, "/api/v1/reserves", "secret"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+	// the rpc class has no key limit
+	if w := serve(handler, "/api/v2/users/AXxK3ZW1k8VFsbF8ZpV8Cp7ZTKXNgFVqyS/overview", "secret"); w.Code != http.StatusOK ||
+		w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestClientIp(t *testing.T) {
+	limiter := NewRateLimiter(&config.RateLimitConfig{TrustProxy: true, TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}},
+		fakeKeyStore{})
+	cases := []struct {
+		forwarded string
+		expected  string
+	}{
+		{"", "10.1.1.1"},
+		{"1.1.1.1", "1.1.1.1"},
+		// the client prepends a forged address, the one appended by the proxy is taken
+		{"6.6.6.6, 1.1.1.1", "1.1.1.1"},
+		{"6.6.6.6, 1.1.1.1, 10.2.2.2, 192.168.1.1", "1.1.1.1"},
+		{"10.2.2.2, 192.168.1.1", "10.2.2.2"},
+	}
+	for _, v := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/reserves", nil)
+		r.RemoteAddr = "10.1.1.1:1234"
+		if v.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", v.forwarded)
+		}
+		if ip := limiter.clientIp(r); ip != v.expected {
+			t.Errorf("X-Forwarded-For %q: expected %s, got %s", v.forwarded, v.expected, ip)
+		}
+	}
+
+	limiter = NewRateLimiter(&config.RateLimitConfig{}, fakeKeyStore{})
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/reserves", nil)
+	r.RemoteAddr = "10.1.1.1:1234"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6")
+	if ip := limiter.clientIp(r); ip != "10.1.1.1" {
+		t.Errorf("X-Forwarded-For trusted without proxy: %s", ip)
+	}
+}
+
+func TestRateLimitBatch(t *testing.T) {
+	limiter, now, _ := newTestLimiter(false)
+	var body string
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	batch := func(entries string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, common.BATCH, strings.NewReader(entries)))
+		return w.Code
+	}
+	// the default class has a burst of 2, a batch of 2 empties it
+	two := `[{"action":"reserves"},{"action":"reserves"}]`
+	if code := batch(two); code != http.StatusOK || body != two {
+		t.Fatalf("unexpected response %d, body %q", code, body)
+	}
+	if code := batch(`[{"action":"reserves"}]`); code != http.StatusTooManyRequests {
+		t.Errorf("batch should have taken a token per entry, got %d", code)
+	}
+	// larger batches cost more than the burst and are rejected even once the bucket is full
+	*now = now.Add(2 * time.Second)
+	if code := batch(`[{"action":"reserves"},{"action":"reserves"},{"action":"reserves"}]`); code !=
+		http.StatusTooManyRequests {
+		t.Errorf("unexpected status %d", code)
+	}
+	if code := batch(two); code != http.StatusOK {
+		t.Errorf("the rejected batch should not have taken tokens, got %d", code)
+	}
+}
+
+func TestRateLimitCost(t *testing.T) {
+	limiter, _, handler := newTestLimiter(false)
+	limiter.SetCost("/api/graphql", func(r *http.Request) int {
+		n, _ := strconv.Atoi(r.URL.Query().Get("cost"))
+		return n
+	})
+	// the default class has a burst of 2
+	for _, v := range []struct {
+		path string
+		code int
+	}{
+		{"/api/graphql?cost=0", http.StatusOK},
+		{"/api/graphql?cost=3", http.StatusTooManyRequests},
+		{"/api/graphql?cost=1", http.StatusOK},
+		{"/api/graphql?cost=1", http.StatusTooManyRequests},
+	} {
+		if w := serve(handler, v.path, ""); w.Code != v.code {
+			t.Errorf("%s: status %d, expected %d", v.path, w.Code, v.code)
+		}
+	}
+}
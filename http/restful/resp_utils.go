@@ -1,5 +1,13 @@
 package restful
 
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
 func PackResponse(errCode uint32) map[string]interface{} {
 	resp := map[string]interface{}{
 		"action": "",
@@ -9,3 +17,18 @@ func PackResponse(errCode uint32) map[string]interface{} {
 	}
 	return resp
 }
+
+// WriteResponse answers with a common.Response and an http status, for the admin endpoints
+func WriteResponse(w http.ResponseWriter, status int, errCode uint32, desc string, result interface{}) {
+	if desc == "" {
+		desc = ErrMap[errCode]
+	}
+	data, err := json.Marshal(&common.Response{Desc: desc, Error: errCode, Result: result})
+	if err != nil {
+		log.Errorf("WriteResponse, json.Marshal error: %s", err)
+		return
+	}
+	w.Header().Set("content-type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
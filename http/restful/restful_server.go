@@ -8,22 +8,22 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
-	"sync"
 	"time"
 
-	"github.com/siovanus/wingServer/http/common"
 	"github.com/siovanus/wingServer/log"
 )
 
 type ApiServer interface {
 	Start() error
 	Stop()
+	Shutdown(ctx context.Context) error
+	Handle(method string, path string, handler http.HandlerFunc)
+	Use(middlewares ...Middleware)
 }
 
 type handler func(map[string]interface{}) map[string]interface{}
 
 type Action struct {
-	sync.RWMutex
 	name    string
 	handler handler
 }
@@ -33,49 +33,67 @@ type restServer struct {
 	port     uint64
 	listener net.Listener
 	server   *http.Server
+	cache    *ResponseCache
 	postMap  map[string]Action //post method map
 	getMap   map[string]Action //get method map
+	actions  map[string]Action //actions by name, for batch calls
 }
 
-//init restful server
-func InitRestServer(web Web, port uint64) ApiServer {
+//init restful server, cache may be nil
+func InitRestServer(web Web, port uint64, cache *ResponseCache) ApiServer {
+	rt := newRestServer(port, cache)
+	rt.registryRestServerAction(web)
+	rt.initGetHandler()
+	rt.initPostHandler()
+	rt.initBatchHandler()
+	rt.registryApiV2(web)
+	return rt
+}
+
+//init a server without the api, serving only the handlers registered with Handle
+func InitBareServer(port uint64) ApiServer {
+	return newRestServer(port, nil)
+}
+
+func newRestServer(port uint64, cache *ResponseCache) *restServer {
+	if cache == nil {
+		cache = NewResponseCache(nil)
+	}
 	rt := &restServer{
-		port: port,
+		port:  port,
+		cache: cache,
 	}
 
 	rt.router = NewRouter()
+	rt.router.Use(rt.router.instrument, RequestLog)
+	rt.router.NotFound = rt.notFound
+	rt.router.MethodNotAllowed = rt.methodNotAllowed
 	rt.getMap = make(map[string]Action)
 	rt.postMap = make(map[string]Action)
-	rt.registryRestServerAction(web)
-	rt.initGetHandler()
-	rt.initPostHandler()
+	rt.actions = make(map[string]Action)
 	return rt
 }
 
 //resigtry handler method
 func (this *restServer) registryRestServerAction(web Web) {
-
-	postMethodMap := map[string]Action{
-		common.USERFLASHPOOLOVERVIEW: {name: common.ACTION_USERFLASHPOOLOVERVIEW, handler: web.UserFlashPoolOverview},
-		common.ASSETPRICE:            {name: common.ACTION_ASSETPRICE, handler: web.AssetPrice},
-		common.ASSETPRICELIST:        {name: common.ACTION_ASSETPRICELIST, handler: web.AssetPriceList},
-		common.CLAIMWING:             {name: common.ACTION_CLAIMWING, handler: web.ClaimWing},
-		common.LIQUIDATIONLIST:       {name: common.ACTION_LIQUIDATIONLIST, handler: web.LiquidationList},
-	}
-	getMethodMap := map[string]Action{
-		common.FLASHPOOLMARKETDISTRIBUTION: {name: common.ACTION_FLASHPOOLMARKETDISTRIBUTION, handler: web.FlashPoolMarketDistribution},
-		common.POOLDISTRIBUTION:            {name: common.ACTION_POOLDISTRIBUTION, handler: web.PoolDistribution},
-		common.GOVBANNEROVERVIEW:           {name: common.ACTION_GOVBANNEROVERVIEW, handler: web.GovBannerOverview},
-		common.GOVBANNER:                   {name: common.ACTION_GOVBANNER, handler: web.GovBanner},
-		common.RESERVES:                    {name: common.ACTION_RESERVES, handler: web.Reserves},
-		common.FLASHPOOLDETAIL:             {name: common.ACTION_FLASHPOOLDETAIL, handler: web.FlashPoolDetail},
-		common.FLASHPOOLBANNER:             {name: common.ACTION_FLASHPOOLBANNER, handler: web.FlashPoolBanner},
-		common.FLASHPOOLALLMARKET:          {name: common.ACTION_FLASHPOOLALLMARKET, handler: web.FlashPoolAllMarket},
-		common.BORROWADDRESSLIST:           {name: common.ACTION_BORROWADDRESSLIST, handler: web.BorrowAddressList},
-		common.WINGAPYS:                    {name: common.ACTION_WINGAPYS, handler: web.WingApys},
+	for _, v := range endpoints {
+		if v.handler == nil {
+			continue
+		}
+		handler := v.handler
+		action := Action{
+			name: v.Action,
+			handler: func(params map[string]interface{}) map[string]interface{} {
+				return handler(web, params)
+			},
+		}
+		this.actions[v.Action] = action
+		if v.Method == http.MethodPost {
+			this.postMap[v.Path] = action
+		} else {
+			this.getMap[v.Path] = action
+		}
 	}
-	this.postMap = postMethodMap
-	this.getMap = getMethodMap
 }
 
 //start server
@@ -96,7 +114,7 @@ func (this *restServer) Start() error {
 	this.server = &http.Server{Handler: this.router}
 	err = this.server.Serve(this.listener)
 
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal("ListenAndServe: ", err.Error())
 		return err
 	}
@@ -104,41 +122,48 @@ func (this *restServer) Start() error {
 	return nil
 }
 
-func (this *restServer) getPath(url string) string {
-	return url
+//register a raw http handler, for endpoints not speaking the action protocol, they are not in openapi.json
+func (this *restServer) Handle(method string, path string, handler http.HandlerFunc) {
+	this.router.add(method, path, handler)
+}
+
+//add middlewares wrapping every request of the server
+func (this *restServer) Use(middlewares ...Middleware) {
+	this.router.Use(middlewares...)
 }
 
+//query parameters, overridden by path parameters of the route
 func (this *restServer) getUrlParams(r *http.Request) map[string]interface{} {
 	values := r.URL.Query()
 	params := make(map[string]interface{})
 	for name, value := range values {
 		params[name] = value[0]
 	}
+	if p, ok := r.Context().Value(paramsKey{}).(paramsMap); ok {
+		for name, value := range p {
+			params[name] = value
+		}
+	}
 	return params
 }
 
 //init get Handler
 func (this *restServer) initGetHandler() {
-	for k := range this.getMap {
-		this.router.Get(k, func(w http.ResponseWriter, r *http.Request) {
-			var resp map[string]interface{}
-			url := this.getPath(r.URL.Path)
-			if h, ok := this.getMap[url]; ok {
-				req := this.getUrlParams(r)
-				resp = h.handler(req)
-				resp["action"] = h.name
-			} else {
-				resp = PackResponse(INVALID_METHOD)
-				resp["action"] = h.name
-			}
+	for k, v := range this.getMap {
+		action := v
+		this.router.Get(k, this.cache.wrap(k, func(w http.ResponseWriter, r *http.Request) {
+			req := this.getUrlParams(r)
+			resp := action.handler(req)
+			resp["action"] = action.name
 			this.response(w, resp)
-		})
+		}))
 	}
 }
 
 //init post Handler
 func (this *restServer) initPostHandler() {
-	for k := range this.postMap {
+	for k, v := range this.postMap {
+		action := v
 		this.router.Post(k, func(w http.ResponseWriter, r *http.Request) {
 
 			body, _ := ioutil.ReadAll(r.Body)
@@ -147,19 +172,18 @@ func (this *restServer) initPostHandler() {
 			var req = make(map[string]interface{})
 			var resp map[string]interface{}
 
-			url := this.getPath(r.URL.Path)
-			if h, ok := this.postMap[url]; ok {
-				if err := json.Unmarshal(body, &req); err == nil {
-					resp = h.handler(req)
-				} else {
-					log.Error("unmarshal body error:", err)
-					resp = PackResponse(ILLEGAL_DATAFORMAT)
+			if err := json.Unmarshal(body, &req); err == nil {
+				for name, value := range this.getUrlParams(r) {
+					if _, ok := req[name]; !ok {
+						req[name] = value
+					}
 				}
-				resp["action"] = h.name
+				resp = action.handler(req)
 			} else {
-				resp = PackResponse(INVALID_METHOD)
-				resp["action"] = h.name
+				log.FromContext(r.Context()).Errorf("unmarshal body error: %s", err)
+				resp = PackResponse(ILLEGAL_DATAFORMAT)
 			}
+			resp["action"] = action.name
 			this.response(w, resp)
 		})
 	}
@@ -172,14 +196,17 @@ func (this *restServer) initPostHandler() {
 
 }
 func (this *restServer) write(w http.ResponseWriter, data []byte) {
-	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, "+API_KEY_HEADER)
 	w.Header().Set("content-type", "application/json;charset=utf-8")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Write(data)
 }
 
-//response
+//response, failures are marked no-store so that they are never cached
 func (this *restServer) response(w http.ResponseWriter, resp map[string]interface{}) {
+	if resp["error"].(uint32) != SUCCESS {
+		w.Header().Set("Cache-Control", "no-store")
+	}
 	resp["desc"] = ErrMap[resp["error"].(uint32)]
 	data, err := json.Marshal(resp)
 	if err != nil {
@@ -191,10 +218,17 @@ func (this *restServer) response(w http.ResponseWriter, resp map[string]interfac
 
 //stop restful server
 func (this *restServer) Stop() {
-	if this.server != nil {
-		this.server.Shutdown(context.Background())
-		log.Error("Close restful ")
+	this.Shutdown(context.Background())
+}
+
+//stop accepting connections and wait for the running requests until ctx is done
+func (this *restServer) Shutdown(ctx context.Context) error {
+	if this.server == nil {
+		return nil
 	}
+	err := this.server.Shutdown(ctx)
+	log.Info("Close restful ")
+	return err
 }
 
 //restart server
@@ -2,61 +2,104 @@ package restful
 
 import (
 	"context"
-	"errors"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 type paramsMap map[string]string
 
+type paramsKey struct{}
+
+// routeKey holds a *string set to the pattern of the matched route, for the metrics middleware
+type routeKey struct{}
+
+// Middleware wraps a handler, middlewares run in the order they are added
+type Middleware func(http.Handler) http.Handler
+
 //http router
 type Route struct {
 	Method  string
+	Pattern string
 	Path    *regexp.Regexp
 	Params  []string
 	Handler http.HandlerFunc
 }
 type Router struct {
-	routes []*Route
+	routes      []*Route
+	middlewares []Middleware
+	// optional handlers of unknown paths and of known paths with an unregistered method
+	NotFound         http.HandlerFunc
+	MethodNotAllowed http.HandlerFunc
 }
 
 func NewRouter() *Router {
 	return &Router{}
 }
 
-func (this *Router) Try(path string, method string) (http.HandlerFunc, paramsMap, error) {
-
+// Try finds the handler of path and method, HEAD falls back to the GET handler. If the path matches
+// routes of other methods only, the handler is nil and the allowed methods are returned.
+func (this *Router) Try(path string, method string) (http.HandlerFunc, paramsMap, []string) {
+	var allowed []string
+	var get *Route
 	for _, route := range this.routes {
+		if !route.Path.MatchString(path) {
+			continue
+		}
 		if route.Method == method {
-			match := route.Path.MatchString(path)
-			if match == false {
-				continue
-			}
-			params := paramsMap{}
-			if len(route.Params) > 0 {
-				params = parseParams(route, path)
-			}
-			return route.Handler, params, nil
+			return route.Handler, parseParams(route, path), nil
+		}
+		if route.Method == http.MethodGet && get == nil {
+			get = route
+		}
+		allowed = append(allowed, route.Method)
+	}
+	if method == http.MethodHead && get != nil {
+		return get.Handler, parseParams(get, path), nil
+	}
+	return nil, paramsMap{}, allowed
+}
 
+// pattern returns the pattern of the first route matching path, for requests answered before dispatch
+func (this *Router) pattern(path string) (string, bool) {
+	for _, route := range this.routes {
+		if route.Path.MatchString(path) {
+			return route.Pattern, true
 		}
 	}
-	return nil, paramsMap{}, errors.New("Route not found")
+	return "", false
+}
 
+func (this *Router) Use(middlewares ...Middleware) {
+	this.middlewares = append(this.middlewares, middlewares...)
 }
 
 func (this *Router) add(method string, path string, handler http.HandlerFunc) {
 	route := &Route{}
 	route.Method = method
-	path = "^" + path + "$"
-	route.Handler = handler
+	route.Pattern = path
+	route.Handler = func(w http.ResponseWriter, r *http.Request) {
+		if pattern, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*pattern = path
+		}
+		handler(w, r)
+	}
+	route.Path, route.Params = compilePath(path)
+	this.routes = append(this.routes, route)
+}
 
+// compilePath turns a route like /api/v2/markets/:asset into a regexp matching its paths and
+// the names of its placeholders
+func compilePath(path string) (*regexp.Regexp, []string) {
+	var params []string
+	path = "^" + path + "$"
 	if strings.Contains(path, ":") {
 		matches := regexp.MustCompile(`:(\w+)`).FindAllStringSubmatch(path, -1)
 		if matches != nil {
 			for _, v := range matches {
-				route.Params = append(route.Params, v[1])
-				path = strings.Replace(path, v[0], `(\w+)`, 1)
+				params = append(params, v[1])
+				path = strings.Replace(path, v[0], `([^/]+)`, 1)
 			}
 		}
 	}
@@ -64,8 +107,11 @@ func (this *Router) add(method string, path string, handler http.HandlerFunc) {
 	if err != nil {
 		panic(err)
 	}
-	route.Path = compiledPath
-	this.routes = append(this.routes, route)
+	return compiledPath, params
+}
+
+func (r *Router) Handle(method string, path string, handler http.HandlerFunc) {
+	r.add(method, path, handler)
 }
 
 func (r *Router) Head(path string, handler http.HandlerFunc) {
@@ -97,18 +143,58 @@ func (r *Router) Options(path string, handler http.HandlerFunc) {
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	handler, params, err := r.Try(req.URL.Path, req.Method)
-	if err != nil {
-		http.NotFound(w, req)
+	var handler http.Handler = http.HandlerFunc(r.dispatch)
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	handler.ServeHTTP(w, req)
+}
+
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	handler, params, allowed := r.Try(req.URL.Path, req.Method)
+	if handler == nil {
+		if len(allowed) == 0 {
+			if r.NotFound != nil {
+				r.NotFound(w, req)
+			} else {
+				http.NotFound(w, req)
+			}
+			return
+		}
+		w.Header().Set("Allow", allowHeader(allowed))
+		if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed(w, req)
+		} else {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
 		return
 	}
-	ctx := context.WithValue(req.Context(), "params", params)
+	ctx := context.WithValue(req.Context(), paramsKey{}, params)
 	handler(w, req.WithContext(ctx))
 }
 
+func allowHeader(methods []string) string {
+	set := make(map[string]bool)
+	for _, v := range methods {
+		set[v] = true
+	}
+	if set[http.MethodGet] {
+		set[http.MethodHead] = true
+	}
+	allowed := make([]string, 0, len(set))
+	for k := range set {
+		allowed = append(allowed, k)
+	}
+	sort.Strings(allowed)
+	return strings.Join(allowed, ", ")
+}
+
 func parseParams(route *Route, path string) paramsMap {
-	matches := route.Path.FindAllStringSubmatch(path, -1)
 	params := paramsMap{}
+	if len(route.Params) == 0 {
+		return params
+	}
+	matches := route.Path.FindAllStringSubmatch(path, -1)
 	matchedParams := matches[0][1:]
 
 	for k, v := range matchedParams {
@@ -116,3 +202,9 @@ func parseParams(route *Route, path string) paramsMap {
 	}
 	return params
 }
+
+// Param returns the value of a :name placeholder of the matched route
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(paramsMap)
+	return params[name]
+}
@@ -0,0 +1,93 @@
+package restful
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+func TestRouterParams(t *testing.T) {
+	router := NewRouter()
+	router.Get("/api/v2/users/:address/overview", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "address")))
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo/overview", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/a/b/overview", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/api/v2/markets/:asset", handler)
+	router.Options("/api/v2/markets/:asset", handler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/markets/pUSDT", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("unexpected Allow header %s", allow)
+	}
+}
+
+func TestRouterHead(t *testing.T) {
+	router := NewRouter()
+	router.Get("/api/v2/markets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "get")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/api/v2/markets", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Test") != "get" {
+		t.Errorf("HEAD not served by GET handler, %d", w.Code)
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	router := NewRouter()
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	router.Use(mark("first"), mark("second"))
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "handler" {
+		t.Errorf("unexpected order %v", order)
+	}
+}
+
+func TestBareServer(t *testing.T) {
+	rt := InitBareServer(0).(*restServer)
+	rt.Handle(http.MethodGet, common.HEALTHZ, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, common.HEALTHZ, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, common.RESERVES, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("api should not be served, got %d", w.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
+const internalErrorDetail = "internal error, see the server logs of the request id"
+
+// registryApiV2 registers the v2 routes, results are written as JSON and errors as RFC 7807 problems,
+// with the status of a *common.ApiError or 500 for any other error
+func (this *restServer) registryApiV2(web WebV2) {
+	for _, v := range endpoints {
+		if v.apiHandler == nil {
+			continue
+		}
+		handler := v.apiHandler
+		this.router.Get(v.Path, this.cache.wrap(v.Path, func(w http.ResponseWriter, r *http.Request) {
+			result, err := handler(web, r)
+			if err != nil {
+				apiErr, ok := err.(*common.ApiError)
+				if !ok {
+					// the rpc and database errors stay in the logs, found by the request id
+					log.FromContext(r.Context()).Errorf("%s error: %s", r.URL.Path, err)
+					apiErr = &common.ApiError{Status: http.StatusInternalServerError, Detail: internalErrorDetail}
+				}
+				WriteProblem(w, r, apiErr.Status, apiErr.Detail)
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				log.FromContext(r.Context()).Errorf("%s json.Marshal error: %s", r.URL.Path, err)
+				WriteProblem(w, r, http.StatusInternalServerError, internalErrorDetail)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Write(data)
+		}))
+	}
+}
+
+// WriteProblem answers with an RFC 7807 problem body
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	data, _ := json.Marshal(&common.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeError answers v2 paths with a problem and v1 paths with plain text
+func writeError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	if strings.HasPrefix(r.URL.Path, common.V2PREFIX) {
+		WriteProblem(w, r, status, detail)
+		return
+	}
+	http.Error(w, detail, status)
+}
+
+// 404 and 405 of v2 paths are answered as problems, v1 keeps the plain text answers
+func (this *restServer) notFound(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, common.V2PREFIX) {
+		WriteProblem(w, r, http.StatusNotFound, "")
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (this *restServer) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, common.V2PREFIX) {
+		WriteProblem(w, r, http.StatusMethodNotAllowed, "")
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,42 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+)
+
+func TestProblemForV2Paths(t *testing.T) {
+	rt := &restServer{router: NewRouter()}
+	rt.router.NotFound = rt.notFound
+	rt.router.MethodNotAllowed = rt.methodNotAllowed
+	rt.router.Get(common.V2MARKET, func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/markets/pUSDT", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	problem := &common.Problem{}
+	if err := json.Unmarshal(w.Body.Bytes(), problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Status != http.StatusMethodNotAllowed || problem.Instance != "/api/v2/markets/pUSDT" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+
+	w = httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/unknown", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = httptest.NewRecorder()
+	rt.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") == "application/problem+json" {
+		t.Errorf("v1 answered with a problem")
+	}
+}
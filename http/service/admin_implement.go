@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	ocommon "github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+// RefreshAddress validates address and refreshes its balances, for the admin api
+func (this *Service) RefreshAddress(address string) error {
+	if _, err := ocommon.AddressFromBase58(address); err != nil {
+		return common.BadRequest(fmt.Sprintf("invalid address %s", address))
+	}
+	return this.RefreshAccount(address)
+}
+
+// RewindTrackHeight moves the indexer blocks back from its current height
+func (this *Service) RewindTrackHeight(blocks uint32) error {
+	trackHeight := this.TrackHeight()
+	if blocks > trackHeight {
+		return common.BadRequest(fmt.Sprintf("cannot rewind %d blocks from height %d", blocks, trackHeight))
+	}
+	return this.SetTrackHeight(trackHeight - blocks)
+}
+
+func (this *Service) IndexerStatus() (*common.IndexerStatus, error) {
+	chainHeight, err := this.sdk.GetCurrentBlockHeight()
+	if err != nil {
+		return nil, fmt.Errorf("IndexerStatus, this.sdk.GetCurrentBlockHeight error: %s", err)
+	}
+	status := &common.IndexerStatus{
+		TrackHeight: this.TrackHeight(),
+		ChainHeight: chainHeight,
+		QueueDepth:  atomic.LoadInt64(&this.inFlight),
+	}
+	if !this.Indexing() {
+		// the leader indexes, its progress is in the database
+		status.TrackHeight, err = this.store.LoadTrackHeight()
+		if err != nil && !store.IsRecordNotFound(err) {
+			return nil, fmt.Errorf("IndexerStatus, this.store.LoadTrackHeight error: %s", err)
+		}
+	}
+	if chainHeight > status.TrackHeight {
+		status.Lag = chainHeight - status.TrackHeight
+	}
+	return status, nil
+}
+
+// ReloadConfig applies the reloadable settings of fileName and listens to the markets of the new asset map
+func (this *Service) ReloadConfig(fileName string) (*common.ConfigReload, error) {
+	restart, err := this.cfg.Reload(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("ReloadConfig, this.cfg.Reload error: %s", err)
+	}
+	if len(restart) != 0 {
+		log.Warnf("ReloadConfig, changes of %v are applied after a restart", restart)
+	}
+	err = this.updateListeningAddressList()
+	if err != nil {
+		return nil, fmt.Errorf("ReloadConfig, this.updateListeningAddressList error: %s", err)
+	}
+	return &common.ConfigReload{Restart: restart}, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	hcommon "github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/utils"
+)
+
+const (
+	EventMint               = "Mint"
+	EventRedeem             = "Redeem"
+	EventBorrow             = "Borrow"
+	EventRepayBorrow        = "RepayBorrow"
+	EventLiquidateBorrow    = "LiquidateBorrow"
+	EventPutUnderlyingPrice = "PutUnderlyingPrice"
+)
+
+// decodeProtocolEvent turns a notification of a market into a protocol event, market events are laid out as
+//
+//	["Mint", minter, mintAmount, mintTokens]
+//	["Redeem", redeemer, redeemAmount, redeemTokens]
+//	["Borrow", borrower, borrowAmount, accountBorrows, totalBorrows]
+//	["RepayBorrow", payer, borrower, repayAmount, accountBorrows, totalBorrows]
+//	["LiquidateBorrow", liquidator, borrower, repayAmount, collateralMarket, seizeTokens]
+//
+// Unknown notifications return nil. The oracle notification is not decoded, a PutUnderlyingPrice only
+// triggers a price feed which reads the prices from the oracle, see OraclePriceFeed.
+func (this *Service) decodeProtocolEvent(contract string, neovm bool, states []interface{},
+	listeningAddressList []string) *store.ProtocolEvent {
+	name, _ := states[0].(string)
+	asset, ok := this.cfg.Get().AssetMap[contract]
+	if !ok || !listContains(listeningAddressList, contract) {
+		return nil
+	}
+	protocolEvent := &store.ProtocolEvent{Asset: asset}
+	switch {
+	case name == EventMint && len(states) > 2:
+		protocolEvent.Type = hcommon.EVENT_TYPE_SUPPLY
+		protocolEvent.Account = utils.ParseString(states[1])
+		protocolEvent.Amount = this.eventAmount(neovm, states[2], asset)
+		if amount, err := utils.ParseAmount(states[2], neovm); err != nil || !this.largeSupply(asset, amount) {
+			return nil
+		}
+	case name == EventRedeem && len(states) > 2:
+		protocolEvent.Type = hcommon.EVENT_TYPE_WITHDRAW
+		protocolEvent.Account = utils.ParseString(states[1])
+		protocolEvent.Amount = this.eventAmount(neovm, states[2], asset)
+	case name == EventBorrow && len(states) > 2:
+		protocolEvent.Type = hcommon.EVENT_TYPE_BORROW
+		protocolEvent.Account = utils.ParseString(states[1])
+		protocolEvent.Amount = this.eventAmount(neovm, states[2], asset)
+	case name == EventRepayBorrow && len(states) > 3:
+		protocolEvent.Type = hcommon.EVENT_TYPE_REPAY
+		protocolEvent.Account = utils.ParseString(states[2])
+		protocolEvent.Counterparty = utils.ParseString(states[1])
+		protocolEvent.Amount = this.eventAmount(neovm, states[3], asset)
+	case name == EventLiquidateBorrow && len(states) > 3:
+		protocolEvent.Type = hcommon.EVENT_TYPE_LIQUIDATION
+		protocolEvent.Account = utils.ParseString(states[2])
+		protocolEvent.Counterparty = utils.ParseString(states[1])
+		protocolEvent.Amount = this.eventAmount(neovm, states[3], asset)
+	default:
+		return nil
+	}
+	if _, err := common.AddressFromBase58(protocolEvent.Account); err != nil {
+		return nil
+	}
+	return protocolEvent
+}
+
+// largeSupply tells whether amount, in the smallest unit of asset, reaches the min_supply of asset
+func (this *Service) largeSupply(asset string, amount *big.Int) bool {
+	cfg := this.cfg.Get()
+	if cfg.Events == nil {
+		return true
+	}
+	minSupply, ok := cfg.Events.MinSupply[asset]
+	if !ok {
+		return true
+	}
+	min := new(big.Float).Mul(big.NewFloat(minSupply),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(cfg.TokenDecimal[asset]), nil)))
+	return new(big.Float).SetInt(amount).Cmp(min) >= 0
+}
+
+func (this *Service) eventAmount(neovm bool, state interface{}, decimal string) string {
+	amount, err := utils.ParseAmount(state, neovm)
+	if err != nil {
+		amount = new(big.Int)
+	}
+	return utils.ToStringByPrecise(amount, this.cfg.Get().TokenDecimal[decimal])
+}
+
+// isNeovm tells whether contract runs in neovm, which notifies numbers as hex byte arrays. The vm type is
+// looked up once per contract, a failed lookup is returned so that the block is parsed again rather than
+// decoded with a guessed encoding
+func (this *Service) isNeovm(contract string) (bool, error) {
+	if vmType, ok := this.vmTypes.Load(contract); ok {
+		return vmType.(payload.VmType) == payload.NEOVM_TYPE, nil
+	}
+	deployCode, err := this.sdk.GetSmartContract(contract)
+	if err != nil {
+		return false, fmt.Errorf("isNeovm, this.sdk.GetSmartContract %s error: %s", contract, err)
+	}
+	if deployCode == nil {
+		return false, fmt.Errorf("isNeovm, contract %s not found", contract)
+	}
+	this.vmTypes.Store(contract, deployCode.VmType())
+	return deployCode.VmType() == payload.NEOVM_TYPE, nil
+}
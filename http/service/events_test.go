@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/core/payload"
+	"github.com/siovanus/wingServer/config"
+	hcommon "github.com/siovanus/wingServer/http/common"
+)
+
+func TestLargeSupply(t *testing.T) {
+	market := "45f93dada46c736d2c8702407e57e23ce51878d2"
+	ethMarket := "b2a1e2b0e5a8bd9e5ae28e3ae2d34f3dbd04ad07"
+	cfg := &config.Config{
+		AssetMap:     map[string]string{market: "pUSDC", ethMarket: "pETH"},
+		TokenDecimal: map[string]uint64{"pUSDC": 6, "pETH": 18},
+		Events:       &config.EventsConfig{MinSupply: map[string]float64{"pUSDC": 10000, "pETH": 1}},
+	}
+	serv := &Service{cfg: config.NewHolder(cfg)}
+	serv.vmTypes.Store(market, payload.WASMVM_TYPE)
+	account := "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+
+	small := serv.decodeProtocolEvent(market, false, []interface{}{EventMint, account, "9999000000"}, []string{market})
+	if small != nil {
+		t.Errorf("small supply should be dropped: %+v", small)
+	}
+	large := serv.decodeProtocolEvent(market, false, []interface{}{EventMint, account, "10000000000"}, []string{market})
+	if large == nil || large.Type != hcommon.EVENT_TYPE_SUPPLY || large.Amount != "10000" {
+		t.Errorf("unexpected event %+v", large)
+	}
+	// 1 pETH less 1 wei rounds to 1 as a float64
+	justBelow := serv.decodeProtocolEvent(ethMarket, false, []interface{}{EventMint, account, "999999999999999999"},
+		[]string{ethMarket})
+	if justBelow != nil {
+		t.Errorf("supply below the minimum should be dropped: %+v", justBelow)
+	}
+	atMin := serv.decodeProtocolEvent(ethMarket, false, []interface{}{EventMint, account, "1000000000000000000"},
+		[]string{ethMarket})
+	if atMin == nil || atMin.Amount != "1" {
+		t.Errorf("unexpected event %+v", atMin)
+	}
+	borrow := serv.decodeProtocolEvent(market, false, []interface{}{EventBorrow, account, "1000000"}, []string{market})
+	if borrow == nil || borrow.Type != hcommon.EVENT_TYPE_BORROW {
+		t.Errorf("borrows are not filtered: %+v", borrow)
+	}
+}
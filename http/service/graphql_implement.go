@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+
+	ocommon "github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+// GraphMarkets returns the stored snapshots of the listed markets, like market(name) and position.market,
+// in the order of the registry. Markets listed before their first snapshot are left out
+func (this *Service) GraphMarkets() ([]*common.Market, error) {
+	allMarkets, err := this.fpMgr.GetAllMarkets()
+	if err != nil {
+		return nil, err
+	}
+	assetMap := this.cfg.Get().AssetMap
+	markets := make([]*common.Market, 0, len(allMarkets))
+	for _, v := range allMarkets {
+		market, err := this.GraphMarket(assetMap[v.ToHexString()])
+		if err != nil {
+			return nil, err
+		}
+		if market != nil {
+			markets = append(markets, market)
+		}
+	}
+	return markets, nil
+}
+
+func (this *Service) GraphMarket(name string) (*common.Market, error) {
+	if this.oracleName(name) == "" {
+		return nil, nil
+	}
+	market, err := this.store.LoadFlashMarket(name)
+	if err != nil {
+		if store.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &market, nil
+}
+
+func (this *Service) GraphPrice(name string) (*common.Price, error) {
+	oracleName := this.oracleName(name)
+	if oracleName == "" {
+		return nil, nil
+	}
+	price, err := this.store.LoadPrice(oracleName)
+	if err != nil {
+		if store.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &common.Price{Name: name, Price: price.Price}, nil
+}
+
+func (this *Service) GraphPriceHistory(name string, limit uint64) ([]*common.PricePoint, error) {
+	oracleName := this.oracleName(name)
+	if oracleName == "" {
+		return []*common.PricePoint{}, nil
+	}
+	pricePoints, err := this.store.LoadPricePoints(oracleName, limit)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]*common.PricePoint, 0, len(pricePoints))
+	for _, v := range pricePoints {
+		points = append(points, &common.PricePoint{Height: v.Height, TxHash: v.TxHash, Price: v.Price})
+	}
+	return points, nil
+}
+
+func (this *Service) GraphPositions(address string) ([]*common.Position, error) {
+	if _, err := ocommon.AddressFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	balances, err := this.store.LoadUserBalance(address)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]*common.Position, 0, len(balances))
+	for _, v := range balances {
+		positions = append(positions, &common.Position{
+			Name:             v.AssetName,
+			Icon:             v.Icon,
+			SupplyBalance:    v.SupplyBalance,
+			BorrowBalance:    v.BorrowBalance,
+			InsuranceBalance: v.InsuranceBalance,
+			IfCollateral:     v.IfCollateral,
+		})
+	}
+	return positions, nil
+}
+
+func (this *Service) GraphWingApy(name string) (*common.WingApy, error) {
+	wingApy, err := this.store.LoadWingApy(name)
+	if err != nil {
+		if store.IsRecordNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &wingApy, nil
+}
+
+func (this *Service) GraphWingApys() ([]common.WingApy, error) {
+	return this.fpMgr.WingApys()
+}
+
+func (this *Service) GraphReserves() (*common.Reserves, error) {
+	return this.fpMgr.Reserves()
+}
+
+func (this *Service) GraphGovPool() (*common.GovPool, error) {
+	govBannerOverview, err := this.govMgr.GovBannerOverview()
+	if err != nil {
+		return nil, err
+	}
+	govBanner, err := this.govMgr.GovBanner()
+	if err != nil {
+		return nil, err
+	}
+	return &common.GovPool{
+		Daily:       govBanner.Daily,
+		Distributed: govBanner.Distributed,
+		Remain20:    govBannerOverview.Remain20,
+		Remain80:    govBannerOverview.Remain80,
+	}, nil
+}
+
+// oracleName returns the oracle name of a listened market, or an empty string for unknown markets
+func (this *Service) oracleName(name string) string {
+	cfg := this.cfg.Get()
+	for k, v := range cfg.AssetMap {
+		if v == name {
+			oracleName := cfg.OracleMap[k]
+			if listContains(this.getAssetList(), oracleName) {
+				return oracleName
+			}
+			return ""
+		}
+	}
+	return ""
+}
@@ -9,6 +9,10 @@ import (
 type GovernanceManager interface {
 	GovBannerOverview() (*common.GovBannerOverview, error)
 	GovBanner() (*common.GovBanner, error)
+	EmissionProjection(interval string, wingSpeeds []*common.WingSpeed) (*common.EmissionProjection, error)
+	ProposalEventForStore(txHash string, height uint32, states []interface{}, neovm bool) error
+	GovProposals(status string, offset, limit uint64) (*common.GovProposals, error)
+	GovProposalDetail(proposalId string) (*common.GovProposalDetail, error)
 }
 
 type FlashPoolManager interface {
@@ -25,10 +29,48 @@ type FlashPoolManager interface {
 	UserFlashPoolOverview(account string) (*common.UserFlashPoolOverview, error)
 	UserBalanceForStore(account string) error
 	GetAllMarkets() ([]ocommon.Address, error)
+	RefreshMarkets() ([]ocommon.Address, error)
 	GetInsuranceAddress(ocommon.Address) (ocommon.Address, error)
 	ClaimWing(account string) (string, error)
 	BorrowAddressList() ([]store.UserAssetBalance, error)
 	LiquidationList(account string) ([]*common.Liquidation, error)
 	WingApyForStore() error
 	Reserves() (*common.Reserves, error)
+	WingSpeeds() ([]*common.WingSpeed, error)
+	WingEarnings(account string) (*common.WingEarnings, error)
+	WingClaimHistory(account string, offset, limit uint64) (*common.WingClaimHistory, error)
+}
+
+type WingManager interface {
+	WingSupply() (*common.WingSupply, error)
+	WingHolders(limit uint64) (*common.WingHolders, error)
+	HolderBalanceForStore(account string, height uint32) error
+}
+
+// Store is implemented by store.Client
+type Store interface {
+	Ping() error
+	Close() error
+	LoadTrackHeight() (uint32, error)
+	SaveTrackHeight(height uint32) error
+	SaveTrackHeightFenced(height uint32, name string, holder string) error
+	LoadPrice(name string) (store.Price, error)
+	SavePrice(price *store.Price) error
+	LoadPricePoints(name string, limit uint64) ([]store.PricePoint, error)
+	SavePricePoint(pricePoint *store.PricePoint) error
+	LoadFlashMarket(name string) (common.Market, error)
+	SaveFlashMarket(market *common.Market) error
+	SaveFlashPoolDetail(flashPoolDetail *store.FlashPoolDetail) error
+	LoadUserBalance(userAddress string) ([]store.UserAssetBalance, error)
+	LoadWingApy(assetName string) (common.WingApy, error)
+	LoadGovProposal(proposalId string) (store.GovProposal, error)
+	SaveProtocolEvent(protocolEvent *store.ProtocolEvent) error
+	SaveWingClaim(wingClaim *store.WingClaim) error
+	SaveWingTransfer(wingTransfer *store.WingTransfer) error
+}
+
+// Notifier is told about every snapshot the service writes, topics are defined in http/common.
+// Events of TOPIC_EVENT are passed as []*store.ProtocolEvent in chain order
+type Notifier interface {
+	Notify(topic string, data interface{})
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/metrics"
+	"github.com/siovanus/wingServer/scheduler"
+	"github.com/siovanus/wingServer/store"
+)
+
+// scheduler jobs of the service, their specs are the defaults of the scheduler config
+const (
+	SCHEDULE_SNAPSHOT_DAILY  = "snapshot_daily"
+	SCHEDULE_SNAPSHOT_MINUTE = "snapshot_minute"
+)
+
+// RegisterJobs adds the snapshot jobs to s, the daily snapshot at midnight UTC and the minute one every
+// snapshot_interval seconds
+func (this *Service) RegisterJobs(s *scheduler.Scheduler) error {
+	err := s.Register(SCHEDULE_SNAPSHOT_DAILY, "@daily", func(ctx context.Context) error {
+		return this.RunSnapshotDaily()
+	})
+	if err != nil {
+		return err
+	}
+	return s.Register(SCHEDULE_SNAPSHOT_MINUTE, fmt.Sprintf("@every %ds", this.cfg.Get().SnapshotInterval),
+		func(ctx context.Context) error {
+			return this.RunSnapshotMinute()
+		})
+}
+
+type jobs struct {
+	sync.Mutex
+	status map[string]*common.JobStatus
+}
+
+// recordJob records a run of a job started at start
+func (this *Service) recordJob(name string, start time.Time, err error) {
+	metrics.ObserveJob(name, start, err)
+	now := time.Now()
+	this.jobs.Lock()
+	defer this.jobs.Unlock()
+	if this.jobs.status == nil {
+		this.jobs.status = make(map[string]*common.JobStatus)
+	}
+	status, ok := this.jobs.status[name]
+	if !ok {
+		status = &common.JobStatus{}
+		this.jobs.status[name] = status
+	}
+	status.Runs++
+	status.LastRun = uint64(start.Unix())
+	status.LastDuration = now.Sub(start).Seconds()
+	if err != nil {
+		status.Failures++
+		status.LastError = err.Error()
+		status.LastErrorTime = uint64(now.Unix())
+	} else {
+		status.LastSuccess = uint64(now.Unix())
+	}
+}
+
+// Jobs returns a copy of the status of the jobs which ran at least once
+func (this *Service) Jobs() map[string]*common.JobStatus {
+	this.jobs.Lock()
+	defer this.jobs.Unlock()
+	jobs := make(map[string]*common.JobStatus, len(this.jobs.status))
+	for k, v := range this.jobs.status {
+		status := *v
+		jobs[k] = &status
+	}
+	return jobs
+}
+
+func (this *Service) PingDB() error {
+	return this.store.Ping()
+}
+
+// PricesUpdatedAt returns when the stalest price of the listed assets was stored, zero when one is missing
+func (this *Service) PricesUpdatedAt() (time.Time, error) {
+	var oldest time.Time
+	for i, v := range this.getAssetList() {
+		price, err := this.store.LoadPrice(v)
+		if store.IsRecordNotFound(err) {
+			return time.Time{}, nil
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("PricesUpdatedAt, this.store.LoadPrice error: %s", err)
+		}
+		if i == 0 || price.UpdatedAt.Before(oldest) {
+			oldest = price.UpdatedAt
+		}
+	}
+	return oldest, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+)
+
+// MAX_CHECKED_ADDRESSES bounds the addresses remembered as no market, the set is emptied once full
+const MAX_CHECKED_ADDRESSES = 100000
+
+// addressSet remembers the addresses named by the flash pool which are no market, accounts or contracts a
+// refresh did not list, so that they are looked up once. A contract listed after it was remembered is picked
+// up by the periodic refresh
+type addressSet struct {
+	sync.Mutex
+	addresses map[string]bool
+}
+
+func (this *addressSet) contains(address string) bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.addresses[address]
+}
+
+func (this *addressSet) add(address string) {
+	this.Lock()
+	defer this.Unlock()
+	if this.addresses == nil || len(this.addresses) >= MAX_CHECKED_ADDRESSES {
+		this.addresses = make(map[string]bool)
+	}
+	this.addresses[address] = true
+}
+
+// unlistedMarket returns the first contract named by a notification of the flash pool which is missing from
+// listeningAddressList, a market listed since the last refresh, or "" if there is none
+func (this *Service) unlistedMarket(states []interface{}, neovm bool, listeningAddressList []string) (string, error) {
+	for _, state := range states[1:] {
+		address, ok := parseNotifiedAddress(state, neovm)
+		if !ok {
+			continue
+		}
+		contract := address.ToHexString()
+		if listContains(listeningAddressList, contract) || this.noMarkets.contains(contract) {
+			continue
+		}
+		deployCode, err := this.sdk.GetSmartContract(contract)
+		if err != nil {
+			return "", fmt.Errorf("unlistedMarket, this.sdk.GetSmartContract %s error: %s", contract, err)
+		}
+		if deployCode == nil {
+			this.noMarkets.add(contract)
+			continue
+		}
+		return contract, nil
+	}
+	return "", nil
+}
+
+// parseNotifiedAddress reads an address notified by a neovm contract as hex bytes, or by a wasm contract in
+// base58 or as the hex string of a contract
+func parseNotifiedAddress(state interface{}, neovm bool) (common.Address, bool) {
+	text, ok := state.(string)
+	if !ok {
+		return common.ADDRESS_EMPTY, false
+	}
+	if neovm {
+		data, err := hex.DecodeString(text)
+		if err != nil || len(data) != common.ADDR_LEN {
+			return common.ADDRESS_EMPTY, false
+		}
+		address, err := common.AddressParseFromBytes(data)
+		return address, err == nil
+	}
+	if address, err := common.AddressFromBase58(text); err == nil {
+		return address, true
+	}
+	if len(text) != 2*common.ADDR_LEN {
+		return common.ADDRESS_EMPTY, false
+	}
+	address, err := common.AddressFromHexString(text)
+	return address, err == nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+)
+
+func TestParseNotifiedAddress(t *testing.T) {
+	market, err := common.AddressFromHexString("45f93dada46c736d2c8702407e57e23ce51878d2")
+	if err != nil {
+		t.Fatalf("common.AddressFromHexString error: %s", err)
+	}
+	for _, v := range []struct {
+		state interface{}
+		neovm bool
+		ok    bool
+	}{
+		{market.ToBase58(), false, true},
+		{market.ToHexString(), false, true},
+		{hex.EncodeToString(market[:]), true, true},
+		{market.ToBase58(), true, false},
+		{"Mint", false, false},
+		{"1000000", false, false},
+		{uint64(1), false, false},
+	} {
+		address, ok := parseNotifiedAddress(v.state, v.neovm)
+		if ok != v.ok || ok && address != market {
+			t.Errorf("%v neovm %v: %s %v", v.state, v.neovm, address.ToHexString(), ok)
+		}
+	}
+}
+
+func TestUnlistedMarketSkipsKnownAddresses(t *testing.T) {
+	listed := "45f93dada46c736d2c8702407e57e23ce51878d2"
+	account := "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+	address, _ := common.AddressFromBase58(account)
+	// without an sdk, any lookup would panic
+	serv := &Service{}
+	serv.noMarkets.add(address.ToHexString())
+	market, err := serv.unlistedMarket([]interface{}{"EnterMarkets", account, listed, "100"}, false, []string{listed})
+	if err != nil || market != "" {
+		t.Errorf("unexpected market %s %v", market, err)
+	}
+}
+
+func TestAddressSetBound(t *testing.T) {
+	set := &addressSet{}
+	for i := 0; i < MAX_CHECKED_ADDRESSES; i++ {
+		set.add(strconv.Itoa(i))
+	}
+	if !set.contains("0") {
+		t.Fatalf("address forgotten before the set is full")
+	}
+	set.add("last")
+	if set.contains("0") || !set.contains("last") || len(set.addresses) != 1 {
+		t.Errorf("set not emptied once full, %d addresses", len(set.addresses))
+	}
+}
@@ -1,51 +1,192 @@
 package service
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology/common"
 	"github.com/siovanus/wingServer/config"
+	hcommon "github.com/siovanus/wingServer/http/common"
 	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/metrics"
 	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/supervisor"
 )
 
 type Service struct {
 	sdk                  *sdk.OntologySdk
-	cfg                  *config.Config
+	cfg                  *config.Holder
 	govMgr               GovernanceManager
 	fpMgr                FlashPoolManager
-	store                *store.Client
+	wingMgr              WingManager
+	store                Store
 	trackHeight          uint32
+	resetHeight          *uint32
+	trackLock            sync.Mutex
+	inFlight             int64
+	indexing             int32
+	lease                string
+	holder               string
+	writes               sync.WaitGroup
+	jobs                 jobs
+	lock                 sync.RWMutex
 	listeningAddressList []string
 	assetList            []string
+	notifiers            []Notifier
+	vmTypes              sync.Map
+	noMarkets            addressSet
 }
 
-func NewService(sdk *sdk.OntologySdk, govMgr GovernanceManager, fpMgr FlashPoolManager, store *store.Client, cfg *config.Config) *Service {
-	return &Service{sdk: sdk, cfg: cfg, govMgr: govMgr, fpMgr: fpMgr, store: store}
+func NewService(sdk *sdk.OntologySdk, govMgr GovernanceManager, fpMgr FlashPoolManager, wingMgr WingManager,
+	store Store, cfg *config.Holder) *Service {
+	return &Service{sdk: sdk, cfg: cfg, govMgr: govMgr, fpMgr: fpMgr, wingMgr: wingMgr, store: store}
 }
 
-func (this *Service) AddListeningAddressList() {
+// AddListeningAddressList lists the markets and the contracts to listen to, before the indexer starts
+func (this *Service) AddListeningAddressList() error {
+	err := this.updateListeningAddressList()
+	if err != nil {
+		return fmt.Errorf("AddListeningAddressList, this.updateListeningAddressList error: %s", err)
+	}
+	return nil
+}
+
+// RefreshMarkets reloads the market registry and starts listening to newly listed markets
+func (this *Service) RefreshMarkets() (err error) {
+	start := time.Now()
+	defer func() { this.recordJob(hcommon.JOB_MARKET_REFRESH, start, err) }()
+	added, err := this.fpMgr.RefreshMarkets()
+	if err != nil {
+		return fmt.Errorf("RefreshMarkets, this.fpMgr.RefreshMarkets error: %s", err)
+	}
+	for _, v := range added {
+		log.Infof("RefreshMarkets, new market listed: %s", v.ToHexString())
+	}
+	return this.updateListeningAddressList()
+}
+
+// TrackMarkets refreshes the market registry every market_refresh_interval seconds until ctx is done. Markets
+// named by the flash pool are refreshed as soon as their block is parsed, the interval is a fallback
+func (this *Service) TrackMarkets(ctx context.Context) error {
+	interval := this.cfg.Get().MarketRefreshInterval
+	if interval == 0 {
+		interval = config.DEFAULT_MARKET_REFRESH_INTERVAL
+	}
+	for supervisor.Sleep(ctx, time.Second*time.Duration(interval)) {
+		err := this.RefreshMarkets()
+		if err != nil {
+			log.Errorf("TrackMarkets, this.RefreshMarkets error: %s", err)
+		}
+	}
+	return nil
+}
+
+// MarketWatcher returns a notifier refreshing the market registry once the indexer of another replica
+// stored a market this replica does not know, for the api replicas which do not track the markets
+func (this *Service) MarketWatcher() Notifier {
+	return &marketWatcher{serv: this}
+}
+
+type marketWatcher struct {
+	serv *Service
+}
+
+func (this *marketWatcher) Notify(topic string, data interface{}) {
+	markets, ok := data.(*hcommon.FlashPoolAllMarket)
+	if topic != hcommon.TOPIC_MARKET || !ok {
+		return
+	}
+	known, err := this.serv.fpMgr.GetAllMarkets()
+	if err != nil {
+		log.Errorf("marketWatcher.Notify, this.serv.fpMgr.GetAllMarkets error: %s", err)
+		return
+	}
+	if !unknownMarket(markets.FlashPoolAllMarket, known, this.serv.cfg.Get().AssetMap) {
+		return
+	}
+	if err := this.serv.RefreshMarkets(); err != nil {
+		log.Errorf("marketWatcher.Notify, this.serv.RefreshMarkets error: %s", err)
+	}
+}
+
+// unknownMarket tells whether markets, named after asset_map, holds the address of a market missing from known.
+// Names missing from asset_map are skipped, a reload of the config lists their markets
+func unknownMarket(markets []*hcommon.Market, known []common.Address, assetMap map[string]string) bool {
+	listed := make(map[string]bool, len(known))
+	for _, v := range known {
+		listed[v.ToHexString()] = true
+	}
+	addresses := make(map[string]string, len(assetMap))
+	for address, name := range assetMap {
+		addresses[name] = address
+	}
+	for _, v := range markets {
+		if address, ok := addresses[v.Name]; ok && !listed[address] {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *Service) updateListeningAddressList() error {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.fpMgr.GetAllMarkets()
 	if err != nil {
-		log.Errorf("AddListeningAddressList, this.fpMgr.GetAllMarkets error: %s", err)
-		os.Exit(1)
+		return fmt.Errorf("updateListeningAddressList, this.fpMgr.GetAllMarkets error: %s", err)
 	}
+	assetList := make([]string, 0, len(allMarkets))
+	listeningAddressList := make([]string, 0, 2*len(allMarkets)+4)
 	for _, v := range allMarkets {
-		log.Infof("ftoken address: %s", v.ToHexString())
-		this.assetList = append(this.assetList, this.cfg.OracleMap[v.ToHexString()])
-		this.listeningAddressList = append(this.listeningAddressList, v.ToHexString())
+		log.Debugf("ftoken address: %s", v.ToHexString())
+		assetList = append(assetList, cfg.OracleMap[v.ToHexString()])
+		listeningAddressList = append(listeningAddressList, v.ToHexString())
 		addr, err := this.fpMgr.GetInsuranceAddress(v)
 		if err != nil {
-			log.Errorf("AddListeningAddressList, this.fpMgr.GetInsuranceAddress error: %s", err)
-			os.Exit(1)
+			return fmt.Errorf("updateListeningAddressList, this.fpMgr.GetInsuranceAddress error: %s", err)
 		}
-		this.listeningAddressList = append(this.listeningAddressList, addr.ToHexString())
+		listeningAddressList = append(listeningAddressList, addr.ToHexString())
+	}
+	listeningAddressList = append(listeningAddressList, cfg.WingAddress)
+	listeningAddressList = append(listeningAddressList, cfg.GovernanceAddress)
+	listeningAddressList = append(listeningAddressList, cfg.OracleAddress)
+	listeningAddressList = append(listeningAddressList, cfg.FlashPoolAddress)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.assetList = assetList
+	this.listeningAddressList = listeningAddressList
+	return nil
+}
+
+func (this *Service) getListeningAddressList() []string {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.listeningAddressList
+}
+
+func (this *Service) getAssetList() []string {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.assetList
+}
+
+func (this *Service) AddNotifier(notifier Notifier) {
+	this.notifiers = append(this.notifiers, notifier)
+}
+
+func (this *Service) notify(topic string, data interface{}) {
+	for _, v := range this.notifiers {
+		v.Notify(topic, data)
 	}
-	this.listeningAddressList = append(this.listeningAddressList, this.cfg.WingAddress)
-	this.listeningAddressList = append(this.listeningAddressList, this.cfg.GovernanceAddress)
-	this.listeningAddressList = append(this.listeningAddressList, this.cfg.OracleAddress)
-	this.listeningAddressList = append(this.listeningAddressList, this.cfg.FlashPoolAddress)
+}
+
+// Wait waits at most timeout for the background writes, it reports whether all of them ended
+func (this *Service) Wait(timeout time.Duration) bool {
+	return supervisor.WaitTimeout(&this.writes, timeout)
 }
 
 func (this *Service) Close() {
@@ -57,59 +198,46 @@ func (this *Service) Close() {
 	log.Info("All connections closed. Bye!")
 }
 
-func (this *Service) SnapshotDaily() {
+// RunSnapshotDaily takes the flash pool detail and market snapshots, errors are logged and the last one returned
+func (this *Service) RunSnapshotDaily() (result error) {
+	start := time.Now()
+	defer func() { this.recordJob(hcommon.JOB_SNAPSHOT_DAILY, start, result) }()
+	// FlashPoolDetailForStore
 	flashPoolDetail, err := this.fpMgr.FlashPoolDetailForStore()
 	if err != nil {
-		log.Errorf("Snapshot, this.fpMgr.FlashPoolDetailForStore error: %s", err)
-	}
-	err = this.store.SaveFlashPoolDetail(flashPoolDetail)
-	if err != nil {
-		log.Errorf("Snapshot, this.store.SaveFlashPoolDetail error: %s", err)
+		result = fmt.Errorf("Snapshot, this.fpMgr.FlashPoolDetailForStore error: %s", err)
+		log.Error(result)
+	} else {
+		err = this.store.SaveFlashPoolDetail(flashPoolDetail)
+		if err != nil {
+			result = fmt.Errorf("Snapshot, this.store.SaveFlashPoolDetail error: %s", err)
+			log.Error(result)
+		}
 	}
 
 	// FlashPoolMarketForStore
 	err = this.fpMgr.FlashPoolMarketStore()
 	if err != nil {
-		log.Errorf("Snapshot, this.fpMgr.FlashPoolMarketStore error: %s", err)
+		result = fmt.Errorf("Snapshot, this.fpMgr.FlashPoolMarketStore error: %s", err)
+		log.Error(result)
 	}
-	for {
-		now := time.Now()
-		// 计算下一个零点
-		next := now.Add(time.Hour * 24)
-		next = time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, next.Location())
-		t := time.NewTimer(next.Sub(now))
-		<-t.C
-		log.Infof("snapshot start: %v", time.Now())
-		// 以下为定时执行的操作
-		// FlashPoolDetailForStore
-		flashPoolDetail, err := this.fpMgr.FlashPoolDetailForStore()
-		if err != nil {
-			log.Errorf("Snapshot, this.fpMgr.FlashPoolDetailForStore error: %s", err)
-		}
-		err = this.store.SaveFlashPoolDetail(flashPoolDetail)
-		if err != nil {
-			log.Errorf("Snapshot, this.store.SaveFlashPoolDetail error: %s", err)
-		}
-
-		// FlashPoolMarketForStore
-		err = this.fpMgr.FlashPoolMarketStore()
-		if err != nil {
-			log.Errorf("Snapshot, this.fpMgr.FlashPoolMarketStore error: %s", err)
-		}
+	if flashPoolDetail != nil {
+		this.notify(hcommon.TOPIC_FLASHPOOL, flashPoolDetail)
 	}
+	return result
 }
 
-func (this *Service) TrackEvent() {
+// TrackEvent parses the blocks after the track height until ctx is done, a block is never left half parsed.
+// Initialization errors are returned so that the supervisor restarts it
+func (this *Service) TrackEvent(ctx context.Context) error {
 	//init
 	err := this.PriceFeed()
 	if err != nil {
-		log.Errorf("TrackEvent, this.PriceFeed error:", err)
-		os.Exit(1)
+		return fmt.Errorf("TrackEvent, this.PriceFeed error: %s", err)
 	}
 	err = this.StoreFlashPoolAllMarket()
 	if err != nil {
-		log.Errorf("TrackEvent, this.StoreFlashPoolAllMarket error:", err)
-		os.Exit(1)
+		return fmt.Errorf("TrackEvent, this.StoreFlashPoolAllMarket error: %s", err)
 	}
 
 	trackHeight, err := this.store.LoadTrackHeight()
@@ -117,53 +245,209 @@ func (this *Service) TrackEvent() {
 		log.Infof("TrackEvent, this.store.LoadTrackHeight error: %s", err)
 		currentHeight, err := this.sdk.GetCurrentBlockHeight()
 		if err != nil {
-			log.Errorf("TrackEvent, this.sdk.GetCurrentBlockHeight error:", err)
-			os.Exit(1)
+			return fmt.Errorf("TrackEvent, this.sdk.GetCurrentBlockHeight error: %s", err)
 		}
-		this.trackHeight = currentHeight
+		this.setTrackHeight(currentHeight)
 	} else {
-		this.trackHeight = trackHeight
+		this.setTrackHeight(trackHeight)
 	}
 	for {
 		currentHeight, err := this.sdk.GetCurrentBlockHeight()
 		if err != nil {
-			log.Errorf("TrackEvent, this.sideSdk.GetCurrentBlockHeight error:", err)
+			log.Errorf("TrackEvent, this.sdk.GetCurrentBlockHeight error: %s", err)
+		} else {
+			metrics.SetIndexerTip(currentHeight)
 		}
-		for i := this.trackHeight + 1; i <= currentHeight; i++ {
-			log.Infof("TrackEvent, parse block: %d", i)
-			ifOracle, accounts, err := this.trackSnapshotEvent(i)
+		this.applyResetHeight()
+		for i := this.TrackHeight() + 1; i <= currentHeight; i++ {
+			if ctx.Err() != nil || this.applyResetHeight() {
+				break
+			}
+			blockLog := log.WithFields(log.Fields{log.FIELD_HEIGHT: i})
+			blockLog.Infof("TrackEvent, parse block: %d", i)
+			start := time.Now()
+			blockEvent, err := this.trackSnapshotEvent(i)
 			if err != nil {
-				log.Errorf("TrackEvent, this.TrackOracle error:", err)
+				blockLog.Errorf("TrackEvent, this.TrackOracle error: %s", err)
+				this.recordJob(hcommon.JOB_TRACK_EVENT, start, err)
 				break
 			}
 
-			if ifOracle {
-				log.Infof("TrackEvent, this.PriceFeed")
-				go this.PriceFeed()
+			if blockEvent.ifOracle {
+				blockLog.Infof("TrackEvent, this.OraclePriceFeed")
+				height, txHash := i, blockEvent.oracleTxHash
+				this.background(func() { this.OraclePriceFeed(height, txHash) })
+			}
+
+			if len(blockEvent.accounts) != 0 {
+				for _, v := range blockEvent.accounts {
+					blockLog.WithFields(log.Fields{log.FIELD_ACCOUNT: v}).Infof("TrackEvent, account: %s", v)
+					account := v
+					this.background(func() { this.StoreUserBalance(account) })
+				}
 			}
 
-			if len(accounts) != 0 {
-				for _, v := range accounts {
-					log.Infof("TrackEvent, account: %s", v)
-					go this.StoreUserBalance(v)
+			for _, v := range blockEvent.wingTransfers {
+				err = this.store.SaveWingTransfer(v)
+				if err != nil {
+					blockLog.Errorf("TrackEvent, this.store.SaveWingTransfer error: %s", err)
+				}
+			}
+			if len(blockEvent.wingClaims) != 0 {
+				block, err := this.sdk.GetBlockByHeight(i)
+				if err != nil {
+					blockLog.Errorf("TrackEvent, this.sdk.GetBlockByHeight error: %s", err)
+					break
+				}
+				for _, v := range blockEvent.wingClaims {
+					v.Timestamp = block.Header.Timestamp
+					err = this.store.SaveWingClaim(v)
+					if err != nil {
+						blockLog.Errorf("TrackEvent, this.store.SaveWingClaim error: %s", err)
+					}
+				}
+			}
+			for _, v := range blockEvent.govEvents {
+				err = this.govMgr.ProposalEventForStore(v.txHash, i, v.states, v.neovm)
+				if err != nil {
+					blockLog.Errorf("TrackEvent, this.govMgr.ProposalEventForStore error: %s", err)
 				}
 			}
+			for _, v := range blockEvent.events {
+				err = this.store.SaveProtocolEvent(v)
+				if err != nil {
+					blockLog.Errorf("TrackEvent, this.store.SaveProtocolEvent error: %s", err)
+				}
+			}
+			if len(blockEvent.events) != 0 {
+				this.notify(hcommon.TOPIC_EVENT, blockEvent.events)
+			}
+			for _, v := range blockEvent.wingHolders {
+				account, height := v, i
+				this.background(func() { this.StoreWingHolder(account, height) })
+			}
 
-			this.trackHeight++
-			err = this.store.SaveTrackHeight(this.trackHeight)
+			this.setTrackHeight(i)
+			err = this.saveTrackHeight(i)
+			if err == store.ErrLeaseLost {
+				this.recordJob(hcommon.JOB_TRACK_EVENT, start, err)
+				return fmt.Errorf("TrackEvent, this.saveTrackHeight: %s, another replica indexes", err)
+			}
 			if err != nil {
-				log.Errorf("TrackEvent, this.store.SaveTrackHeight error:", err)
+				blockLog.Errorf("TrackEvent, this.saveTrackHeight error: %s", err)
+				this.recordJob(hcommon.JOB_TRACK_EVENT, start, err)
 				break
 			}
+			this.recordJob(hcommon.JOB_TRACK_EVENT, start, nil)
+		}
+		if !supervisor.Sleep(ctx, time.Second*time.Duration(this.cfg.Get().ScanInterval)) {
+			return nil
 		}
-		time.Sleep(time.Second * time.Duration(this.cfg.ScanInterval))
 	}
 }
 
-func (this *Service) SnapshotMinute() {
-	for {
-		go this.StoreFlashPoolAllMarket()
-		go this.StoreWingApy()
-		time.Sleep(time.Second * time.Duration(this.cfg.SnapshotInterval))
+// RunSnapshotMinute stores the markets and wing apys once, waiting for both. The wing apys are stored even
+// when the markets fail, the errors of both are returned
+func (this *Service) RunSnapshotMinute() error {
+	marketErr := this.StoreFlashPoolAllMarket()
+	apyErr := this.StoreWingApy()
+	if marketErr != nil && apyErr != nil {
+		return fmt.Errorf("RunSnapshotMinute, this.StoreFlashPoolAllMarket error: %s; %s", marketErr, apyErr)
+	}
+	if marketErr != nil {
+		return fmt.Errorf("RunSnapshotMinute, this.StoreFlashPoolAllMarket error: %s", marketErr)
+	}
+	return apyErr
+}
+
+// SetTrackHeight makes the indexer continue after height, it is applied before the next block is parsed.
+// Rewinding parses the blocks in between again
+func (this *Service) SetTrackHeight(height uint32) error {
+	if !this.Indexing() {
+		return hcommon.Conflict("this replica does not run the indexer, ask the leader")
+	}
+	currentHeight, err := this.sdk.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("SetTrackHeight, this.sdk.GetCurrentBlockHeight error: %s", err)
+	}
+	if height > currentHeight {
+		return hcommon.BadRequest(fmt.Sprintf("height %d is above the chain height %d", height, currentHeight))
+	}
+	this.trackLock.Lock()
+	defer this.trackLock.Unlock()
+	this.resetHeight = &height
+	return nil
+}
+
+// SetIndexing tells whether this replica runs the indexer, only the leader does when replicas share the
+// database
+func (this *Service) SetIndexing(indexing bool) {
+	var v int32
+	if indexing {
+		v = 1
+	}
+	atomic.StoreInt32(&this.indexing, v)
+}
+
+// SetFence makes the indexer save the track height only while holder holds the writer lease, set before
+// TrackEvent runs when the replicas elect a leader
+func (this *Service) SetFence(lease string, holder string) {
+	this.lease = lease
+	this.holder = holder
+}
+
+func (this *Service) saveTrackHeight(height uint32) error {
+	if this.lease == "" {
+		return this.store.SaveTrackHeight(height)
 	}
+	return this.store.SaveTrackHeightFenced(height, this.lease, this.holder)
+}
+
+func (this *Service) Indexing() bool {
+	return atomic.LoadInt32(&this.indexing) == 1
+}
+
+func (this *Service) TrackHeight() uint32 {
+	this.trackLock.Lock()
+	defer this.trackLock.Unlock()
+	return this.trackHeight
+}
+
+// applyResetHeight moves the track height to the one of SetTrackHeight, it reports whether it was moved
+func (this *Service) applyResetHeight() bool {
+	this.trackLock.Lock()
+	defer this.trackLock.Unlock()
+	if this.resetHeight == nil {
+		return false
+	}
+	log.Infof("TrackEvent, track height moved from %d to %d", this.trackHeight, *this.resetHeight)
+	this.trackHeight = *this.resetHeight
+	this.resetHeight = nil
+	metrics.SetIndexerHeight(this.trackHeight)
+	err := this.saveTrackHeight(this.trackHeight)
+	if err != nil {
+		log.Errorf("TrackEvent, this.saveTrackHeight error: %s", err)
+	}
+	return true
+}
+
+func (this *Service) setTrackHeight(height uint32) {
+	this.trackLock.Lock()
+	defer this.trackLock.Unlock()
+	this.trackHeight = height
+	metrics.SetIndexerHeight(height)
+}
+
+// background runs a write of the indexer in a goroutine, counted in the queue depth of IndexerStatus and
+// waited for by Wait
+func (this *Service) background(f func()) {
+	this.writes.Add(1)
+	atomic.AddInt64(&this.inFlight, 1)
+	metrics.QueueDepth.Inc()
+	go func() {
+		defer this.writes.Done()
+		defer metrics.QueueDepth.Dec()
+		defer atomic.AddInt64(&this.inFlight, -1)
+		f()
+	}()
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/config"
+	hcommon "github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	testMarket    = "45f93dada46c736d2c8702407e57e23ce51878d2"
+	testNewMarket = "c93b1ee2f3a10e6c58b3a55fcc1b11d5b0e5d2e8"
+)
+
+// fakeFlashPool lists markets, the registry of the chain, after each refresh
+type fakeFlashPool struct {
+	FlashPoolManager
+	chain     []common.Address
+	markets   []common.Address
+	refreshes int
+}
+
+func (this *fakeFlashPool) GetAllMarkets() ([]common.Address, error) {
+	if this.markets == nil {
+		this.RefreshMarkets()
+	}
+	return this.markets, nil
+}
+
+func (this *fakeFlashPool) RefreshMarkets() ([]common.Address, error) {
+	this.refreshes++
+	this.markets = append([]common.Address{}, this.chain...)
+	return nil, nil
+}
+
+func (this *fakeFlashPool) GetInsuranceAddress(market common.Address) (common.Address, error) {
+	return market, nil
+}
+
+type fakeStore struct {
+	Store
+	prices map[string]string
+}
+
+func (this *fakeStore) LoadPrice(name string) (store.Price, error) {
+	return store.Price{Name: name, Price: this.prices[name]}, nil
+}
+
+func address(t *testing.T, hex string) common.Address {
+	address, err := common.AddressFromHexString(hex)
+	if err != nil {
+		t.Fatalf("common.AddressFromHexString error: %s", err)
+	}
+	return address
+}
+
+// newApiService returns a service started like an api replica, whose markets are listed at startup
+func newApiService(t *testing.T, fpMgr *fakeFlashPool) *Service {
+	cfg := config.NewHolder(&config.Config{
+		AssetMap:  map[string]string{testMarket: "pONT", testNewMarket: "pETH"},
+		OracleMap: map[string]string{testMarket: "ONT", testNewMarket: "ETH"},
+	})
+	serv := NewService(nil, nil, fpMgr, nil, &fakeStore{prices: map[string]string{"ONT": "0.5", "ETH": "400"}},
+		cfg)
+	if err := serv.AddListeningAddressList(); err != nil {
+		t.Fatalf("serv.AddListeningAddressList error: %s", err)
+	}
+	return serv
+}
+
+func TestApiModePrices(t *testing.T) {
+	serv := newApiService(t, &fakeFlashPool{chain: []common.Address{address(t, testMarket)}})
+	result, err := serv.V2Prices(httptest.NewRequest(http.MethodGet, hcommon.V2PRICES, nil))
+	if err != nil {
+		t.Fatalf("serv.V2Prices error: %s", err)
+	}
+	prices := result.([]*hcommon.ApiPrice)
+	if len(prices) != 1 || prices[0].Asset != "ONT" || prices[0].Price.String() != "0.5" {
+		t.Errorf("unexpected prices %v", prices)
+	}
+}
+
+func TestMarketWatcher(t *testing.T) {
+	fpMgr := &fakeFlashPool{chain: []common.Address{address(t, testMarket)}}
+	serv := newApiService(t, fpMgr)
+	watcher := serv.MarketWatcher()
+	fpMgr.chain = append(fpMgr.chain, address(t, testNewMarket))
+
+	// a snapshot of the known markets, or naming an asset missing from asset_map, does not refresh
+	watcher.Notify(hcommon.TOPIC_MARKET, &hcommon.FlashPoolAllMarket{
+		FlashPoolAllMarket: []*hcommon.Market{{Name: "pONT"}, {Name: "pUNKNOWN"}},
+	})
+	if fpMgr.refreshes != 1 {
+		t.Fatalf("refreshed %d times", fpMgr.refreshes)
+	}
+	// a market listed since, with as many markets as known, does
+	watcher.Notify(hcommon.TOPIC_MARKET, &hcommon.FlashPoolAllMarket{
+		FlashPoolAllMarket: []*hcommon.Market{{Name: "pETH"}},
+	})
+	if fpMgr.refreshes != 2 || !listContains(serv.getAssetList(), "ETH") {
+		t.Errorf("refreshed %d times, assets %v", fpMgr.refreshes, serv.getAssetList())
+	}
+}
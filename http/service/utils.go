@@ -2,30 +2,132 @@ package service
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/ontio/ontology/common"
+	hcommon "github.com/siovanus/wingServer/http/common"
 	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/metrics"
 	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/utils"
 )
 
-func (this *Service) trackSnapshotEvent(height uint32) (bool, []string, error) {
-	accounts := []string{}
+type blockEvent struct {
+	ifOracle      bool
+	oracleTxHash  string
+	accounts      []string
+	wingHolders   []string
+	wingTransfers []*store.WingTransfer
+	wingClaims    []*store.WingClaim
+	govEvents     []*govEvent
+	events        []*store.ProtocolEvent
+}
+
+type govEvent struct {
+	txHash string
+	states []interface{}
+	neovm  bool
+}
+
+func (this *Service) trackSnapshotEvent(height uint32) (*blockEvent, error) {
+	cfg := this.cfg.Get()
+	result := &blockEvent{
+		accounts:      []string{},
+		wingHolders:   []string{},
+		wingTransfers: []*store.WingTransfer{},
+		wingClaims:    []*store.WingClaim{},
+		govEvents:     []*govEvent{},
+		events:        []*store.ProtocolEvent{},
+	}
+	flashPoolAddress, err := common.AddressFromHexString(cfg.FlashPoolAddress)
+	if err != nil {
+		return result, fmt.Errorf("TrackOracle, common.AddressFromHexString error:%s", err)
+	}
 	events, err := this.sdk.GetSmartContractEventByBlock(height)
 	if err != nil {
-		return false, accounts, fmt.Errorf("TrackOracle, this.sdk.GetSmartContractEventByBlock error:%s", err)
+		return result, fmt.Errorf("TrackOracle, this.sdk.GetSmartContractEventByBlock error:%s", err)
 	}
-	flag := false
+	listeningAddressList := this.getListeningAddressList()
+	// log index counts every notification of the block, so it stays stable whatever gets decoded
+	var logIndex uint32
 	for _, event := range events {
-		for _, notify := range event.Notify {
+		for index, notify := range event.Notify {
+			logIndex++
 			states, ok := notify.States.([]interface{})
-			if !ok {
+			if !ok || len(states) == 0 {
 				continue
 			}
-			if !listContains(this.listeningAddressList, notify.ContractAddress) {
+			if !listContains(listeningAddressList, notify.ContractAddress) {
 				continue
 			}
+			neovm, err := this.isNeovm(notify.ContractAddress)
+			if err != nil {
+				return result, fmt.Errorf("TrackOracle, this.isNeovm error:%s", err)
+			}
+			if notify.ContractAddress == cfg.FlashPoolAddress {
+				market, err := this.unlistedMarket(states, neovm, listeningAddressList)
+				if err != nil {
+					return result, fmt.Errorf("TrackOracle, this.unlistedMarket error:%s", err)
+				}
+				if market != "" {
+					log.Infof("trackSnapshotEvent, flash pool names unlisted contract %s, refresh markets", market)
+					if err := this.RefreshMarkets(); err != nil {
+						return result, fmt.Errorf("TrackOracle, this.RefreshMarkets error:%s", err)
+					}
+					listeningAddressList = this.getListeningAddressList()
+					if !listContains(listeningAddressList, market) {
+						this.noMarkets.add(market)
+					}
+				}
+			}
+			if protocolEvent := this.decodeProtocolEvent(notify.ContractAddress, neovm, states,
+				listeningAddressList); protocolEvent != nil {
+				protocolEvent.Height = height
+				protocolEvent.LogIndex = logIndex
+				protocolEvent.TxHash = event.TxHash
+				result.events = append(result.events, protocolEvent)
+			}
 			name, _ := states[0].(string)
-			if name == "PutUnderlyingPrice" {
-				flag = true
+			if name == EventPutUnderlyingPrice {
+				result.ifOracle = true
+				result.oracleTxHash = event.TxHash
+			}
+			if notify.ContractAddress == cfg.GovernanceAddress {
+				result.govEvents = append(result.govEvents, &govEvent{txHash: event.TxHash, states: states, neovm: neovm})
+			}
+			if notify.ContractAddress == cfg.WingAddress && strings.EqualFold(name, "transfer") && len(states) > 3 {
+				from, _ := states[1].(string)
+				to, _ := states[2].(string)
+				amount, err := utils.ParseAmount(states[3], neovm)
+				if err != nil {
+					log.Errorf("trackSnapshotEvent, utils.ParseAmount of wing transfer %s error: %s", event.TxHash, err)
+				} else {
+					result.wingTransfers = append(result.wingTransfers, &store.WingTransfer{
+						TxHash:      event.TxHash,
+						EventIndex:  uint32(index),
+						Height:      height,
+						FromAddress: from,
+						ToAddress:   to,
+						Amount:      amount.String(),
+					})
+					// wing accrued in flash pool is paid out by the flash pool contract when claimed
+					if from == flashPoolAddress.ToBase58() {
+						result.wingClaims = append(result.wingClaims, &store.WingClaim{
+							TxHash:      event.TxHash,
+							EventIndex:  uint32(index),
+							UserAddress: to,
+							Height:      height,
+							Amount:      amount.String(),
+						})
+					}
+				}
+				for _, a := range []string{from, to} {
+					if _, err := common.AddressFromBase58(a); err == nil && !listContains(result.wingHolders, a) {
+						result.wingHolders = append(result.wingHolders, a)
+					}
+				}
 			}
 
 			if len(states) > 1 {
@@ -33,9 +135,9 @@ func (this *Service) trackSnapshotEvent(height uint32) (bool, []string, error) {
 				if ok {
 					address, err := common.AddressFromBase58(a)
 					if err == nil {
-						if !listContains(this.listeningAddressList, address.ToHexString()) {
-							if !listContains(accounts, a) {
-								accounts = append(accounts, a)
+						if !listContains(listeningAddressList, address.ToHexString()) {
+							if !listContains(result.accounts, a) {
+								result.accounts = append(result.accounts, a)
 							}
 						}
 					}
@@ -46,9 +148,9 @@ func (this *Service) trackSnapshotEvent(height uint32) (bool, []string, error) {
 				if ok {
 					address, err := common.AddressFromBase58(a)
 					if err == nil {
-						if !listContains(this.listeningAddressList, address.ToHexString()) {
-							if !listContains(accounts, a) {
-								accounts = append(accounts, a)
+						if !listContains(listeningAddressList, address.ToHexString()) {
+							if !listContains(result.accounts, a) {
+								result.accounts = append(result.accounts, a)
 							}
 						}
 					}
@@ -56,15 +158,42 @@ func (this *Service) trackSnapshotEvent(height uint32) (bool, []string, error) {
 			}
 		}
 	}
-	return flag, accounts, nil
+	return result, nil
+}
+
+func (this *Service) PriceFeed() (err error) {
+	_, err = this.priceFeed()
+	return err
 }
 
-func (this *Service) PriceFeed() error {
-	for _, v := range this.assetList {
+// OraclePriceFeed stores the prices after the oracle update in txHash at height, and records them as the
+// price points of that height. The prices are read once the block is parsed, a later update in the meantime
+// is recorded under the height of this one
+func (this *Service) OraclePriceFeed(height uint32, txHash string) error {
+	prices, err := this.priceFeed()
+	if err != nil {
+		return err
+	}
+	for _, v := range prices {
+		err = this.store.SavePricePoint(&store.PricePoint{Name: v.Name, Height: height, TxHash: txHash, Price: v.Price})
+		if err != nil {
+			log.Errorf("OraclePriceFeed, this.store.SavePricePoint error: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Service) priceFeed() (prices []*hcommon.Price, err error) {
+	start := time.Now()
+	defer func() { this.recordJob(hcommon.JOB_PRICE_FEED, start, err) }()
+	assetList := this.getAssetList()
+	prices = make([]*hcommon.Price, 0, len(assetList))
+	for _, v := range assetList {
 		data, err := this.fpMgr.AssetPrice(v)
 		if err != nil {
 			log.Errorf("PriceFeed, this.fpMgr.AssetPrice error: %s", err)
-			return err
+			return nil, err
 		}
 		price := &store.Price{
 			Name:  v,
@@ -73,20 +202,53 @@ func (this *Service) PriceFeed() error {
 		err = this.store.SavePrice(price)
 		if err != nil {
 			log.Errorf("PriceFeed, this.store.SavePrice error: %s", err)
-			return err
+			return nil, err
 		}
+		prices = append(prices, &hcommon.Price{Name: v, Price: data})
 	}
-	return nil
+	this.notify(hcommon.TOPIC_PRICE, prices)
+	return prices, nil
 }
 
 func (this *Service) StoreUserBalance(account string) {
+	err := this.RefreshAccount(account)
+	if err != nil {
+		log.WithFields(log.Fields{log.FIELD_ACCOUNT: account}).Errorf("StoreUserBalance, this.RefreshAccount error: %s",
+			err)
+	}
+}
+
+// RefreshAccount stores the balances of account and notifies them
+func (this *Service) RefreshAccount(account string) error {
 	err := this.fpMgr.UserBalanceForStore(account)
 	if err != nil {
-		log.Errorf("StoreUserBalance, this.fpMgr.UserFlashPoolOverviewForStore error: %s", err)
+		return fmt.Errorf("RefreshAccount, this.fpMgr.UserBalanceForStore error: %s", err)
+	}
+	if len(this.notifiers) == 0 {
+		return nil
+	}
+	balances, err := this.store.LoadUserBalance(account)
+	if err != nil {
+		return fmt.Errorf("RefreshAccount, this.store.LoadUserBalance error: %s", err)
 	}
+	update := &hcommon.AccountUpdate{Address: account, Positions: make([]*hcommon.Position, 0, len(balances))}
+	for _, v := range balances {
+		update.Positions = append(update.Positions, &hcommon.Position{
+			Name:             v.AssetName,
+			Icon:             v.Icon,
+			SupplyBalance:    v.SupplyBalance,
+			BorrowBalance:    v.BorrowBalance,
+			InsuranceBalance: v.InsuranceBalance,
+			IfCollateral:     v.IfCollateral,
+		})
+	}
+	this.notify(hcommon.TOPIC_ACCOUNT, update)
+	return nil
 }
 
-func (this *Service) StoreFlashPoolAllMarket() error {
+func (this *Service) StoreFlashPoolAllMarket() (err error) {
+	start := time.Now()
+	defer func() { this.recordJob(hcommon.JOB_FLASHPOOL_MARKETS, start, err) }()
 	flashPoolAllMarket, err := this.fpMgr.FlashPoolAllMarketForStore()
 	if err != nil {
 		log.Errorf("StoreFlashPoolAllMarket, this.fpMgr.FlashPoolAllMarketForStore error: %s", err)
@@ -99,16 +261,57 @@ func (this *Service) StoreFlashPoolAllMarket() error {
 			return err
 		}
 	}
+	setMarketMetrics(flashPoolAllMarket.FlashPoolAllMarket)
+	this.notify(hcommon.TOPIC_MARKET, flashPoolAllMarket)
 	return nil
 }
 
-func (this *Service) StoreWingApy() {
-	err := this.fpMgr.WingApyForStore()
+// setMarketMetrics updates the TVL, borrow and APY gauges from a market snapshot
+func setMarketMetrics(markets []*hcommon.Market) {
+	var tvl, borrow float64
+	for _, v := range markets {
+		supplyDollar := parseFloat(v.TotalSupplyDollar)
+		borrowDollar := parseFloat(v.TotalBorrowDollar)
+		insuranceDollar := parseFloat(v.TotalInsuranceDollar)
+		metrics.MarketSupply.WithLabelValues(v.Name).Set(supplyDollar)
+		metrics.MarketBorrow.WithLabelValues(v.Name).Set(borrowDollar)
+		metrics.MarketInsurance.WithLabelValues(v.Name).Set(insuranceDollar)
+		metrics.MarketSupplyApy.WithLabelValues(v.Name).Set(parseFloat(v.SupplyApy))
+		metrics.MarketBorrowApy.WithLabelValues(v.Name).Set(parseFloat(v.BorrowApy))
+		tvl += supplyDollar + insuranceDollar
+		borrow += borrowDollar
+	}
+	metrics.Tvl.Set(tvl)
+	metrics.TotalBorrow.Set(borrow)
+}
+
+// parseFloat reads a decimal string for a gauge, empty and malformed values count as zero
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func (this *Service) StoreWingHolder(account string, height uint32) {
+	err := this.wingMgr.HolderBalanceForStore(account, height)
 	if err != nil {
-		log.Errorf("StoreUserBalance, this.fpMgr.WingApyForStore error: %s", err)
+		log.Errorf("StoreWingHolder, this.wingMgr.HolderBalanceForStore error: %s", err)
 	}
 }
 
+func (this *Service) StoreWingApy() (err error) {
+	start := time.Now()
+	defer func() { this.recordJob(hcommon.JOB_WING_APY, start, err) }()
+	err = this.fpMgr.WingApyForStore()
+	if err != nil {
+		log.Errorf("StoreWingApy, this.fpMgr.WingApyForStore error: %s", err)
+		return fmt.Errorf("StoreWingApy, this.fpMgr.WingApyForStore error: %s", err)
+	}
+	return nil
+}
+
 func listContains(list []string, arg string) bool {
 	for _, v := range list {
 		if arg == v {
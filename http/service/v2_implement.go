@@ -0,0 +1,405 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	ocommon "github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+func (this *Service) V2Markets(r *http.Request) (interface{}, error) {
+	flashPoolAllMarket, err := this.fpMgr.FlashPoolAllMarket()
+	if err != nil {
+		return nil, err
+	}
+	wingApys, err := this.fpMgr.WingApys()
+	if err != nil {
+		return nil, err
+	}
+	markets := make([]*common.ApiMarket, 0, len(flashPoolAllMarket.FlashPoolAllMarket))
+	for _, v := range flashPoolAllMarket.FlashPoolAllMarket {
+		markets = append(markets, toApiMarket(v, wingApys))
+	}
+	return markets, nil
+}
+
+func (this *Service) V2Market(r *http.Request) (interface{}, error) {
+	asset := restful.Param(r, "asset")
+	markets, err := this.V2Markets(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range markets.([]*common.ApiMarket) {
+		if v.Asset == asset {
+			return v, nil
+		}
+	}
+	return nil, common.NotFound(fmt.Sprintf("market %s not found", asset))
+}
+
+func (this *Service) V2Prices(r *http.Request) (interface{}, error) {
+	assetList := this.getAssetList()
+	prices := make([]*common.ApiPrice, 0, len(assetList))
+	for _, v := range assetList {
+		price, err := this.store.LoadPrice(v)
+		if err != nil {
+			if store.IsRecordNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		prices = append(prices, &common.ApiPrice{Asset: v, Price: number(price.Price)})
+	}
+	return prices, nil
+}
+
+func (this *Service) V2Price(r *http.Request) (interface{}, error) {
+	asset := restful.Param(r, "asset")
+	if !listContains(this.getAssetList(), asset) {
+		return nil, common.NotFound(fmt.Sprintf("price of %s not found", asset))
+	}
+	price, err := this.store.LoadPrice(asset)
+	if err != nil {
+		if store.IsRecordNotFound(err) {
+			return nil, common.NotFound(fmt.Sprintf("price of %s not found", asset))
+		}
+		return nil, err
+	}
+	return &common.ApiPrice{Asset: asset, Price: number(price.Price)}, nil
+}
+
+func (this *Service) V2Reserves(r *http.Request) (interface{}, error) {
+	reserves, err := this.fpMgr.Reserves()
+	if err != nil {
+		return nil, err
+	}
+	apiReserves := &common.ApiReserves{
+		Total:    number(reserves.TotalReserve),
+		Reserves: make([]*common.ApiReserve, 0, len(reserves.AssetReserve)),
+	}
+	for _, v := range reserves.AssetReserve {
+		apiReserves.Reserves = append(apiReserves.Reserves, &common.ApiReserve{
+			Asset:         v.Name,
+			Icon:          v.Icon,
+			ReserveFactor: number(v.ReserveFactor),
+			Reserve:       number(v.ReserveBalance),
+			ReserveUsd:    number(v.ReserveDollar),
+		})
+	}
+	return apiReserves, nil
+}
+
+func (this *Service) V2UserOverview(r *http.Request) (interface{}, error) {
+	address, err := addressParam(r)
+	if err != nil {
+		return nil, err
+	}
+	overview, err := this.fpMgr.UserFlashPoolOverview(address)
+	if err != nil {
+		return nil, err
+	}
+	apiOverview := &common.ApiUserOverview{
+		Address:           address,
+		BorrowLimit:       number(overview.BorrowLimit),
+		NetApy:            number(overview.NetApy),
+		NetApyWithRewards: number(overview.NetApyWithRewards),
+		YearlyYield:       number(overview.YearlyYield),
+		Supplies:          make([]*common.ApiPosition, 0, len(overview.CurrentSupply)),
+		Borrows:           make([]*common.ApiPosition, 0, len(overview.CurrentBorrow)),
+		Insurances:        make([]*common.ApiPosition, 0, len(overview.CurrentInsurance)),
+	}
+	for _, v := range overview.CurrentSupply {
+		apiOverview.Supplies = append(apiOverview.Supplies, &common.ApiPosition{
+			Asset:      v.Name,
+			Icon:       v.Icon,
+			Balance:    number(v.SupplyBalance),
+			Apy:        number(v.Apy),
+			RewardApy:  number(v.RewardApy),
+			WingEarned: number(v.WingEarned),
+			Collateral: v.IfCollateral,
+		})
+	}
+	for _, v := range overview.CurrentBorrow {
+		apiOverview.Borrows = append(apiOverview.Borrows, &common.ApiPosition{
+			Asset:      v.Name,
+			Icon:       v.Icon,
+			Balance:    number(v.BorrowBalance),
+			Apy:        number(v.Apy),
+			RewardApy:  number(v.RewardApy),
+			WingEarned: number(v.WingEarned),
+			Limit:      number(v.Limit),
+		})
+	}
+	for _, v := range overview.CurrentInsurance {
+		apiOverview.Insurances = append(apiOverview.Insurances, &common.ApiPosition{
+			Asset:      v.Name,
+			Icon:       v.Icon,
+			Balance:    number(v.InsuranceBalance),
+			Apy:        number(v.Apy),
+			RewardApy:  number(v.RewardApy),
+			WingEarned: number(v.WingEarned),
+		})
+	}
+	return apiOverview, nil
+}
+
+func (this *Service) V2UserLiquidations(r *http.Request) (interface{}, error) {
+	address, err := addressParam(r)
+	if err != nil {
+		return nil, err
+	}
+	liquidationList, err := this.fpMgr.LiquidationList(address)
+	if err != nil {
+		return nil, err
+	}
+	liquidations := make([]*common.ApiLiquidation, 0, len(liquidationList))
+	for _, v := range liquidationList {
+		liquidation := &common.ApiLiquidation{
+			Asset:           v.Name,
+			Icon:            v.Icon,
+			BorrowLimitUsed: number(v.BorrowLimitUsed),
+			Borrow:          number(v.BorrowBalance),
+			BorrowUsd:       number(v.BorrowDollar),
+			CollateralUsd:   number(v.CollateralDollar),
+			Collateral:      make([]*common.ApiCollateral, 0, len(v.CollateralAssets)),
+		}
+		for _, c := range v.CollateralAssets {
+			liquidation.Collateral = append(liquidation.Collateral, &common.ApiCollateral{
+				Asset:      c.Name,
+				Icon:       c.Icon,
+				Balance:    number(c.Balance),
+				BalanceUsd: number(c.Dollar),
+			})
+		}
+		liquidations = append(liquidations, liquidation)
+	}
+	return liquidations, nil
+}
+
+func (this *Service) V2UserWingEarnings(r *http.Request) (interface{}, error) {
+	address, err := addressParam(r)
+	if err != nil {
+		return nil, err
+	}
+	wingEarnings, err := this.fpMgr.WingEarnings(address)
+	if err != nil {
+		return nil, err
+	}
+	apiWingEarnings := &common.ApiWingEarnings{
+		Earned:       number(wingEarnings.Earned),
+		Claimed:      number(wingEarnings.Claimed),
+		Unclaimed:    number(wingEarnings.Unclaimed),
+		Markets:      make([]*common.ApiMarketWingEarned, 0, len(wingEarnings.Markets)),
+		SplitMethod:  wingEarnings.SplitMethod,
+		ClaimedScope: wingEarnings.ClaimedScope,
+	}
+	for _, v := range wingEarnings.Markets {
+		apiWingEarnings.Markets = append(apiWingEarnings.Markets, &common.ApiMarketWingEarned{
+			Asset:     v.Name,
+			Supply:    number(v.SupplyEarned),
+			Borrow:    number(v.BorrowEarned),
+			Insurance: number(v.InsuranceEarned),
+			Total:     number(v.Total),
+		})
+	}
+	return apiWingEarnings, nil
+}
+
+func (this *Service) V2UserWingClaims(r *http.Request) (interface{}, error) {
+	address, err := addressParam(r)
+	if err != nil {
+		return nil, err
+	}
+	offset, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+	wingClaimHistory, err := this.fpMgr.WingClaimHistory(address, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	claims := make([]*common.ApiWingClaim, 0, len(wingClaimHistory.Claims))
+	for _, v := range wingClaimHistory.Claims {
+		claims = append(claims, &common.ApiWingClaim{
+			TxHash:    v.TxHash,
+			Height:    v.Height,
+			Timestamp: v.Timestamp,
+			Amount:    number(v.Amount),
+		})
+	}
+	return &common.ApiWingClaimPage{Total: wingClaimHistory.Total, Offset: offset, Items: claims}, nil
+}
+
+func (this *Service) V2Governance(r *http.Request) (interface{}, error) {
+	govBannerOverview, err := this.govMgr.GovBannerOverview()
+	if err != nil {
+		return nil, err
+	}
+	govBanner, err := this.govMgr.GovBanner()
+	if err != nil {
+		return nil, err
+	}
+	return &common.ApiGovernance{
+		Remain20:    number(govBannerOverview.Remain20),
+		Remain80:    number(govBannerOverview.Remain80),
+		Daily:       number(govBanner.Daily),
+		Distributed: number(govBanner.Distributed),
+	}, nil
+}
+
+func (this *Service) V2GovProposals(r *http.Request) (interface{}, error) {
+	offset, limit, err := pageParams(r)
+	if err != nil {
+		return nil, err
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", common.PROPOSAL_STATUS_ACTIVE, common.PROPOSAL_STATUS_EXECUTED, common.PROPOSAL_STATUS_CANCELED:
+	default:
+		return nil, common.BadRequest(fmt.Sprintf("unknown status %s", status))
+	}
+	govProposals, err := this.govMgr.GovProposals(status, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	proposals := make([]*common.ApiProposal, 0, len(govProposals.Proposals))
+	for _, v := range govProposals.Proposals {
+		proposals = append(proposals, toApiProposal(v))
+	}
+	return &common.ApiProposalPage{Total: govProposals.Total, Offset: offset, Items: proposals}, nil
+}
+
+func (this *Service) V2GovProposalDetail(r *http.Request) (interface{}, error) {
+	proposalId := restful.Param(r, "id")
+	_, err := this.store.LoadGovProposal(proposalId)
+	if err != nil {
+		if store.IsRecordNotFound(err) {
+			return nil, common.NotFound(fmt.Sprintf("proposal %s not found", proposalId))
+		}
+		return nil, err
+	}
+	govProposalDetail, err := this.govMgr.GovProposalDetail(proposalId)
+	if err != nil {
+		return nil, err
+	}
+	detail := &common.ApiProposalDetail{
+		ApiProposal: toApiProposal(govProposalDetail.Proposal),
+		TotalWeight: number(govProposalDetail.TotalWeight),
+		Tallies:     make([]*common.ApiTally, 0, len(govProposalDetail.Tallies)),
+		Votes:       make([]*common.ApiVote, 0, len(govProposalDetail.Votes)),
+	}
+	for _, v := range govProposalDetail.Tallies {
+		detail.Tallies = append(detail.Tallies, &common.ApiTally{
+			Option: v.Option,
+			Votes:  v.VoteCount,
+			Weight: number(v.Weight),
+			Share:  number(v.Share),
+		})
+	}
+	for _, v := range govProposalDetail.Votes {
+		detail.Votes = append(detail.Votes, &common.ApiVote{
+			Voter:  v.Voter,
+			Option: v.Option,
+			Weight: number(v.Weight),
+			Height: v.Height,
+			TxHash: v.TxHash,
+		})
+	}
+	return detail, nil
+}
+
+func toApiMarket(market *common.Market, wingApys []common.WingApy) *common.ApiMarket {
+	wingApy := common.WingApy{}
+	for _, v := range wingApys {
+		if v.AssetName == market.Name {
+			wingApy = v
+			break
+		}
+	}
+	return &common.ApiMarket{
+		Asset:            market.Name,
+		Icon:             market.Icon,
+		CollateralFactor: number(market.CollateralFactor),
+		Supply: &common.ApiMarketSide{
+			Total:            number(market.TotalSupplyAmount),
+			TotalUsd:         number(market.TotalSupplyDollar),
+			Apy:              number(market.SupplyApy),
+			WingApy:          number(wingApy.SupplyApy),
+			WingDistribution: number(market.SupplyDistribution),
+		},
+		Borrow: &common.ApiMarketSide{
+			Total:            number(market.TotalBorrowAmount),
+			TotalUsd:         number(market.TotalBorrowDollar),
+			Apy:              number(market.BorrowApy),
+			WingApy:          number(wingApy.BorrowApy),
+			WingDistribution: number(market.BorrowDistribution),
+		},
+		Insurance: &common.ApiMarketSide{
+			Total:            number(market.TotalInsuranceAmount),
+			TotalUsd:         number(market.TotalInsuranceDollar),
+			Apy:              number(market.InsuranceApy),
+			WingApy:          number(wingApy.InsuranceApy),
+			WingDistribution: number(market.InsuranceDistribution),
+		},
+	}
+}
+
+func toApiProposal(proposal *common.GovProposal) *common.ApiProposal {
+	return &common.ApiProposal{
+		Id:           proposal.ProposalId,
+		Proposer:     proposal.Proposer,
+		Title:        proposal.Title,
+		Description:  proposal.Description,
+		StartHeight:  proposal.StartHeight,
+		EndHeight:    proposal.EndHeight,
+		Status:       proposal.Status,
+		CreateHeight: proposal.CreateHeight,
+		CreateTxHash: proposal.CreateTxHash,
+		UpdateHeight: proposal.UpdateHeight,
+		UpdateTxHash: proposal.UpdateTxHash,
+	}
+}
+
+func addressParam(r *http.Request) (string, error) {
+	address := restful.Param(r, "address")
+	if _, err := ocommon.AddressFromBase58(address); err != nil {
+		return "", common.BadRequest(fmt.Sprintf("invalid address %s", address))
+	}
+	return address, nil
+}
+
+func pageParams(r *http.Request) (uint64, uint64, error) {
+	var offset, limit uint64
+	var err error
+	query := r.URL.Query()
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return 0, 0, common.BadRequest(fmt.Sprintf("invalid offset %s", v))
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return 0, 0, common.BadRequest(fmt.Sprintf("invalid limit %s", v))
+		}
+	}
+	return offset, limit, nil
+}
+
+var decimalRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// number turns the decimal strings of v1 into JSON numbers, anything else is logged and becomes null
+func number(s string) *json.Number {
+	if !decimalRegexp.MatchString(s) {
+		log.Warnf("number, invalid decimal %q", s)
+		return nil
+	}
+	n := json.Number(s)
+	return &n
+}
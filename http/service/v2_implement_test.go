@@ -0,0 +1,30 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	for _, v := range []struct {
+		value    string
+		expected string
+	}{
+		{"12", "12"},
+		{"-0.05", "-0.05"},
+		{"1234567890123456789.123456789", "1234567890123456789.123456789"},
+		{"", "null"},
+		{"1e5", "null"},
+		{"1.", "null"},
+		{"NaN", "null"},
+		{"12%", "null"},
+	} {
+		data, err := json.Marshal(struct{ N *json.Number }{number(v.value)})
+		if err != nil {
+			t.Fatalf("json.Marshal error: %s", err)
+		}
+		if string(data) != `{"N":`+v.expected+`}` {
+			t.Errorf("number(%q) is written %s", v.value, data)
+		}
+	}
+}
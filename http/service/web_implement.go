@@ -2,6 +2,8 @@ package service
 
 import (
 	"fmt"
+	"strconv"
+
 	"github.com/siovanus/wingServer/http/common"
 	"github.com/siovanus/wingServer/http/restful"
 	"github.com/siovanus/wingServer/log"
@@ -404,3 +406,250 @@ func (this *Service) Reserves(param map[string]interface{}) map[string]interface
 	}
 	return m
 }
+
+func (this *Service) EmissionProjection(param map[string]interface{}) map[string]interface{} {
+	req := &common.EmissionProjectionRequest{}
+	resp := &common.Response{}
+	err := utils.ParseParams(req, param)
+	if err == nil && req.Interval == "" {
+		req.Interval = common.PROJECTION_INTERVAL_DAY
+	}
+	if err == nil && req.Interval != common.PROJECTION_INTERVAL_DAY && req.Interval != common.PROJECTION_INTERVAL_MONTH {
+		err = fmt.Errorf("invalid interval %s", req.Interval)
+	}
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("EmissionProjection: decode params failed, err: %s", err)
+	} else {
+		wingSpeeds := make([]*common.WingSpeed, 0)
+		if req.PerMarket == "true" {
+			wingSpeeds, err = this.fpMgr.WingSpeeds()
+		}
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("EmissionProjection, this.fpMgr.WingSpeeds error: %s", err)
+		} else {
+			emissionProjection, err := this.govMgr.EmissionProjection(req.Interval, wingSpeeds)
+			if err != nil {
+				resp.Error = restful.INTERNAL_ERROR
+				resp.Desc = err.Error()
+				log.Errorf("EmissionProjection error: %s", err)
+			} else {
+				resp.Error = restful.SUCCESS
+				resp.Result = emissionProjection
+				log.Infof("EmissionProjection success")
+			}
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("EmissionProjection: failed, err: %s", err)
+	} else {
+		log.Debug("EmissionProjection: resp success")
+	}
+	return m
+}
+
+func (this *Service) WingSupply(param map[string]interface{}) map[string]interface{} {
+	resp := &common.Response{}
+	wingSupply, err := this.wingMgr.WingSupply()
+	if err != nil {
+		resp.Error = restful.INTERNAL_ERROR
+		resp.Desc = err.Error()
+		log.Errorf("WingSupply error: %s", err)
+	} else {
+		resp.Error = restful.SUCCESS
+		resp.Result = wingSupply
+		log.Infof("WingSupply success")
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("WingSupply: failed, err: %s", err)
+	} else {
+		log.Debug("WingSupply: resp success")
+	}
+	return m
+}
+
+func (this *Service) WingHolders(param map[string]interface{}) map[string]interface{} {
+	req := &common.WingHoldersRequest{}
+	resp := &common.Response{}
+	var limit uint64
+	err := utils.ParseParams(req, param)
+	if err == nil && req.Limit != "" {
+		limit, err = strconv.ParseUint(req.Limit, 10, 64)
+	}
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("WingHolders: decode params failed, err: %s", err)
+	} else {
+		wingHolders, err := this.wingMgr.WingHolders(limit)
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("WingHolders error: %s", err)
+		} else {
+			resp.Error = restful.SUCCESS
+			resp.Result = wingHolders
+			log.Infof("WingHolders success")
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("WingHolders: failed, err: %s", err)
+	} else {
+		log.Debug("WingHolders: resp success")
+	}
+	return m
+}
+
+func (this *Service) WingEarnings(param map[string]interface{}) map[string]interface{} {
+	req := &common.WingEarningsRequest{}
+	resp := &common.Response{}
+	err := utils.ParseParams(req, param)
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("WingEarnings: decode params failed, err: %s", err)
+	} else {
+		wingEarnings, err := this.fpMgr.WingEarnings(req.Address)
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("WingEarnings error: %s", err)
+		} else {
+			resp.Error = restful.SUCCESS
+			resp.Result = &common.WingEarningsResponse{
+				Id:           req.Id,
+				Address:      req.Address,
+				WingEarnings: wingEarnings,
+			}
+			log.Infof("WingEarnings success")
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("WingEarnings: failed, err: %s", err)
+	} else {
+		log.Debug("WingEarnings: resp success")
+	}
+	return m
+}
+
+func (this *Service) WingClaimHistory(param map[string]interface{}) map[string]interface{} {
+	req := &common.WingClaimHistoryRequest{}
+	resp := &common.Response{}
+	var offset, limit uint64
+	err := utils.ParseParams(req, param)
+	if err == nil && req.Offset != "" {
+		offset, err = strconv.ParseUint(req.Offset, 10, 64)
+	}
+	if err == nil && req.Limit != "" {
+		limit, err = strconv.ParseUint(req.Limit, 10, 64)
+	}
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("WingClaimHistory: decode params failed, err: %s", err)
+	} else {
+		wingClaimHistory, err := this.fpMgr.WingClaimHistory(req.Address, offset, limit)
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("WingClaimHistory error: %s", err)
+		} else {
+			resp.Error = restful.SUCCESS
+			resp.Result = &common.WingClaimHistoryResponse{
+				Id:               req.Id,
+				Address:          req.Address,
+				WingClaimHistory: wingClaimHistory,
+			}
+			log.Infof("WingClaimHistory success")
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("WingClaimHistory: failed, err: %s", err)
+	} else {
+		log.Debug("WingClaimHistory: resp success")
+	}
+	return m
+}
+
+func (this *Service) GovProposals(param map[string]interface{}) map[string]interface{} {
+	req := &common.GovProposalsRequest{}
+	resp := &common.Response{}
+	var offset, limit uint64
+	err := utils.ParseParams(req, param)
+	if err == nil && req.Offset != "" {
+		offset, err = strconv.ParseUint(req.Offset, 10, 64)
+	}
+	if err == nil && req.Limit != "" {
+		limit, err = strconv.ParseUint(req.Limit, 10, 64)
+	}
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("GovProposals: decode params failed, err: %s", err)
+	} else {
+		govProposals, err := this.govMgr.GovProposals(req.Status, offset, limit)
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("GovProposals error: %s", err)
+		} else {
+			resp.Error = restful.SUCCESS
+			resp.Result = govProposals
+			log.Infof("GovProposals success")
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("GovProposals: failed, err: %s", err)
+	} else {
+		log.Debug("GovProposals: resp success")
+	}
+	return m
+}
+
+func (this *Service) GovProposalDetail(param map[string]interface{}) map[string]interface{} {
+	req := &common.GovProposalDetailRequest{}
+	resp := &common.Response{}
+	err := utils.ParseParams(req, param)
+	if err == nil && req.ProposalId == "" {
+		err = fmt.Errorf("ProposalId is empty")
+	}
+	if err != nil {
+		resp.Error = restful.INVALID_PARAMS
+		resp.Desc = err.Error()
+		log.Errorf("GovProposalDetail: decode params failed, err: %s", err)
+	} else {
+		govProposalDetail, err := this.govMgr.GovProposalDetail(req.ProposalId)
+		if err != nil {
+			resp.Error = restful.INTERNAL_ERROR
+			resp.Desc = err.Error()
+			log.Errorf("GovProposalDetail error: %s", err)
+		} else {
+			resp.Error = restful.SUCCESS
+			resp.Result = govProposalDetail
+			log.Infof("GovProposalDetail success")
+		}
+	}
+
+	m, err := utils.RefactorResp(resp, resp.Error)
+	if err != nil {
+		log.Errorf("GovProposalDetail: failed, err: %s", err)
+	} else {
+		log.Debug("GovProposalDetail: resp success")
+	}
+	return m
+}
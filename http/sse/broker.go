@@ -0,0 +1,211 @@
+// Package sse streams decoded protocol events to clients as Server-Sent Events
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	heartbeatPeriod = 15 * time.Second
+	sendBufferSize  = 64
+	replayBatchSize = 500
+)
+
+// Store is implemented by store.Client
+type Store interface {
+	LoadProtocolEvents(height, logIndex uint32, limit uint64) ([]store.ProtocolEvent, error)
+}
+
+type Broker struct {
+	sync.RWMutex
+	store       Store
+	subscribers map[*subscriber]bool
+}
+
+type subscriber struct {
+	filter *filter
+	send   chan []*store.ProtocolEvent
+}
+
+func NewBroker(store Store) *Broker {
+	return &Broker{
+		store:       store,
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+// Notify forwards the events of one block to every subscriber
+func (this *Broker) Notify(topic string, data interface{}) {
+	if topic != common.TOPIC_EVENT {
+		return
+	}
+	events, ok := data.([]*store.ProtocolEvent)
+	if !ok {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+	for s := range this.subscribers {
+		select {
+		case s.send <- events:
+		default:
+			log.Warnf("Broker.Notify, sse subscriber is too slow, disconnect")
+			delete(this.subscribers, s)
+			close(s.send)
+		}
+	}
+}
+
+// ServeHTTP streams events matching the asset, account and type query parameters, each accepting a
+// comma separated list. Streaming resumes after Last-Event-ID, formatted as <height>-<logIndex>.
+func (this *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	var height, logIndex uint32
+	resume := lastEventId != ""
+	if resume {
+		var err error
+		height, logIndex, err = parseEventId(lastEventId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s := &subscriber{
+		filter: newFilter(r),
+		send:   make(chan []*store.ProtocolEvent, sendBufferSize),
+	}
+	this.Lock()
+	this.subscribers[s] = true
+	this.Unlock()
+	defer this.remove(s)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// replay from the database, live events already queued are skipped below if they were replayed
+	if resume {
+		for {
+			events, err := this.store.LoadProtocolEvents(height, logIndex, replayBatchSize)
+			if err != nil {
+				log.Errorf("Broker.ServeHTTP, this.store.LoadProtocolEvents error: %s", err)
+				return
+			}
+			for i := range events {
+				if err := write(w, s.filter, &events[i]); err != nil {
+					return
+				}
+				height, logIndex = events[i].Height, events[i].LogIndex
+			}
+			flusher.Flush()
+			if len(events) < replayBatchSize {
+				break
+			}
+		}
+	}
+
+	ticker := time.NewTicker(heartbeatPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case events, ok := <-s.send:
+			if !ok {
+				return
+			}
+			for _, v := range events {
+				if resume && !after(v, height, logIndex) {
+					continue
+				}
+				if err := write(w, s.filter, v); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Close ends every stream, clients reconnect with their Last-Event-ID
+func (this *Broker) Close() {
+	this.Lock()
+	defer this.Unlock()
+	for s := range this.subscribers {
+		delete(this.subscribers, s)
+		close(s.send)
+	}
+}
+
+func (this *Broker) remove(s *subscriber) {
+	this.Lock()
+	defer this.Unlock()
+	if _, ok := this.subscribers[s]; ok {
+		delete(this.subscribers, s)
+		close(s.send)
+	}
+}
+
+func write(w http.ResponseWriter, f *filter, event *store.ProtocolEvent) error {
+	if !f.match(event) {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", formatEventId(event), event.Type, data)
+	return err
+}
+
+// after tells whether event comes after the event at height and logIndex in chain order
+func after(event *store.ProtocolEvent, height, logIndex uint32) bool {
+	return event.Height > height || event.Height == height && event.LogIndex > logIndex
+}
+
+func formatEventId(event *store.ProtocolEvent) string {
+	return fmt.Sprintf("%d-%d", event.Height, event.LogIndex)
+}
+
+func parseEventId(id string) (uint32, uint32, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid event id %s", id)
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id %s: %s", id, err)
+	}
+	logIndex, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id %s: %s", id, err)
+	}
+	return uint32(height), uint32(logIndex), nil
+}
@@ -0,0 +1,131 @@
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	testAccount      = "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+	testCounterparty = "AQf4Mzu1YJrhz9f3aRkkwSm9n3qhXGSh4p"
+)
+
+type fakeStore struct {
+	events []store.ProtocolEvent
+}
+
+func (this *fakeStore) LoadProtocolEvents(height, logIndex uint32, limit uint64) ([]store.ProtocolEvent, error) {
+	events := make([]store.ProtocolEvent, 0)
+	for i := range this.events {
+		if after(&this.events[i], height, logIndex) && uint64(len(events)) < limit {
+			events = append(events, this.events[i])
+		}
+	}
+	return events, nil
+}
+
+func TestParseEventId(t *testing.T) {
+	height, logIndex, err := parseEventId("1200-3")
+	if err != nil || height != 1200 || logIndex != 3 {
+		t.Errorf("parseEventId: %d %d %v", height, logIndex, err)
+	}
+	event := &store.ProtocolEvent{Height: height, LogIndex: logIndex}
+	if id := formatEventId(event); id != "1200-3" {
+		t.Errorf("formatEventId: %s", id)
+	}
+	for _, id := range []string{"", "1200", "1200-3-1", "a-3", "1200-b", "-1-3", "4294967296-0"} {
+		if _, _, err := parseEventId(id); err == nil {
+			t.Errorf("parseEventId %q: expected an error", id)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	event := &store.ProtocolEvent{Type: common.EVENT_TYPE_LIQUIDATION, Asset: "pUSDC", Account: testAccount,
+		Counterparty: testCounterparty}
+	cases := []struct {
+		query string
+		match bool
+	}{
+		{"", true},
+		{"asset=pusdc", true},
+		{"asset=pWBTC,+pUSDC", true},
+		{"asset=pWBTC", false},
+		{"type=LIQUIDATION", true},
+		{"type=supply,borrow", false},
+		{"account=" + testCounterparty, true},
+		{"account=" + strings.ToLower(testAccount), false},
+		{"asset=pUSDC&type=liquidation&account=" + testAccount, true},
+		{"asset=pUSDC&type=supply&account=" + testAccount, false},
+		{"asset=,", true},
+	}
+	for _, v := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/v2/events?"+v.query, nil)
+		if match := newFilter(r).match(event); match != v.match {
+			t.Errorf("%q: match %v", v.query, match)
+		}
+	}
+}
+
+// TestResume replays the events after Last-Event-ID, then skips the live events already replayed
+func TestResume(t *testing.T) {
+	replayed := []store.ProtocolEvent{
+		{Height: 5, LogIndex: 1, Type: common.EVENT_TYPE_SUPPLY, Account: testAccount},
+		{Height: 5, LogIndex: 2, Type: common.EVENT_TYPE_SUPPLY, Account: testAccount},
+		{Height: 5, LogIndex: 3, Type: common.EVENT_TYPE_BORROW, Account: testAccount},
+	}
+	broker := NewBroker(&fakeStore{events: append([]store.ProtocolEvent{
+		{Height: 4, LogIndex: 9, Type: common.EVENT_TYPE_SUPPLY, Account: testAccount}}, replayed...)})
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	defer broker.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?type=supply", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest error: %s", err)
+	}
+	req.Header.Set("Last-Event-ID", "5-0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.DefaultClient.Do error: %s", err)
+	}
+	defer resp.Body.Close()
+	ids := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "id: ") {
+				ids <- strings.TrimPrefix(scanner.Text(), "id: ")
+			}
+		}
+		close(ids)
+	}()
+	next := func() string {
+		select {
+		case id := <-ids:
+			return id
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no event")
+		}
+		return ""
+	}
+
+	// the borrow is replayed but filtered out
+	for _, expected := range []string{"5-1", "5-2"} {
+		if id := next(); id != expected {
+			t.Fatalf("replayed %s, expected %s", id, expected)
+		}
+	}
+	broker.Notify(common.TOPIC_EVENT, []*store.ProtocolEvent{&replayed[1], &replayed[2],
+		{Height: 6, LogIndex: 0, Type: common.EVENT_TYPE_SUPPLY, Account: testAccount}})
+	if id := next(); id != "6-0" {
+		t.Errorf("streamed %s, expected 6-0", id)
+	}
+}
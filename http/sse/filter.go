@@ -0,0 +1,52 @@
+package sse
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/siovanus/wingServer/store"
+)
+
+type filter struct {
+	assets   map[string]bool
+	accounts map[string]bool
+	types    map[string]bool
+}
+
+func newFilter(r *http.Request) *filter {
+	query := r.URL.Query()
+	return &filter{
+		assets:   parseList(query.Get("asset"), true),
+		accounts: parseList(query.Get("account"), false),
+		types:    parseList(query.Get("type"), true),
+	}
+}
+
+// match reports whether the event passes every filter, an account filter matches either side of it
+func (this *filter) match(event *store.ProtocolEvent) bool {
+	if len(this.assets) != 0 && !this.assets[strings.ToLower(event.Asset)] {
+		return false
+	}
+	if len(this.types) != 0 && !this.types[strings.ToLower(event.Type)] {
+		return false
+	}
+	if len(this.accounts) != 0 && !this.accounts[event.Account] && !this.accounts[event.Counterparty] {
+		return false
+	}
+	return true
+}
+
+func parseList(value string, lower bool) map[string]bool {
+	result := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if lower {
+			v = strings.ToLower(v)
+		}
+		result[v] = true
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+)
+
+type client struct {
+	sync.RWMutex
+	hub    *Hub
+	conn   *gws.Conn
+	send   chan []byte
+	topics map[string]bool
+}
+
+func (this *client) subscribed(topic string) bool {
+	this.RLock()
+	defer this.RUnlock()
+	return this.topics[topic]
+}
+
+// push never blocks, a client too slow to drain its buffer is disconnected
+func (this *client) push(msg []byte) {
+	select {
+	case this.send <- msg:
+	default:
+		log.Warnf("websocket client %s is too slow, disconnect", this.conn.RemoteAddr())
+		this.conn.Close()
+	}
+}
+
+func (this *client) readPump() {
+	defer func() {
+		this.hub.remove(this)
+		this.conn.Close()
+	}()
+	this.conn.SetReadLimit(maxMessageSize)
+	this.conn.SetReadDeadline(time.Now().Add(pongWait))
+	this.conn.SetPongHandler(func(string) error {
+		this.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		_, data, err := this.conn.ReadMessage()
+		if err != nil {
+			if gws.IsUnexpectedCloseError(err, gws.CloseGoingAway, gws.CloseNormalClosure) {
+				log.Errorf("client.readPump, this.conn.ReadMessage error: %s", err)
+			}
+			return
+		}
+		resp := this.handle(data)
+		msg, err := json.Marshal(resp)
+		if err != nil {
+			log.Errorf("client.readPump, json.Marshal error: %s", err)
+			continue
+		}
+		this.hub.RLock()
+		this.push(msg)
+		this.hub.RUnlock()
+	}
+}
+
+func (this *client) handle(data []byte) *common.Response {
+	req := &Request{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return &common.Response{Error: restful.ILLEGAL_DATAFORMAT,
+			Desc: fmt.Sprintf("%s: %s", restful.ErrMap[restful.ILLEGAL_DATAFORMAT], err)}
+	}
+	resp := &common.Response{Action: req.Action, Result: req.Topics}
+	for _, topic := range req.Topics {
+		if !validTopic(topic) {
+			resp.Error = restful.INVALID_PARAMS
+			resp.Desc = fmt.Sprintf("%s: unknown topic %s", restful.ErrMap[restful.INVALID_PARAMS], topic)
+			return resp
+		}
+	}
+	this.Lock()
+	defer this.Unlock()
+	switch req.Action {
+	case ACTION_SUBSCRIBE:
+		for _, topic := range req.Topics {
+			this.topics[topic] = true
+		}
+	case ACTION_UNSUBSCRIBE:
+		for _, topic := range req.Topics {
+			delete(this.topics, topic)
+		}
+	default:
+		resp.Error = restful.INVALID_METHOD
+		resp.Desc = fmt.Sprintf("%s: %s", restful.ErrMap[restful.INVALID_METHOD], req.Action)
+		return resp
+	}
+	resp.Desc = restful.ErrMap[restful.SUCCESS]
+	return resp
+}
+
+func (this *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		this.conn.Close()
+	}()
+	for {
+		select {
+		case msg, ok := <-this.send:
+			this.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				this.conn.WriteMessage(gws.CloseMessage, []byte{})
+				return
+			}
+			if err := this.conn.WriteMessage(gws.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			this.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := this.conn.WriteMessage(gws.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
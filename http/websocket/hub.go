@@ -0,0 +1,144 @@
+// Package websocket pushes market, price and account updates to subscribed clients
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	ACTION_SUBSCRIBE   = "subscribe"
+	ACTION_UNSUBSCRIBE = "unsubscribe"
+
+	ACCOUNT_TOPIC_PREFIX = common.TOPIC_ACCOUNT + ":"
+
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+	sendBufferSize = 64
+)
+
+type Request struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+type Hub struct {
+	sync.RWMutex
+	upgrader gws.Upgrader
+	clients  map[*client]bool
+}
+
+// NewHub creates a hub accepting the browser connections of allowedOrigins, * allows any origin and no
+// origin only the same host. Clients which send no Origin header, which are not browsers, are accepted
+func NewHub(allowedOrigins []string) *Hub {
+	return &Hub{
+		upgrader: gws.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
+		clients: make(map[*client]bool),
+	}
+}
+
+// checkOrigin returns nil without allowed origins, for the same host check of the upgrader
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	if len(allowedOrigins) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, v := range allowedOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(v, "/"))] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed["*"] || allowed[strings.ToLower(origin)]
+	}
+}
+
+// ServeHTTP upgrades the request to a websocket connection
+func (this *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := this.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Errorf("Hub.ServeHTTP, this.upgrader.Upgrade error: %s", err)
+		return
+	}
+	c := &client{
+		hub:    this,
+		conn:   conn,
+		send:   make(chan []byte, sendBufferSize),
+		topics: make(map[string]bool),
+	}
+	this.Lock()
+	this.clients[c] = true
+	this.Unlock()
+
+	go c.writePump()
+	go c.readPump()
+}
+
+// Notify pushes data to every client subscribed to topic, account updates are
+// pushed to the account:<address> topic
+func (this *Hub) Notify(topic string, data interface{}) {
+	if topic == common.TOPIC_ACCOUNT {
+		update, ok := data.(*common.AccountUpdate)
+		if !ok {
+			return
+		}
+		topic = ACCOUNT_TOPIC_PREFIX + update.Address
+	}
+	if !validTopic(topic) {
+		return
+	}
+	msg, err := json.Marshal(&common.Response{
+		Action: topic,
+		Desc:   "SUCCESS",
+		Result: data,
+	})
+	if err != nil {
+		log.Errorf("Hub.Notify, json.Marshal error: %s", err)
+		return
+	}
+	this.RLock()
+	defer this.RUnlock()
+	for c := range this.clients {
+		if c.subscribed(topic) {
+			c.push(msg)
+		}
+	}
+}
+
+// Close disconnects every client
+func (this *Hub) Close() {
+	this.Lock()
+	defer this.Unlock()
+	for c := range this.clients {
+		c.conn.Close()
+	}
+}
+
+func (this *Hub) remove(c *client) {
+	this.Lock()
+	defer this.Unlock()
+	if _, ok := this.clients[c]; ok {
+		delete(this.clients, c)
+		close(c.send)
+	}
+}
+
+func validTopic(topic string) bool {
+	switch topic {
+	case common.TOPIC_MARKET, common.TOPIC_PRICE:
+		return true
+	}
+	return strings.HasPrefix(topic, ACCOUNT_TOPIC_PREFIX) && len(topic) > len(ACCOUNT_TOPIC_PREFIX)
+}
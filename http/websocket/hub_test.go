@@ -0,0 +1,174 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/siovanus/wingServer/http/common"
+)
+
+const testAccount = "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+
+func newTestServer(t *testing.T, allowedOrigins []string) (*Hub, *httptest.Server) {
+	hub := NewHub(allowedOrigins)
+	server := httptest.NewServer(hub)
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+	return hub, server
+}
+
+func dial(t *testing.T, server *httptest.Server, header http.Header) *gws.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := gws.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Dial error: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *gws.Conn) *common.Response {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage error: %s", err)
+	}
+	resp := &common.Response{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		t.Fatalf("json.Unmarshal error: %s", err)
+	}
+	return resp
+}
+
+// request sends an action and waits for its answer, so the topics are set once it returns
+func request(t *testing.T, conn *gws.Conn, action string, topics ...string) *common.Response {
+	if err := conn.WriteJSON(&Request{Action: action, Topics: topics}); err != nil {
+		t.Fatalf("WriteJSON error: %s", err)
+	}
+	return read(t, conn)
+}
+
+// expectNothing fails if a message arrives within a short wait, the conn cannot be read after it
+func expectNothing(t *testing.T, conn *gws.Conn) {
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, data, err := conn.ReadMessage(); err == nil {
+		t.Errorf("unexpected message %s", data)
+	}
+}
+
+func TestHubNotify(t *testing.T) {
+	hub, server := newTestServer(t, nil)
+	priceConn := dial(t, server, nil)
+	accountConn := dial(t, server, nil)
+	if resp := request(t, priceConn, ACTION_SUBSCRIBE, common.TOPIC_PRICE); resp.Error != 0 {
+		t.Fatalf("subscribe error: %+v", resp)
+	}
+	if resp := request(t, accountConn, ACTION_SUBSCRIBE, ACCOUNT_TOPIC_PREFIX+testAccount); resp.Error != 0 {
+		t.Fatalf("subscribe error: %+v", resp)
+	}
+
+	hub.Notify(common.TOPIC_PRICE, "1.5")
+	if resp := read(t, priceConn); resp.Action != common.TOPIC_PRICE || resp.Result != "1.5" {
+		t.Errorf("unexpected price update %+v", resp)
+	}
+	hub.Notify(common.TOPIC_ACCOUNT, &common.AccountUpdate{Address: testAccount})
+	if resp := read(t, accountConn); resp.Action != ACCOUNT_TOPIC_PREFIX+testAccount {
+		t.Errorf("unexpected account update %+v", resp)
+	}
+	// neither is pushed the topic of the other, nor an update of another account
+	hub.Notify(common.TOPIC_ACCOUNT, &common.AccountUpdate{Address: "another"})
+	hub.Notify(common.TOPIC_MARKET, "market")
+	expectNothing(t, priceConn)
+	expectNothing(t, accountConn)
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub, server := newTestServer(t, nil)
+	conn := dial(t, server, nil)
+	request(t, conn, ACTION_SUBSCRIBE, common.TOPIC_MARKET, common.TOPIC_PRICE)
+	if resp := request(t, conn, ACTION_UNSUBSCRIBE, common.TOPIC_MARKET); resp.Error != 0 {
+		t.Fatalf("unsubscribe error: %+v", resp)
+	}
+	hub.Notify(common.TOPIC_MARKET, "market")
+	hub.Notify(common.TOPIC_PRICE, "price")
+	if resp := read(t, conn); resp.Action != common.TOPIC_PRICE {
+		t.Errorf("unexpected update %+v", resp)
+	}
+	if resp := request(t, conn, ACTION_SUBSCRIBE, "account:"); resp.Error == 0 {
+		t.Errorf("subscribed an account topic without address")
+	}
+	if resp := request(t, conn, "publish", common.TOPIC_PRICE); resp.Error == 0 {
+		t.Errorf("unknown action accepted")
+	}
+	expectNothing(t, conn)
+}
+
+func TestHubEvictSlowClient(t *testing.T) {
+	hub, server := newTestServer(t, nil)
+	conn := dial(t, server, nil)
+	request(t, conn, ACTION_SUBSCRIBE, common.TOPIC_PRICE)
+
+	hub.RLock()
+	var slow *client
+	for c := range hub.clients {
+		slow = c
+	}
+	hub.RUnlock()
+	// a client whose buffer is full, push can only disconnect it
+	(&client{hub: hub, conn: slow.conn, send: make(chan []byte)}).push([]byte("update"))
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatalf("slow client not disconnected")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.RLock()
+		count := len(hub.clients)
+		hub.RUnlock()
+		if count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slow client not removed from the hub")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHubCheckOrigin(t *testing.T) {
+	cases := []struct {
+		allowed []string
+		origin  string
+		ok      bool
+	}{
+		{nil, "", true},
+		{nil, "https://evil.example", false},
+		{[]string{"https://app.ont.io/"}, "https://APP.ont.io", true},
+		{[]string{"https://app.ont.io"}, "https://evil.example", false},
+		{[]string{"https://app.ont.io"}, "", true},
+		{[]string{"*"}, "https://evil.example", true},
+	}
+	for _, v := range cases {
+		_, server := newTestServer(t, v.allowed)
+		header := http.Header{}
+		if v.origin != "" {
+			header.Set("Origin", v.origin)
+		}
+		url := "ws" + strings.TrimPrefix(server.URL, "http")
+		conn, _, err := gws.DefaultDialer.Dial(url, header)
+		if conn != nil {
+			conn.Close()
+		}
+		if (err == nil) != v.ok {
+			t.Errorf("allowed %v, origin %q: connected %v", v.allowed, v.origin, err == nil)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package leader elects one of the replicas sharing the database to run the writers, with a lease stored
+// in the database which the leader renews and the others take over once it expired
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/metrics"
+	"github.com/siovanus/wingServer/supervisor"
+)
+
+// Leases is implemented by store.Client
+type Leases interface {
+	AcquireLease(name string, holder string, duration time.Duration) (bool, error)
+	ReleaseLease(name string, holder string) error
+}
+
+// Elector campaigns for the lease name as id. The lease is renewed every third of its duration and the
+// leader steps down once it could not renew it before half of the lease is left of its local deadline, which
+// is never later than the expiry seen by the database since it is counted from before the renewal was sent.
+// The other half is left to lead to drain its writes, see Drain
+type Elector struct {
+	leases Leases
+	name   string
+	id     string
+	lease  time.Duration
+	leader int32
+	now    func() time.Time
+}
+
+func NewElector(leases Leases, name string, id string, lease time.Duration) *Elector {
+	return &Elector{leases: leases, name: name, id: id, lease: lease, now: time.Now}
+}
+
+// DefaultId identifies the replica by host name and pid
+func DefaultId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func (this *Elector) Id() string {
+	return this.id
+}
+
+// Drain is the time lead has to return once its context is cancelled, for its writes to end before the
+// lease can expire
+func (this *Elector) Drain() time.Duration {
+	return this.lease / 2
+}
+
+func (this *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&this.leader) == 1
+}
+
+// Run campaigns until ctx is done. While this replica leads, lead runs with a context cancelled when the
+// lease is lost or about to expire, and the lease is only given up or campaigned for again once lead
+// returned. lead has to return within Drain
+func (this *Elector) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	interval := this.lease / 3
+	for {
+		start := this.now()
+		acquired, err := this.leases.AcquireLease(this.name, this.id, this.lease)
+		if err != nil {
+			log.Errorf("Elector.Run, this.leases.AcquireLease error: %s", err)
+		}
+		if acquired {
+			this.leadUntilLost(ctx, lead, start.Add(this.lease), interval)
+			if ctx.Err() != nil {
+				this.release()
+				return nil
+			}
+		}
+		if !supervisor.Sleep(ctx, interval) {
+			return nil
+		}
+	}
+}
+
+func (this *Elector) leadUntilLost(ctx context.Context, lead func(ctx context.Context), deadline time.Time,
+	interval time.Duration) {
+	log.Infof("leader: %s elected for %s", this.id, this.name)
+	this.setLeader(true)
+	defer this.setLeader(false)
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lead(leadCtx)
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	for {
+		// stepping down at half the lease leaves lead the other half to drain
+		stepDown := deadline.Add(-this.Drain())
+		wait := interval
+		if left := stepDown.Sub(this.now()); left < wait {
+			wait = left
+		}
+		if !supervisor.Sleep(ctx, wait) {
+			return
+		}
+		if !this.now().Before(stepDown) {
+			log.Warnf("leader: %s could not renew the lease of %s in time, stepping down", this.id, this.name)
+			return
+		}
+		start := this.now()
+		renewed, err := this.leases.AcquireLease(this.name, this.id, this.lease)
+		if err != nil {
+			log.Errorf("Elector.leadUntilLost, this.leases.AcquireLease error: %s", err)
+			continue
+		}
+		if !renewed {
+			log.Warnf("leader: %s lost the lease of %s, stepping down", this.id, this.name)
+			return
+		}
+		deadline = start.Add(this.lease)
+	}
+}
+
+func (this *Elector) setLeader(leader bool) {
+	var v int32
+	if leader {
+		v = 1
+	}
+	atomic.StoreInt32(&this.leader, v)
+	metrics.Leader.Set(float64(v))
+}
+
+func (this *Elector) release() {
+	err := this.leases.ReleaseLease(this.name, this.id)
+	if err != nil {
+		log.Errorf("Elector.release, this.leases.ReleaseLease error: %s", err)
+		return
+	}
+	log.Infof("leader: %s released the lease of %s", this.id, this.name)
+}
@@ -0,0 +1,141 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLeases struct {
+	lock     sync.Mutex
+	holder   string
+	released bool
+	down     bool
+}
+
+func (this *fakeLeases) AcquireLease(name string, holder string, duration time.Duration) (bool, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.down {
+		return false, errors.New("connection refused")
+	}
+	if this.holder == "" {
+		this.holder = holder
+	}
+	return this.holder == holder, nil
+}
+
+func (this *fakeLeases) ReleaseLease(name string, holder string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.holder == holder {
+		this.holder = ""
+		this.released = true
+	}
+	return nil
+}
+
+func (this *fakeLeases) setDown(down bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.down = down
+}
+
+func (this *fakeLeases) steal(holder string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.holder = holder
+}
+
+func TestElector(t *testing.T) {
+	leases := &fakeLeases{}
+	elector := NewElector(leases, "writers", "a", 30*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	led := make(chan struct{}, 10)
+	stopped := make(chan struct{}, 10)
+	go func() {
+		done <- elector.Run(ctx, func(ctx context.Context) {
+			led <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+		})
+	}()
+
+	select {
+	case <-led:
+	case <-time.After(time.Second):
+		t.Fatalf("replica was not elected")
+	}
+	if !elector.IsLeader() {
+		t.Errorf("replica should lead")
+	}
+
+	// another replica took the lease over, this one steps down at the next renewal
+	leases.steal("b")
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("replica did not step down")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if elector.IsLeader() {
+		t.Errorf("replica should not lead")
+	}
+
+	// the lease is given up, this replica campaigns again
+	leases.ReleaseLease("writers", "b")
+	select {
+	case <-led:
+	case <-time.After(time.Second):
+		t.Fatalf("replica was not elected again")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("elector did not stop")
+	}
+	if leases.holder != "" || elector.IsLeader() {
+		t.Errorf("lease should be released on stop, holder %q", leases.holder)
+	}
+}
+
+func TestElectorStepsDownBeforeExpiry(t *testing.T) {
+	leases := &fakeLeases{}
+	lease := 300 * time.Millisecond
+	elector := NewElector(leases, "writers", "a", lease)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	elected := make(chan time.Time, 10)
+	stopped := make(chan time.Time, 10)
+	go elector.Run(ctx, func(ctx context.Context) {
+		elected <- time.Now()
+		// the database is down, the lease cannot be renewed
+		leases.setDown(true)
+		<-ctx.Done()
+		stopped <- time.Now()
+	})
+
+	var start time.Time
+	select {
+	case start = <-elected:
+	case <-time.After(time.Second):
+		t.Fatalf("replica was not elected")
+	}
+	select {
+	case end := <-stopped:
+		// the lease acquired before start expires at start + lease at the latest
+		if left := start.Add(lease).Sub(end); left < elector.Drain()/2 {
+			t.Errorf("stepped down %s before the lease expiry, expected about %s", left, elector.Drain())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("replica did not step down")
+	}
+}
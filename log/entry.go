@@ -0,0 +1,227 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+
+	FIELD_TIME       = "time"
+	FIELD_LEVEL      = "level"
+	FIELD_COMPONENT  = "component"
+	FIELD_ACTION     = "action"
+	FIELD_MESSAGE    = "msg"
+	FIELD_REQUEST_ID = "request_id"
+	FIELD_ACCOUNT    = "account"
+	FIELD_HEIGHT     = "height"
+	FIELD_ERROR      = "error"
+)
+
+var (
+	jsonLevels = map[int]string{
+		TraceLog: "trace",
+		DebugLog: "debug",
+		InfoLog:  "info",
+		WarnLog:  "warn",
+		ErrorLog: "error",
+		FatalLog: "fatal",
+	}
+	// messages of this repo start with the function logging them, e.g. "TrackEvent, this.PriceFeed error: ..."
+	actionPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*), `)
+	// the json lines name the package of the caller as component, skipping the frames of this package
+	logPackage = strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(InitLog).Pointer()).Name(), ".InitLog")
+
+	jsonFormat bool
+)
+
+// SetFormat switches the logs between the colored text lines and json lines, for log aggregators
+func SetFormat(format string) error {
+	switch format {
+	case FORMAT_TEXT, "":
+		jsonFormat = false
+	case FORMAT_JSON:
+		jsonFormat = true
+	default:
+		return fmt.Errorf("unknown log format %s, expected %s or %s", format, FORMAT_TEXT, FORMAT_JSON)
+	}
+	Log.setFormat(jsonFormat)
+	return nil
+}
+
+// Fields are added to a log line, as key=value pairs in text and as properties in json
+type Fields map[string]interface{}
+
+// Entry logs with fields, e.g. the request id of a request or the height of a block
+type Entry struct {
+	fields Fields
+}
+
+func WithFields(fields Fields) *Entry {
+	return (&Entry{}).WithFields(fields)
+}
+
+func (e *Entry) WithFields(fields Fields) *Entry {
+	merged := make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{fields: merged}
+}
+
+func (e *Entry) Debugf(format string, a ...interface{}) {
+	Log.outputFields(DebugLog, e.fields, format, a...)
+}
+
+func (e *Entry) Infof(format string, a ...interface{}) {
+	Log.outputFields(InfoLog, e.fields, format, a...)
+}
+
+func (e *Entry) Warnf(format string, a ...interface{}) {
+	Log.outputFields(WarnLog, e.fields, format, a...)
+}
+
+func (e *Entry) Errorf(format string, a ...interface{}) {
+	Log.outputFields(ErrorLog, e.fields, format, a...)
+}
+
+type entryKey struct{}
+
+// NewContext carries entry with ctx, e.g. to log with the request id in the handlers of a request
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the entry of NewContext, or an entry without fields
+func FromContext(ctx context.Context) *Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*Entry); ok {
+		return entry
+	}
+	return &Entry{}
+}
+
+func (l *Logger) outputFields(level int, fields Fields, format string, a ...interface{}) error {
+	if level < l.level {
+		return nil
+	}
+	if l.json {
+		return l.logger.Output(CALL_DEPTH, formatJson(level, fields, fmt.Sprintf(format, a...), a))
+	}
+	return l.Outputf(level, format+formatFields(fields), a...)
+}
+
+// formatFields appends the fields to a text line, sorted by name
+func formatFields(fields Fields) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return strings.Replace(b.String(), "%", "%%", -1)
+}
+
+// formatJson writes a json line with time, level, component, action and msg first, then the other fields
+// sorted by name. The error field defaults to the first error of args and the action to the function
+// starting msg
+func formatJson(level int, fields Fields, msg string, args []interface{}) string {
+	line := make(Fields, len(fields)+6)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line[FIELD_TIME] = time.Now().UTC().Format(time.RFC3339Nano)
+	line[FIELD_LEVEL] = jsonLevels[level]
+	line[FIELD_MESSAGE] = msg
+	if _, ok := line[FIELD_COMPONENT]; !ok {
+		line[FIELD_COMPONENT] = callerPackage()
+	}
+	if _, ok := line[FIELD_ACTION]; !ok {
+		if match := actionPattern.FindStringSubmatch(msg); match != nil {
+			line[FIELD_ACTION] = match[1]
+		}
+	}
+	if _, ok := line[FIELD_ERROR]; !ok {
+		for _, v := range args {
+			if err, ok := v.(error); ok {
+				line[FIELD_ERROR] = err.Error()
+				break
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(line))
+	for k := range line {
+		switch k {
+		case FIELD_TIME, FIELD_LEVEL, FIELD_COMPONENT, FIELD_ACTION, FIELD_MESSAGE:
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{FIELD_TIME, FIELD_LEVEL, FIELD_COMPONENT, FIELD_ACTION, FIELD_MESSAGE}, keys...)
+	var b strings.Builder
+	b.WriteByte('{')
+	for _, k := range keys {
+		v, ok := line[k]
+		if !ok {
+			continue
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(v))
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// callerPackage names the package of the first caller outside of this package, e.g. service
+func callerPackage() string {
+	pc := make([]uintptr, 16)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, logPackage+".") {
+			name := frame.Function
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name = name[i+1:]
+			}
+			if i := strings.Index(name, "."); i >= 0 {
+				name = name[:i]
+			}
+			return name
+		}
+		if !more {
+			return ""
+		}
+	}
+}
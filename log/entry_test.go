@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func captureLog(t *testing.T, format string, f func()) string {
+	old := Log
+	defer func() {
+		Log = old
+		SetFormat(FORMAT_TEXT)
+	}()
+	var buf bytes.Buffer
+	InitLog(InfoLog)
+	Log.logger.SetOutput(&buf)
+	if err := SetFormat(format); err != nil {
+		t.Fatal(err)
+	}
+	f()
+	return buf.String()
+}
+
+func TestJsonFormat(t *testing.T) {
+	out := captureLog(t, FORMAT_JSON, func() {
+		WithFields(Fields{FIELD_HEIGHT: 100, FIELD_COMPONENT: "service"}).
+			Errorf("TrackEvent, this.store.SaveTrackHeight error: %s", errors.New("connection refused"))
+		Debugf("not logged")
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("unexpected lines %q", out)
+	}
+	if !strings.HasPrefix(lines[0], `{"time":`) {
+		t.Errorf("time should come first: %s", lines[0])
+	}
+	line := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("json.Unmarshal error: %s", err)
+	}
+	expected := map[string]interface{}{
+		FIELD_LEVEL:     "error",
+		FIELD_COMPONENT: "service",
+		FIELD_ACTION:    "TrackEvent",
+		FIELD_HEIGHT:    float64(100),
+		FIELD_ERROR:     "connection refused",
+		FIELD_MESSAGE:   "TrackEvent, this.store.SaveTrackHeight error: connection refused",
+	}
+	for k, v := range expected {
+		if line[k] != v {
+			t.Errorf("%s: expected %v, got %v", k, v, line[k])
+		}
+	}
+}
+
+func TestTextFields(t *testing.T) {
+	out := captureLog(t, FORMAT_TEXT, func() {
+		WithFields(Fields{FIELD_REQUEST_ID: "abc", FIELD_ACCOUNT: "100%"}).Infof("refresh %d", 1)
+	})
+	if !strings.Contains(out, "refresh 1 account=100% request_id=abc\n") {
+		t.Errorf("unexpected line %q", out)
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	if err := SetFormat("xml"); err == nil {
+		t.Errorf("unknown format should fail")
+	}
+}
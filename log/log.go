@@ -12,7 +12,6 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"time"
 )
 
 const (
@@ -96,16 +95,20 @@ func NameLevel(name string) int {
 
 type Logger struct {
 	level   int
+	json    bool
 	logger  *log.Logger
-	logFile *os.File
+	logFile *logFile
 }
 
 func New(out io.Writer, prefix string, flag, level int, file *os.File) *Logger {
-	return &Logger{
-		level:   level,
-		logger:  log.New(out, prefix, flag),
-		logFile: file,
+	logger := &Logger{
+		level:  level,
+		logger: log.New(out, prefix, flag),
 	}
+	if file != nil {
+		logger.logFile = &logFile{path: filepath.Dir(file.Name()) + "/", file: file}
+	}
+	return logger
 }
 
 func (l *Logger) SetDebugLevel(level int) error {
@@ -117,7 +120,19 @@ func (l *Logger) SetDebugLevel(level int) error {
 	return nil
 }
 
+func (l *Logger) setFormat(json bool) {
+	l.json = json
+	if json {
+		l.logger.SetFlags(0)
+	} else {
+		l.logger.SetFlags(log.Ldate | log.Lmicroseconds)
+	}
+}
+
 func (l *Logger) Output(level int, a ...interface{}) error {
+	if level >= l.level && l.json {
+		return l.logger.Output(CALL_DEPTH, formatJson(level, nil, strings.TrimSuffix(fmt.Sprintln(a...), "\n"), a))
+	}
 	if level >= l.level {
 		gid := GetGID()
 		gidStr := strconv.FormatUint(gid, 10)
@@ -131,6 +146,9 @@ func (l *Logger) Output(level int, a ...interface{}) error {
 }
 
 func (l *Logger) Outputf(level int, format string, v ...interface{}) error {
+	if level >= l.level && l.json {
+		return l.logger.Output(CALL_DEPTH, formatJson(level, nil, fmt.Sprintf(format, v...), v))
+	}
 	if level >= l.level {
 		gid := GetGID()
 		v = append([]interface{}{LevelName(level), "GID",
@@ -306,9 +324,7 @@ func FileOpen(path string) (*os.File, error) {
 		return nil, err
 	}
 
-	var currenttime = time.Now().Format("2006-01-02_15.04.05")
-
-	logfile, err := os.OpenFile(path+currenttime+"_LOG.log", os.O_RDWR|os.O_CREATE, 0666)
+	logfile, err := os.OpenFile(path+CURRENT_LOG_FILE, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		return nil, err
 	}
@@ -323,20 +339,20 @@ func Init(a ...interface{}) {
 
 func InitLog(logLevel int, a ...interface{}) {
 	writers := []io.Writer{}
-	var logFile *os.File
-	var err error
+	var current *logFile
 	if len(a) == 0 {
 		writers = append(writers, ioutil.Discard)
 	} else {
 		for _, o := range a {
 			switch o.(type) {
 			case string:
-				logFile, err = FileOpen(o.(string))
+				file, err := FileOpen(o.(string))
 				if err != nil {
 					fmt.Println("error: open log file failed")
 					os.Exit(1)
 				}
-				writers = append(writers, logFile)
+				current = &logFile{path: o.(string), file: file}
+				writers = append(writers, current)
 			case *os.File:
 				writers = append(writers, o.(*os.File))
 			default:
@@ -346,15 +362,19 @@ func InitLog(logLevel int, a ...interface{}) {
 		}
 	}
 	fileAndStdoutWrite := io.MultiWriter(writers...)
-	Log = New(fileAndStdoutWrite, "", log.Ldate|log.Lmicroseconds, logLevel, logFile)
+	Log = &Logger{
+		level:   logLevel,
+		logger:  log.New(fileAndStdoutWrite, "", log.Ldate|log.Lmicroseconds),
+		logFile: current,
+	}
+	Log.setFormat(jsonFormat)
 }
 
 func GetLogFileSize() (int64, error) {
-	f, e := Log.logFile.Stat()
-	if e != nil {
-		return 0, e
+	if Log.logFile == nil {
+		return 0, os.ErrInvalid
 	}
-	return f.Size(), nil
+	return Log.logFile.size()
 }
 
 func GetMaxLogChangeInterval(maxLogSize int64) int64 {
@@ -367,7 +387,7 @@ func GetMaxLogChangeInterval(maxLogSize int64) int64 {
 
 func CheckIfNeedNewFile() bool {
 	logFileSize, err := GetLogFileSize()
-	maxLogFileSize := GetMaxLogChangeInterval(0)
+	maxLogFileSize := GetMaxLogChangeInterval(maxLogSize())
 	if err != nil {
 		return false
 	}
@@ -381,7 +401,7 @@ func CheckIfNeedNewFile() bool {
 func ClosePrintLog() error {
 	var err error
 	if Log.logFile != nil {
-		err = Log.logFile.Close()
+		err = Log.logFile.close()
 	}
 	return err
 }
@@ -0,0 +1,198 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOG_FILE_SUFFIX = "_LOG.log"
+	GZIP_SUFFIX     = ".gz"
+	// CURRENT_LOG_FILE is the file in use, size rotations rename it after the time of the rotation and an
+	// external logrotate renames it as configured before sending SIGHUP
+	CURRENT_LOG_FILE = "wing" + LOG_FILE_SUFFIX
+	// rotated files written within this period may still be written by the process, they are compressed
+	// by the next prune
+	COMPRESS_DELAY = time.Minute
+)
+
+var (
+	rotation struct {
+		sync.RWMutex
+		maxSize  int64
+		maxAge   time.Duration
+		maxFiles int
+		compress bool
+	}
+	// one prune at a time
+	pruneLock sync.Mutex
+)
+
+// SetRotation sets the size in MB above which a new file is opened, zero keeps the default, and the
+// retention of the rotated files: the files older than maxAge and above maxFiles are removed, zero keeps
+// them, and the rotated files are gzipped when compress is set. Leave compress to logrotate when it rotates
+// the files
+func SetRotation(maxSize uint64, maxAge time.Duration, maxFiles int, compress bool) {
+	rotation.Lock()
+	defer rotation.Unlock()
+	rotation.maxSize = int64(maxSize)
+	rotation.maxAge = maxAge
+	rotation.maxFiles = maxFiles
+	rotation.compress = compress
+}
+
+func maxLogSize() int64 {
+	rotation.RLock()
+	defer rotation.RUnlock()
+	return rotation.maxSize
+}
+
+// logFile writes to the current log file of path. Rotate and Reopen swap the file under the lock, so that
+// no line is written to a closed file
+type logFile struct {
+	lock sync.Mutex
+	path string
+	file *os.File
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Write(p)
+}
+
+func (f *logFile) size() (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// reopen opens the current file of path again, after renaming the file in use to rotated when set
+func (f *logFile) reopen(rotated string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if rotated != "" {
+		if err := os.Rename(f.file.Name(), rotated); err != nil {
+			return err
+		}
+	}
+	file, err := FileOpen(f.path)
+	if err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file = file
+	return nil
+}
+
+func (f *logFile) close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
+
+// Rotate renames the current file after the current time and opens a new one
+func Rotate() error {
+	if Log.logFile == nil {
+		return nil
+	}
+	rotated := Log.logFile.path + time.Now().Format("2006-01-02_15.04.05") + LOG_FILE_SUFFIX
+	return Log.logFile.reopen(rotated)
+}
+
+// Reopen opens the current file again once an external logrotate renamed it
+func Reopen() error {
+	if Log.logFile == nil {
+		return nil
+	}
+	return Log.logFile.reopen("")
+}
+
+// PruneFiles compresses the rotated files of path and removes those beyond the retention, newest first by
+// modification time. Rotated files are those of Rotate and those renamed by logrotate like
+// wing_LOG.log.1 or wing_LOG.log-20201010, the current file is left alone
+func PruneFiles(path string) error {
+	pruneLock.Lock()
+	defer pruneLock.Unlock()
+	rotation.RLock()
+	maxAge, maxFiles, compress := rotation.maxAge, rotation.maxFiles, rotation.compress
+	rotation.RUnlock()
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var rotated []os.FileInfo
+	for _, v := range infos {
+		name := v.Name()
+		if v.IsDir() || name == CURRENT_LOG_FILE || !strings.Contains(name, LOG_FILE_SUFFIX) {
+			continue
+		}
+		if compress && !strings.HasSuffix(name, GZIP_SUFFIX) && now.Sub(v.ModTime()) > COMPRESS_DELAY {
+			if err := compressFile(filepath.Join(path, name)); err != nil {
+				return fmt.Errorf("compressFile %s error: %s", name, err)
+			}
+			v, err = os.Stat(filepath.Join(path, name+GZIP_SUFFIX))
+			if err != nil {
+				return err
+			}
+		}
+		rotated = append(rotated, v)
+	}
+
+	sort.Slice(rotated, func(i, j int) bool { return rotated[i].ModTime().After(rotated[j].ModTime()) })
+	for i, v := range rotated {
+		expired := maxAge != 0 && now.Sub(v.ModTime()) > maxAge
+		if expired || (maxFiles != 0 && i >= maxFiles) {
+			if err := os.Remove(filepath.Join(path, v.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressFile replaces name with name.gz, keeping its modification time for the retention by age
+func compressFile(name string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+GZIP_SUFFIX, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name + GZIP_SUFFIX)
+		return err
+	}
+	if err := os.Chtimes(name+GZIP_SUFFIX, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
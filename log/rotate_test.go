@@ -0,0 +1,129 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPruneFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	files := map[string]time.Duration{
+		"2020-10-01_00.00.00" + LOG_FILE_SUFFIX:               10 * 24 * time.Hour,
+		"2020-10-08_00.00.00" + LOG_FILE_SUFFIX + GZIP_SUFFIX: 3 * 24 * time.Hour,
+		"2020-10-09_00.00.00" + LOG_FILE_SUFFIX:               2 * 24 * time.Hour,
+		CURRENT_LOG_FILE + ".2":                               25 * time.Hour,
+		CURRENT_LOG_FILE + ".1":                               time.Hour,
+		// just renamed by logrotate, the process may still write it
+		CURRENT_LOG_FILE + "-20201011": time.Second,
+		CURRENT_LOG_FILE:               0,
+		"notes.txt":                    30 * 24 * time.Hour,
+	}
+	for name, age := range files {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte("log line\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, now.Add(-age), now.Add(-age))
+	}
+
+	defer SetRotation(0, 0, 0, false)
+	SetRotation(0, 7*24*time.Hour, 3, true)
+	if err := PruneFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, _ := ioutil.ReadDir(dir)
+	var names []string
+	for _, v := range infos {
+		names = append(names, v.Name())
+	}
+	sort.Strings(names)
+	expected := []string{
+		"notes.txt",
+		CURRENT_LOG_FILE,
+		CURRENT_LOG_FILE + "-20201011",
+		CURRENT_LOG_FILE + ".1" + GZIP_SUFFIX,
+		CURRENT_LOG_FILE + ".2" + GZIP_SUFFIX,
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("unexpected files %v", names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("unexpected files %v", names)
+		}
+	}
+
+	file, err := os.Open(filepath.Join(dir, expected[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(zr)
+	if string(data) != "log line\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestRotateWhileLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := Log
+	defer func() { Log = old }()
+	InitLog(InfoLog, dir+"/")
+	defer ClosePrintLog()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Infof("line %d", j)
+			}
+		}()
+	}
+	// logrotate renames the file and sends SIGHUP, then the size rotation renames it after the time
+	if err := os.Rename(filepath.Join(dir, CURRENT_LOG_FILE), filepath.Join(dir, CURRENT_LOG_FILE+".1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	lines := 0
+	infos, _ := ioutil.ReadDir(dir)
+	for _, v := range infos {
+		data, err := ioutil.ReadFile(filepath.Join(dir, v.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines += strings.Count(string(data), "line ")
+	}
+	if len(infos) != 3 || lines != 400 {
+		t.Errorf("expected 400 lines in 3 files, got %d lines in %d files", lines, len(infos))
+	}
+}
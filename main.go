@@ -19,34 +19,63 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/siovanus/wingServer/store"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
 
 	sdk "github.com/ontio/ontology-go-sdk"
 	"github.com/ontio/ontology/common"
 	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/admin"
+	hcommon "github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/graphql"
+	"github.com/siovanus/wingServer/http/health"
+	"github.com/siovanus/wingServer/http/openapi"
 	"github.com/siovanus/wingServer/http/restful"
 	"github.com/siovanus/wingServer/http/service"
+	"github.com/siovanus/wingServer/http/sse"
+	"github.com/siovanus/wingServer/http/websocket"
+	"github.com/siovanus/wingServer/leader"
 	"github.com/siovanus/wingServer/log"
 	"github.com/siovanus/wingServer/manager/flashpool"
 	"github.com/siovanus/wingServer/manager/governance"
+	"github.com/siovanus/wingServer/manager/webhook"
+	"github.com/siovanus/wingServer/manager/wing"
+	"github.com/siovanus/wingServer/metrics"
+	"github.com/siovanus/wingServer/relay"
+	"github.com/siovanus/wingServer/scheduler"
+	"github.com/siovanus/wingServer/supervisor"
 	"github.com/urfave/cli"
 )
 
 var ConfigPath = ""
 
+// LEADER_LEASE is the lease of the replica running the writers
+const LEADER_LEASE = "writers"
+
+// Version is set at build time with -ldflags "-X main.Version=<version>"
+var Version = "dev"
+
 func setupApp() *cli.App {
 	app := cli.NewApp()
 	app.Usage = "wing rest server"
+	app.Version = Version
 	app.Action = startServer
 	app.Copyright = "Copyright in 2018 The Ontology Authors"
 	app.Flags = []cli.Flag{
 		config.LogLevelFlag,
+		config.LogFormatFlag,
 		config.ConfigPathFlag,
+		config.ModeFlag,
+	}
+	app.Commands = []cli.Command{
+		apiKeyCommand,
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -65,17 +94,33 @@ func main() {
 func startServer(ctx *cli.Context) {
 	logLevel := ctx.GlobalInt(config.GetFlagName(config.LogLevelFlag))
 	log.InitLog(logLevel, log.PATH, log.Stdout)
+	if err := log.SetFormat(ctx.GlobalString(config.GetFlagName(config.LogFormatFlag))); err != nil {
+		log.Errorf("log.SetFormat error: %s", err)
+		return
+	}
 
 	configPath := ctx.GlobalString(config.GetFlagName(config.ConfigPathFlag))
 	if configPath != "" {
 		ConfigPath = configPath
 	}
+	mode := ctx.GlobalString(config.GetFlagName(config.ModeFlag))
+	if mode != config.MODE_ALL && mode != config.MODE_API && mode != config.MODE_INDEXER {
+		log.Errorf("unknown mode %s, expected %s, %s or %s", mode, config.MODE_ALL, config.MODE_API,
+			config.MODE_INDEXER)
+		return
+	}
 	servConfig, err := config.NewConfig(ConfigPath)
 	if err != nil {
 		log.Errorf("parse config failed, err: %s", err)
 		return
 	}
+	if servConfig.Log != nil {
+		log.SetRotation(servConfig.Log.MaxSize, time.Duration(servConfig.Log.MaxAge)*24*time.Hour,
+			int(servConfig.Log.MaxFiles), servConfig.Log.Compress)
+	}
 
+	// the components read the config through the holder, reloads publish a new one
+	configHolder := config.NewHolder(servConfig)
 	store, err := store.ConnectToDb(servConfig.DatabaseURL)
 	if err != nil {
 		log.Errorf("store.ConnectToDb error: %s", err)
@@ -84,7 +129,8 @@ func startServer(ctx *cli.Context) {
 	defer store.Close()
 
 	sdk := sdk.NewOntologySdk()
-	sdk.NewRpcClient().SetAddress(servConfig.JsonRpcAddress)
+	// every rpc call of the sdk is timed in the rpc metrics
+	sdk.NewRpcClient().SetAddress(servConfig.JsonRpcAddress).SetHttpClient(metrics.NewRpcHttpClient())
 
 	govAddress, err := common.AddressFromHexString(servConfig.GovernanceAddress)
 	if err != nil {
@@ -101,45 +147,279 @@ func startServer(ctx *cli.Context) {
 		log.Errorf("oracleAddress common.AddressFromHexString error: %s", err)
 		return
 	}
-	govMgr := governance.NewGovernanceManager(govAddress, servConfig.WingAddress, sdk, servConfig)
+	govMgr := governance.NewGovernanceManager(govAddress, servConfig.WingAddress, sdk, store, configHolder)
 	if govMgr == nil {
 		log.Errorf("governance manager is nil")
 		return
 	}
-	fpMgr := flashpool.NewFlashPoolManager(fpAddress, oracleAddress, sdk, store, servConfig)
+	fpMgr := flashpool.NewFlashPoolManager(fpAddress, oracleAddress, sdk, store, configHolder)
 	if fpMgr == nil {
 		log.Errorf("flashpool manager is nil")
 		return
 	}
-	log.Infof("init svr success")
-	serv := service.NewService(sdk, govMgr, fpMgr, store, servConfig)
-	serv.AddListeningAddressList()
-	restServer := restful.InitRestServer(serv, servConfig.Port)
+	wingMgr := wing.NewWingManager(servConfig.WingAddress, sdk, store, configHolder)
+	if wingMgr == nil {
+		log.Errorf("wing manager is nil")
+		return
+	}
+	log.Infof("init svr success, mode %s", mode)
+	serv := service.NewService(sdk, govMgr, fpMgr, wingMgr, store, configHolder)
+
+	// every mode lists the markets, the indexer listens to them and the api serves their prices
+	if err := serv.AddListeningAddressList(); err != nil {
+		log.Errorf("serv.AddListeningAddressList error: %s", err)
+		return
+	}
+	// the indexer notifies the streams and the cache of its own replica, the relay passes its notifications
+	// on to the other replicas through the database. There are other replicas with leader election and with
+	// the indexer and api modes, a single process in all mode does without. api replicas only serve reads,
+	// they neither listen to the contracts nor track the markets, which they refresh once the indexer stored
+	// a market they do not know
+	replicated := mode != config.MODE_ALL || servConfig.Leader != nil
+	notifications := relay.NewRelay(store, serv.Indexing)
+	if mode != config.MODE_API {
+		if replicated {
+			serv.AddNotifier(notifications)
+		}
+	} else {
+		notifications.AddNotifier(serv.MarketWatcher())
+	}
+	var restServer restful.ApiServer
+	var hub *websocket.Hub
+	var broker *sse.Broker
+	if mode != config.MODE_INDEXER {
+		ttls := make(map[string]time.Duration)
+		if servConfig.Cache != nil {
+			for k, v := range servConfig.Cache.Routes {
+				ttls[k] = time.Duration(v) * time.Second
+			}
+		}
+		cache := restful.NewResponseCache(ttls)
+		serv.AddNotifier(cache)
+		notifications.AddNotifier(cache)
+		restServer = restful.InitRestServer(serv, servConfig.Port, cache)
+		if servConfig.RateLimit != nil {
+			limiter := restful.NewRateLimiter(servConfig.RateLimit, store)
+			limiter.SetCost(hcommon.GRAPHQL, graphql.RequestCost)
+			restServer.Use(limiter.Middleware)
+		}
+		restServer.Handle(http.MethodGet, hcommon.OPENAPI, openapi.Handler)
+		var allowedOrigins []string
+		if servConfig.Websocket != nil {
+			allowedOrigins = servConfig.Websocket.AllowedOrigins
+		}
+		hub = websocket.NewHub(allowedOrigins)
+		serv.AddNotifier(hub)
+		notifications.AddNotifier(hub)
+		restServer.Handle(http.MethodGet, hcommon.WEBSOCKET, hub.ServeHTTP)
+		broker = sse.NewBroker(store)
+		serv.AddNotifier(broker)
+		notifications.AddNotifier(broker)
+		restServer.Handle(http.MethodGet, hcommon.EVENTSTREAM, broker.ServeHTTP)
+		graph := graphql.NewHandler(serv)
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodOptions} {
+			restServer.Handle(method, hcommon.GRAPHQL, graph.ServeHTTP)
+		}
+	} else {
+		// the indexer still answers the probes on its port
+		restServer = restful.InitBareServer(servConfig.Port)
+	}
+	probes := health.NewHandler(serv, configHolder, Version)
+	restServer.Handle(http.MethodGet, hcommon.HEALTHZ, probes.Healthz)
+	restServer.Handle(http.MethodGet, hcommon.READYZ, probes.Readyz)
 
-	go serv.SnapshotDaily()
-	go serv.SnapshotMinute()
-	go serv.TrackEvent()
+	// the webhooks, the scheduler and the admin endpoints writing the database are left out of api replicas
+	var webhookMgr *webhook.WebhookManager
+	var sched *scheduler.Scheduler
+	if mode != config.MODE_API {
+		webhookMgr = webhook.NewWebhookManager(fpMgr, store, configHolder)
+		if webhookMgr == nil {
+			log.Errorf("webhook manager is nil")
+			return
+		}
+		serv.AddNotifier(webhookMgr)
+		sched = scheduler.NewScheduler(servConfig.Scheduler, store)
+		if err := serv.RegisterJobs(sched); err != nil {
+			log.Errorf("serv.RegisterJobs error: %s", err)
+			return
+		}
+		if err := sched.RegisterHistoryPruning(); err != nil {
+			log.Errorf("sched.RegisterHistoryPruning error: %s", err)
+			return
+		}
+		if replicated {
+			if err := sched.Register(relay.JOB_PRUNE, relay.JOB_PRUNE_SPEC, notifications.Prune); err != nil {
+				log.Errorf("sched.Register error: %s", err)
+				return
+			}
+		}
+	}
+	var adminServer *admin.Server
+	if servConfig.AdminToken != "" && servConfig.AdminPort != 0 {
+		if mode == config.MODE_API {
+			adminServer = admin.NewReadOnlyServer(servConfig.AdminPort, servConfig.AdminToken)
+		} else {
+			adminServer = admin.NewServer(serv, servConfig.AdminPort, servConfig.AdminToken, ConfigPath)
+			for _, v := range webhookMgr.AdminRoutes() {
+				adminServer.Handle(v.Method, v.Path, v.Handler)
+			}
+			adminServer.HandleJobs(sched)
+		}
+		adminServer.Handle(http.MethodGet, hcommon.ADMINSTATUS, probes.Status)
+		adminServer.Handle(http.MethodGet, hcommon.METRICS, metrics.Handler)
+	} else {
+		log.Warnf("admin_token or admin_port is not configured, admin api disabled")
+	}
+	var metricsServer restful.ApiServer
+	if servConfig.MetricsPort != 0 {
+		metricsServer = restful.InitBareServer(servConfig.MetricsPort)
+		metricsServer.Handle(http.MethodGet, hcommon.METRICS, metrics.Handler)
+	}
+
+	shutdownTimeout := time.Duration(servConfig.ShutdownTimeout) * time.Second
+	if shutdownTimeout == 0 {
+		shutdownTimeout = config.DEFAULT_SHUTDOWN_TIMEOUT * time.Second
+	}
+	// the indexer and the scheduler write the database, a single replica runs them. They drain within drain
+	// once ctx is done, a leader has to be done before its lease can expire
+	drain := shutdownTimeout
+	writers := func(ctx context.Context) {
+		serv.SetIndexing(true)
+		defer serv.SetIndexing(false)
+		writerJobs := supervisor.NewSupervisor(ctx)
+		writerJobs.Go("track_event", serv.TrackEvent)
+		writerJobs.Go("scheduler", sched.Run)
+		<-ctx.Done()
+		deadline := time.Now().Add(drain)
+		if !writerJobs.Stop(drain) {
+			log.Warnf("writers did not stop within %s", drain)
+		}
+		if !serv.Wait(time.Until(deadline)) {
+			log.Warnf("background writes did not end within %s", drain)
+		}
+	}
+
+	jobs := supervisor.NewSupervisor(context.Background())
+	if mode == config.MODE_API {
+		log.Infof("api mode, the indexer and the scheduler are left to other replicas")
+	} else if servConfig.Leader != nil {
+		id := servConfig.Leader.Id
+		if id == "" {
+			id = leader.DefaultId()
+		}
+		lease := time.Duration(servConfig.Leader.Lease) * time.Second
+		if lease == 0 {
+			lease = config.DEFAULT_LEADER_LEASE * time.Second
+		}
+		elector := leader.NewElector(store, LEADER_LEASE, id, lease)
+		if elector.Drain() < drain {
+			drain = elector.Drain()
+		}
+		// a former leader which still runs cannot move the track height once the lease is taken over
+		serv.SetFence(LEADER_LEASE, id)
+		jobs.Go("leader", func(ctx context.Context) error {
+			return elector.Run(ctx, writers)
+		})
+	} else {
+		metrics.Leader.Set(1)
+		jobs.Go("writers", func(ctx context.Context) error {
+			writers(ctx)
+			return nil
+		})
+	}
+	if mode != config.MODE_INDEXER && replicated {
+		jobs.Go("relay", notifications.Run)
+	}
+	if mode != config.MODE_API {
+		jobs.Go("track_markets", serv.TrackMarkets)
+	}
 	go restServer.Start()
-	go checkLogFile(logLevel)
+	if adminServer != nil {
+		go adminServer.Start()
+	}
+	if metricsServer != nil {
+		go metricsServer.Start()
+	}
+	jobs.Go("check_log_file", checkLogFile)
 
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
 	log.Info("Shutting down...")
+	deadline := time.Now().Add(shutdownTimeout)
+	shutdownCtx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	// streams never end by themselves, close them so that the servers can drain
+	if hub != nil {
+		hub.Close()
+		broker.Close()
+	}
+	if err := restServer.Shutdown(shutdownCtx); err != nil {
+		log.Warnf("restServer.Shutdown error: %s", err)
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			log.Warnf("adminServer.Shutdown error: %s", err)
+		}
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Warnf("metricsServer.Shutdown error: %s", err)
+		}
+	}
+	if !jobs.Stop(time.Until(deadline)) {
+		log.Warnf("jobs did not stop within %s", shutdownTimeout)
+	}
+	if !serv.Wait(time.Until(deadline)) {
+		log.Warnf("background writes did not end within %s", shutdownTimeout)
+	}
+	if webhookMgr != nil {
+		webhookMgr.Close()
+	}
 	serv.Close()
 	os.Exit(0)
 }
 
-func checkLogFile(logLevel int) {
+// checkLogFile rotates the log file once it is too large and prunes the rotated files at start, after each
+// rotation and hourly. On SIGHUP it reopens the log file, which an external logrotate renamed. It runs until
+// ctx is done
+func checkLogFile(ctx context.Context) error {
 	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	pruneFiles()
 	for {
 		select {
+		case <-ctx.Done():
+			return nil
 		case <-ticker.C:
 			isNeedNewFile := log.CheckIfNeedNewFile()
 			if isNeedNewFile {
-				log.ClosePrintLog()
-				log.InitLog(logLevel, log.PATH, log.Stdout)
+				if err := log.Rotate(); err != nil {
+					log.Errorf("log.Rotate error: %s", err)
+					continue
+				}
+				pruneFiles()
+			}
+		case <-prune.C:
+			pruneFiles()
+		case <-hup:
+			if err := log.Reopen(); err != nil {
+				log.Errorf("log.Reopen error: %s", err)
+				continue
 			}
+			log.Infof("SIGHUP received, log file reopened")
 		}
 	}
 }
+
+func pruneFiles() {
+	if err := log.PruneFiles(log.PATH); err != nil {
+		log.Errorf("log.PruneFiles error: %s", err)
+	}
+}
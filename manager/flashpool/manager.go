@@ -16,27 +16,30 @@ import (
 )
 
 const (
-	BlockPerYear = 60 * 60 * 24 * 365 * 2 / 5
+	BlockPerYear         = 60 * 60 * 24 * 365 * 2 / 5
+	MaxClaimHistoryLimit = 100
 )
 
 var GAP = new(big.Int).SetUint64(198684465873214)
 
 type FlashPoolManager struct {
-	cfg             *config.Config
+	cfg             *config.Holder
 	contractAddress ocommon.Address
 	oracleAddress   ocommon.Address
 	sdk             *sdk.OntologySdk
 	store           *store.Client
+	registry        *marketRegistry
 }
 
 func NewFlashPoolManager(contractAddress, oracleAddress ocommon.Address, sdk *sdk.OntologySdk,
-	store *store.Client, cfg *config.Config) *FlashPoolManager {
+	store *store.Client, cfg *config.Holder) *FlashPoolManager {
 	manager := &FlashPoolManager{
 		cfg:             cfg,
 		contractAddress: contractAddress,
 		oracleAddress:   oracleAddress,
 		sdk:             sdk,
 		store:           store,
+		registry:        newMarketRegistry(),
 	}
 
 	return manager
@@ -47,28 +50,30 @@ func (this *FlashPoolManager) AssetPrice(asset string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("AssetPrice, this.assetPrice error: %s", err)
 	}
-	return utils.ToStringByPrecise(price, this.cfg.TokenDecimal["oracle"]), nil
+	return utils.ToStringByPrecise(price, this.cfg.Get().TokenDecimal["oracle"]), nil
 }
 
 func (this *FlashPoolManager) AssetStoredPrice(asset string) (*big.Int, error) {
+	cfg := this.cfg.Get()
 	if asset == "USDT" {
-		return new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["oracle"])))), nil
+		return new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["oracle"])))), nil
 	}
 	price, err := this.store.LoadPrice(asset)
 	if err != nil {
 		return nil, fmt.Errorf("AssetStoredPrice, this.store.LoadPrice error: %s", err)
 	}
-	return utils.ToIntByPrecise(price.Price, this.cfg.TokenDecimal["oracle"]), nil
+	return utils.ToIntByPrecise(price.Price, cfg.TokenDecimal["oracle"]), nil
 }
 
 func (this *FlashPoolManager) FlashPoolMarketDistribution() (*common.FlashPoolMarketDistribution, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("FlashPoolMarketDistribution, this.GetAllMarkets error: %s", err)
 	}
 	flashPoolMarketDistribution := make([]*common.Distribution, 0)
 	for _, address := range allMarkets {
-		market, err := this.store.LoadFlashMarket(this.cfg.AssetMap[address.ToHexString()])
+		market, err := this.store.LoadFlashMarket(cfg.AssetMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolMarketDistribution, this.store.LoadFlashMarket error: %s", err)
 		}
@@ -82,15 +87,15 @@ func (this *FlashPoolManager) FlashPoolMarketDistribution() (*common.FlashPoolMa
 		}
 		distributedDay := (uint64(time.Now().Unix()) - governance.GenesisTime) / governance.DaySecond
 		distribution := &common.Distribution{
-			Icon: this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-			Name: this.cfg.AssetMap[address.ToHexString()],
+			Icon: cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+			Name: cfg.AssetMap[address.ToHexString()],
 			// totalDistribution / distributedDay
 			PerDay: utils.ToStringByPrecise(new(big.Int).Div(totalDistribution,
-				new(big.Int).SetUint64(distributedDay)), this.cfg.TokenDecimal["WING"]),
+				new(big.Int).SetUint64(distributedDay)), cfg.TokenDecimal["WING"]),
 			SupplyAmount:    supplyAmount,
 			BorrowAmount:    borrowAmount,
 			InsuranceAmount: insuranceAmount,
-			Total:           utils.ToStringByPrecise(totalDistribution, this.cfg.TokenDecimal["WING"]),
+			Total:           utils.ToStringByPrecise(totalDistribution, cfg.TokenDecimal["WING"]),
 		}
 		flashPoolMarketDistribution = append(flashPoolMarketDistribution, distribution)
 	}
@@ -98,6 +103,7 @@ func (this *FlashPoolManager) FlashPoolMarketDistribution() (*common.FlashPoolMa
 }
 
 func (this *FlashPoolManager) PoolDistribution() (*common.Distribution, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("PoolDistribution, this.GetAllMarkets error: %s", err)
@@ -108,7 +114,7 @@ func (this *FlashPoolManager) PoolDistribution() (*common.Distribution, error) {
 	i := new(big.Int).SetUint64(0)
 	d := new(big.Int).SetUint64(0)
 	for _, address := range allMarkets {
-		market, err := this.store.LoadFlashMarket(this.cfg.AssetMap[address.ToHexString()])
+		market, err := this.store.LoadFlashMarket(cfg.AssetMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("PoolDistribution, this.store.LoadFlashMarket error: %s", err)
 		}
@@ -122,25 +128,26 @@ func (this *FlashPoolManager) PoolDistribution() (*common.Distribution, error) {
 		}
 
 		// supplyAmount * price
-		s = new(big.Int).Add(s, utils.ToIntByPrecise(supplyAmount, this.cfg.TokenDecimal["pUSDT"]))
+		s = new(big.Int).Add(s, utils.ToIntByPrecise(supplyAmount, cfg.TokenDecimal["pUSDT"]))
 		// borrowAmount * price
-		b = new(big.Int).Add(b, utils.ToIntByPrecise(borrowAmount, this.cfg.TokenDecimal["pUSDT"]))
+		b = new(big.Int).Add(b, utils.ToIntByPrecise(borrowAmount, cfg.TokenDecimal["pUSDT"]))
 		// insuranceAmount * price
-		i = new(big.Int).Add(i, utils.ToIntByPrecise(insuranceAmount, this.cfg.TokenDecimal["pUSDT"]))
+		i = new(big.Int).Add(i, utils.ToIntByPrecise(insuranceAmount, cfg.TokenDecimal["pUSDT"]))
 		d = new(big.Int).Add(d, totalDistribution)
 	}
 	distribution.Name = "Flash"
-	distribution.Icon = this.cfg.IconMap[distribution.Name]
+	distribution.Icon = cfg.IconMap[distribution.Name]
 	distributedDay := new(big.Int).SetUint64((uint64(time.Now().Unix()) - governance.GenesisTime) / governance.DaySecond)
-	distribution.SupplyAmount = utils.ToStringByPrecise(s, this.cfg.TokenDecimal["pUSDT"])
-	distribution.BorrowAmount = utils.ToStringByPrecise(b, this.cfg.TokenDecimal["pUSDT"])
-	distribution.InsuranceAmount = utils.ToStringByPrecise(i, this.cfg.TokenDecimal["pUSDT"])
-	distribution.PerDay = utils.ToStringByPrecise(new(big.Int).Div(d, distributedDay), this.cfg.TokenDecimal["WING"])
-	distribution.Total = utils.ToStringByPrecise(d, this.cfg.TokenDecimal["WING"])
+	distribution.SupplyAmount = utils.ToStringByPrecise(s, cfg.TokenDecimal["pUSDT"])
+	distribution.BorrowAmount = utils.ToStringByPrecise(b, cfg.TokenDecimal["pUSDT"])
+	distribution.InsuranceAmount = utils.ToStringByPrecise(i, cfg.TokenDecimal["pUSDT"])
+	distribution.PerDay = utils.ToStringByPrecise(new(big.Int).Div(d, distributedDay), cfg.TokenDecimal["WING"])
+	distribution.Total = utils.ToStringByPrecise(d, cfg.TokenDecimal["WING"])
 	return distribution, nil
 }
 
 func (this *FlashPoolManager) FlashPoolBanner() (*common.FlashPoolBanner, error) {
+	cfg := this.cfg.Get()
 	gap := uint64(time.Now().Unix()) - governance.GenesisTime
 	length := len(governance.DailyDistibute)
 	epoch := []uint64{0}
@@ -173,19 +180,20 @@ func (this *FlashPoolManager) FlashPoolBanner() (*common.FlashPoolBanner, error)
 	share := new(big.Int).SetUint64(0)
 	if total.Uint64() != 0 {
 		t := new(big.Int).Mul(new(big.Int).Mul(new(big.Int).SetUint64(today),
-			new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["WING"]))))),
-			new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["percentage"])))))
+			new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["WING"]))))),
+			new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["percentage"])))))
 		share = new(big.Int).Div(new(big.Int).Div(t, new(big.Int).SetUint64(100)), total)
 	}
 
 	return &common.FlashPoolBanner{
 		Today: utils.ToStringByPrecise(new(big.Int).SetUint64(today), 2),
-		Share: utils.ToStringByPrecise(share, this.cfg.TokenDecimal["percentage"]),
-		Total: utils.ToStringByPrecise(total, this.cfg.TokenDecimal["WING"]),
+		Share: utils.ToStringByPrecise(share, cfg.TokenDecimal["percentage"]),
+		Total: utils.ToStringByPrecise(total, cfg.TokenDecimal["WING"]),
 	}, nil
 }
 
 func (this *FlashPoolManager) FlashPoolDetail() (*common.FlashPoolDetail, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("FlashPoolDetail, this.GetAllMarkets error: %s", err)
@@ -195,7 +203,7 @@ func (this *FlashPoolManager) FlashPoolDetail() (*common.FlashPoolDetail, error)
 	b := new(big.Int).SetUint64(0)
 	i := new(big.Int).SetUint64(0)
 	for _, address := range allMarkets {
-		market, err := this.store.LoadFlashMarket(this.cfg.AssetMap[address.ToHexString()])
+		market, err := this.store.LoadFlashMarket(cfg.AssetMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolDetail, this.store.LoadFlashMarket error: %s", err)
 		}
@@ -206,22 +214,23 @@ func (this *FlashPoolManager) FlashPoolDetail() (*common.FlashPoolDetail, error)
 		// supplyAmount * price
 		// borrowAmount * price
 		// insuranceAmount * price
-		supplyDollar := utils.ToIntByPrecise(supplyAmount, this.cfg.TokenDecimal["pUSDT"])
-		borrowDollar := utils.ToIntByPrecise(borrowAmount, this.cfg.TokenDecimal["pUSDT"])
-		insuranceDollar := utils.ToIntByPrecise(insuranceAmount, this.cfg.TokenDecimal["pUSDT"])
+		supplyDollar := utils.ToIntByPrecise(supplyAmount, cfg.TokenDecimal["pUSDT"])
+		borrowDollar := utils.ToIntByPrecise(borrowAmount, cfg.TokenDecimal["pUSDT"])
+		insuranceDollar := utils.ToIntByPrecise(insuranceAmount, cfg.TokenDecimal["pUSDT"])
 		s = new(big.Int).Add(s, supplyDollar)
 		b = new(big.Int).Add(b, borrowDollar)
 		i = new(big.Int).Add(i, insuranceDollar)
 	}
 
-	flashPoolDetail.TotalSupply = utils.ToStringByPrecise(s, this.cfg.TokenDecimal["pUSDT"])
-	flashPoolDetail.TotalBorrow = utils.ToStringByPrecise(b, this.cfg.TokenDecimal["pUSDT"])
-	flashPoolDetail.TotalInsurance = utils.ToStringByPrecise(i, this.cfg.TokenDecimal["pUSDT"])
+	flashPoolDetail.TotalSupply = utils.ToStringByPrecise(s, cfg.TokenDecimal["pUSDT"])
+	flashPoolDetail.TotalBorrow = utils.ToStringByPrecise(b, cfg.TokenDecimal["pUSDT"])
+	flashPoolDetail.TotalInsurance = utils.ToStringByPrecise(i, cfg.TokenDecimal["pUSDT"])
 
 	return flashPoolDetail, nil
 }
 
 func (this *FlashPoolManager) FlashPoolDetailForStore() (*store.FlashPoolDetail, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("FlashPoolDetailForStore, this.GetAllMarkets error: %s", err)
@@ -231,7 +240,7 @@ func (this *FlashPoolManager) FlashPoolDetailForStore() (*store.FlashPoolDetail,
 	b := new(big.Int).SetUint64(0)
 	i := new(big.Int).SetUint64(0)
 	for _, address := range allMarkets {
-		name := this.cfg.AssetMap[address.ToHexString()]
+		name := cfg.AssetMap[address.ToHexString()]
 		supplyAmount, err := this.getSupplyAmount(address)
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolDetailForStore, this.getSupplyAmount error: %s", err)
@@ -244,32 +253,33 @@ func (this *FlashPoolManager) FlashPoolDetailForStore() (*store.FlashPoolDetail,
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolDetailForStore, this.getSupplyAmount error: %s", err)
 		}
-		priceStr, err := this.AssetPrice(this.cfg.OracleMap[address.ToHexString()])
+		priceStr, err := this.AssetPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolDetailForStore, this.AssetPrice error: %s", err)
 		}
-		price := utils.ToIntByPrecise(priceStr, this.cfg.TokenDecimal["oracle"])
+		price := utils.ToIntByPrecise(priceStr, cfg.TokenDecimal["oracle"])
 		// supplyAmount * price
 		// borrowAmount * price
 		// insuranceAmount * price
 		supplyDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(supplyAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		borrowDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(borrowAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		insuranceDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(insuranceAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		s = new(big.Int).Add(s, supplyDollar)
 		b = new(big.Int).Add(b, borrowDollar)
 		i = new(big.Int).Add(i, insuranceDollar)
 	}
 	flashPoolDetail.Timestamp = uint64(time.Now().Unix())
-	flashPoolDetail.TotalSupply = utils.ToStringByPrecise(s, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
-	flashPoolDetail.TotalBorrow = utils.ToStringByPrecise(b, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
-	flashPoolDetail.TotalInsurance = utils.ToStringByPrecise(i, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
+	flashPoolDetail.TotalSupply = utils.ToStringByPrecise(s, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
+	flashPoolDetail.TotalBorrow = utils.ToStringByPrecise(b, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
+	flashPoolDetail.TotalInsurance = utils.ToStringByPrecise(i, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
 	return flashPoolDetail, nil
 }
 
 func (this *FlashPoolManager) FlashPoolMarketStore() error {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return fmt.Errorf("FlashPoolMarketStore, this.GetAllMarkets error: %s", err)
@@ -289,8 +299,8 @@ func (this *FlashPoolManager) FlashPoolMarketStore() error {
 		if err != nil {
 			return fmt.Errorf("FlashPoolMarketStore, this.getSupplyAmount error: %s", err)
 		}
-		name := this.cfg.AssetMap[address.ToHexString()]
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[address.ToHexString()])
+		name := cfg.AssetMap[address.ToHexString()]
+		price, err := this.AssetStoredPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return fmt.Errorf("FlashPoolMarketStore, this.AssetStoredPrice error: %s", err)
 		}
@@ -298,15 +308,15 @@ func (this *FlashPoolManager) FlashPoolMarketStore() error {
 		// borrowAmount * price
 		// insuranceAmount * price
 		supplyDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(supplyAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		borrowDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(borrowAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		insuranceDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(insuranceAmount, price),
-			this.cfg.TokenDecimal[name]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[name]), cfg.TokenDecimal["pUSDT"])
 		flashPoolMarket.Name = name
-		flashPoolMarket.TotalSupply = utils.ToStringByPrecise(supplyDollar, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
-		flashPoolMarket.TotalBorrow = utils.ToStringByPrecise(borrowDollar, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
-		flashPoolMarket.TotalInsurance = utils.ToStringByPrecise(insuranceDollar, this.cfg.TokenDecimal["pUSDT"]+this.cfg.TokenDecimal["oracle"])
+		flashPoolMarket.TotalSupply = utils.ToStringByPrecise(supplyDollar, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
+		flashPoolMarket.TotalBorrow = utils.ToStringByPrecise(borrowDollar, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
+		flashPoolMarket.TotalInsurance = utils.ToStringByPrecise(insuranceDollar, cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["oracle"])
 		flashPoolMarket.Timestamp = timestamp
 		err = this.store.SaveFlashPoolMarket(flashPoolMarket)
 		if err != nil {
@@ -325,7 +335,7 @@ func (this *FlashPoolManager) FlashPoolAllMarket() (*common.FlashPoolAllMarket,
 		FlashPoolAllMarket: make([]*common.Market, 0),
 	}
 	for _, address := range allMarkets {
-		market, err := this.store.LoadFlashMarket(this.cfg.AssetMap[address.ToHexString()])
+		market, err := this.store.LoadFlashMarket(this.cfg.Get().AssetMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolAllMarket, this.store.LoadFlashMarket error: %s", err)
 		}
@@ -335,6 +345,7 @@ func (this *FlashPoolManager) FlashPoolAllMarket() (*common.FlashPoolAllMarket,
 }
 
 func (this *FlashPoolManager) FlashPoolAllMarketForStore() (*common.FlashPoolAllMarket, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.GetAllMarkets error: %s", err)
@@ -343,7 +354,7 @@ func (this *FlashPoolManager) FlashPoolAllMarketForStore() (*common.FlashPoolAll
 		FlashPoolAllMarket: make([]*common.Market, 0),
 	}
 	for _, address := range allMarkets {
-		name := this.cfg.AssetMap[address.ToHexString()]
+		name := cfg.AssetMap[address.ToHexString()]
 		supplyAmount, err := this.getSupplyAmount(address)
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.getSupplyAmount error: %s", err)
@@ -356,7 +367,7 @@ func (this *FlashPoolManager) FlashPoolAllMarketForStore() (*common.FlashPoolAll
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.getInsuranceAmount error: %s", err)
 		}
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[address.ToHexString()])
+		price, err := this.AssetStoredPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.AssetStoredPrice error: %s", err)
 		}
@@ -373,31 +384,31 @@ func (this *FlashPoolManager) FlashPoolAllMarketForStore() (*common.FlashPoolAll
 		//if err != nil {
 		//	return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.getInsuranceApy error: %s", err)
 		//}
-		marketMeta, err := this.getMarketMeta(address)
+		marketMeta, err := this.marketMeta(address)
 		if err != nil {
-			return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.getMarketMeta error: %s", err)
+			return nil, fmt.Errorf("FlashPoolAllMarketForStore, this.marketMeta error: %s", err)
 		}
 
 		market := new(common.Market)
-		market.Name = this.cfg.AssetMap[address.ToHexString()]
-		market.Icon = this.cfg.IconMap[market.Name]
+		market.Name = cfg.AssetMap[address.ToHexString()]
+		market.Icon = cfg.IconMap[market.Name]
 
-		market.TotalSupplyAmount = utils.ToStringByPrecise(supplyAmount, this.cfg.TokenDecimal[name])
-		market.TotalBorrowAmount = utils.ToStringByPrecise(borrowAmount, this.cfg.TokenDecimal[name])
-		market.TotalInsuranceAmount = utils.ToStringByPrecise(insuranceAmount, this.cfg.TokenDecimal[name])
+		market.TotalSupplyAmount = utils.ToStringByPrecise(supplyAmount, cfg.TokenDecimal[name])
+		market.TotalBorrowAmount = utils.ToStringByPrecise(borrowAmount, cfg.TokenDecimal[name])
+		market.TotalInsuranceAmount = utils.ToStringByPrecise(insuranceAmount, cfg.TokenDecimal[name])
 		// supplyAmount * price
 		// borrowAmount * price
 		// insuranceAmount * price
 		market.TotalSupplyDollar = utils.ToStringByPrecise(new(big.Int).Mul(supplyAmount, price),
-			this.cfg.TokenDecimal[name]+this.cfg.TokenDecimal["oracle"])
+			cfg.TokenDecimal[name]+cfg.TokenDecimal["oracle"])
 		market.TotalBorrowDollar = utils.ToStringByPrecise(new(big.Int).Mul(borrowAmount, price),
-			this.cfg.TokenDecimal[name]+this.cfg.TokenDecimal["oracle"])
+			cfg.TokenDecimal[name]+cfg.TokenDecimal["oracle"])
 		market.TotalInsuranceDollar = utils.ToStringByPrecise(new(big.Int).Mul(insuranceAmount, price),
-			this.cfg.TokenDecimal[name]+this.cfg.TokenDecimal["oracle"])
-		market.CollateralFactor = utils.ToStringByPrecise(marketMeta.CollateralFactorMantissa, this.cfg.TokenDecimal["flash"])
-		market.SupplyApy = utils.ToStringByPrecise(supplyApy, this.cfg.TokenDecimal["flash"])
-		market.BorrowApy = utils.ToStringByPrecise(borrowApy, this.cfg.TokenDecimal["flash"])
-		//market.InsuranceApy = utils.ToStringByPrecise(insuranceApy, this.cfg.TokenDecimal["flash"])
+			cfg.TokenDecimal[name]+cfg.TokenDecimal["oracle"])
+		market.CollateralFactor = utils.ToStringByPrecise(marketMeta.CollateralFactorMantissa, cfg.TokenDecimal["flash"])
+		market.SupplyApy = utils.ToStringByPrecise(supplyApy, cfg.TokenDecimal["flash"])
+		market.BorrowApy = utils.ToStringByPrecise(borrowApy, cfg.TokenDecimal["flash"])
+		//market.InsuranceApy = utils.ToStringByPrecise(insuranceApy, cfg.TokenDecimal["flash"])
 		flashPoolAllMarket.FlashPoolAllMarket = append(flashPoolAllMarket.FlashPoolAllMarket, market)
 	}
 	return flashPoolAllMarket, nil
@@ -412,6 +423,7 @@ func (this *FlashPoolManager) UserFlashPoolOverview(accountStr string) (*common.
 }
 
 func (this *FlashPoolManager) allMarket() (*common.UserFlashPoolOverview, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("AllMarket, this.GetAllMarkets error: %s", err)
@@ -424,33 +436,33 @@ func (this *FlashPoolManager) allMarket() (*common.UserFlashPoolOverview, error)
 	}
 
 	for _, address := range allMarkets {
-		assetName := this.cfg.AssetMap[address.ToHexString()]
+		assetName := cfg.AssetMap[address.ToHexString()]
 		market, err := this.store.LoadFlashMarket(assetName)
 		if err != nil {
 			return nil, fmt.Errorf("UserFlashPoolOverview, this.store.LoadAssetApy error: %s", err)
 		}
 
-		borrowApy := utils.ToIntByPrecise(market.BorrowApy, this.cfg.TokenDecimal["flash"])
-		insuranceApy := utils.ToIntByPrecise(market.InsuranceApy, this.cfg.TokenDecimal["flash"])
-		borrowAmount := utils.ToIntByPrecise(market.TotalBorrowAmount, this.cfg.TokenDecimal[assetName])
-		supplyAmount := utils.ToIntByPrecise(market.TotalSupplyAmount, this.cfg.TokenDecimal[assetName])
+		borrowApy := utils.ToIntByPrecise(market.BorrowApy, cfg.TokenDecimal["flash"])
+		insuranceApy := utils.ToIntByPrecise(market.InsuranceApy, cfg.TokenDecimal["flash"])
+		borrowAmount := utils.ToIntByPrecise(market.TotalBorrowAmount, cfg.TokenDecimal[assetName])
+		supplyAmount := utils.ToIntByPrecise(market.TotalSupplyAmount, cfg.TokenDecimal[assetName])
 		supplyApy := new(big.Int)
 		if borrowAmount.Uint64() != 0 {
 			ratio := new(big.Int).Div(new(big.Int).Mul(borrowAmount,
-				new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["flash"]))))), supplyAmount)
+				new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["flash"]))))), supplyAmount)
 			supplyApy = new(big.Int).Div(new(big.Int).Mul(new(big.Int).Mul(borrowApy, ratio),
 				new(big.Int).SetUint64(85)), new(big.Int).SetUint64(100))
 		}
 
 		userMarket := &common.UserMarket{
-			Name:      this.cfg.AssetMap[address.ToHexString()],
-			Icon:      this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-			SupplyApy: utils.ToStringByPrecise(supplyApy, 2*this.cfg.TokenDecimal["flash"]),
-			BorrowApy: utils.ToStringByPrecise(borrowApy, this.cfg.TokenDecimal["flash"]),
+			Name:      cfg.AssetMap[address.ToHexString()],
+			Icon:      cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+			SupplyApy: utils.ToStringByPrecise(supplyApy, 2*cfg.TokenDecimal["flash"]),
+			BorrowApy: utils.ToStringByPrecise(borrowApy, cfg.TokenDecimal["flash"]),
 			BorrowLiquidity: utils.ToStringByPrecise(new(big.Int).Sub(utils.ToIntByPrecise(market.TotalSupplyAmount,
-				this.cfg.TokenDecimal[assetName]), utils.ToIntByPrecise(market.TotalBorrowAmount,
-				this.cfg.TokenDecimal[assetName])), this.cfg.TokenDecimal[assetName]),
-			InsuranceApy:     utils.ToStringByPrecise(insuranceApy, this.cfg.TokenDecimal["flash"]),
+				cfg.TokenDecimal[assetName]), utils.ToIntByPrecise(market.TotalBorrowAmount,
+				cfg.TokenDecimal[assetName])), cfg.TokenDecimal[assetName]),
+			InsuranceApy:     utils.ToStringByPrecise(insuranceApy, cfg.TokenDecimal["flash"]),
 			InsuranceAmount:  market.TotalInsuranceAmount,
 			CollateralFactor: market.CollateralFactor,
 			IfCollateral:     false,
@@ -461,6 +473,7 @@ func (this *FlashPoolManager) allMarket() (*common.UserFlashPoolOverview, error)
 }
 
 func (this *FlashPoolManager) userFlashPoolOverview(accountStr string) (*common.UserFlashPoolOverview, error) {
+	cfg := this.cfg.Get()
 	account, err := ocommon.AddressFromBase58(accountStr)
 	if err != nil {
 		return nil, fmt.Errorf("UserFlashPoolOverview, ocommon.AddressFromBase58 error: %s", err)
@@ -480,19 +493,25 @@ func (this *FlashPoolManager) userFlashPoolOverview(accountStr string) (*common.
 		return nil, fmt.Errorf("UserFlashPoolOverview, this.getAccountLiquidity error: %s", err)
 	}
 	userFlashPoolOverview.BorrowLimit = utils.ToStringByPrecise(new(big.Int).Sub(accountLiquidity.Liquidity.ToBigInt(),
-		accountLiquidity.Shortfall.ToBigInt()), this.cfg.TokenDecimal["oracle"])
+		accountLiquidity.Shortfall.ToBigInt()), cfg.TokenDecimal["oracle"])
 
 	userBalance, err := this.store.LoadUserBalance(accountStr)
 	if err != nil {
 		return nil, fmt.Errorf("UserFlashPoolOverview, this.store.LoadUserBalance error: %s", err)
 	}
-	netApy := new(big.Int).SetUint64(0)
-	s := new(big.Int).SetUint64(0)
+	wingApys, err := this.store.LoadWingApys()
+	if err != nil {
+		return nil, fmt.Errorf("UserFlashPoolOverview, this.store.LoadWingApys error: %s", err)
+	}
+	wingApyMap := make(map[string]common.WingApy)
+	for _, v := range wingApys {
+		wingApyMap[v.AssetName] = v
+	}
+	yield := newNetYield()
 	b := new(big.Int).SetUint64(0)
-	i := new(big.Int).SetUint64(0)
 	for _, address := range allMarkets {
-		assetName := this.cfg.AssetMap[address.ToHexString()]
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[address.ToHexString()])
+		assetName := cfg.AssetMap[address.ToHexString()]
+		price, err := this.AssetStoredPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("UserFlashPoolOverview, this.AssetStoredPrice error: %s", err)
 		}
@@ -502,18 +521,18 @@ func (this *FlashPoolManager) userFlashPoolOverview(accountStr string) (*common.
 				userAssetBalance = v
 			}
 		}
-		borrowAmount := utils.ToIntByPrecise(userAssetBalance.BorrowBalance, this.cfg.TokenDecimal[assetName])
+		borrowAmount := utils.ToIntByPrecise(userAssetBalance.BorrowBalance, cfg.TokenDecimal[assetName])
 		borrowDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(borrowAmount, price),
-			this.cfg.TokenDecimal[assetName]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[assetName]), cfg.TokenDecimal["pUSDT"])
 		b = new(big.Int).Add(b, borrowDollar)
 	}
 	for _, address := range allMarkets {
-		assetName := this.cfg.AssetMap[address.ToHexString()]
+		assetName := cfg.AssetMap[address.ToHexString()]
 		market, err := this.store.LoadFlashMarket(assetName)
 		if err != nil {
 			return nil, fmt.Errorf("UserFlashPoolOverview, this.store.LoadAssetApy error: %s", err)
 		}
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[address.ToHexString()])
+		price, err := this.AssetStoredPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("UserFlashPoolOverview, this.AssetStoredPrice error: %s", err)
 		}
@@ -523,99 +542,114 @@ func (this *FlashPoolManager) userFlashPoolOverview(accountStr string) (*common.
 				userAssetBalance = v
 			}
 		}
-		supplyAmount := utils.ToIntByPrecise(userAssetBalance.SupplyBalance, this.cfg.TokenDecimal[assetName])
-		borrowAmount := utils.ToIntByPrecise(userAssetBalance.BorrowBalance, this.cfg.TokenDecimal[assetName])
-		insuranceAmount := utils.ToIntByPrecise(userAssetBalance.InsuranceBalance, this.cfg.TokenDecimal[assetName])
+		supplyAmount := utils.ToIntByPrecise(userAssetBalance.SupplyBalance, cfg.TokenDecimal[assetName])
+		borrowAmount := utils.ToIntByPrecise(userAssetBalance.BorrowBalance, cfg.TokenDecimal[assetName])
+		insuranceAmount := utils.ToIntByPrecise(userAssetBalance.InsuranceBalance, cfg.TokenDecimal[assetName])
 		// supplyAmount * price
 		// borrowAmount * price
 		// insuranceAmount * price
 		supplyDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(supplyAmount, price),
-			this.cfg.TokenDecimal[assetName]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[assetName]), cfg.TokenDecimal["pUSDT"])
 		borrowDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(borrowAmount, price),
-			this.cfg.TokenDecimal[assetName]), this.cfg.TokenDecimal["pUSDT"])
+			cfg.TokenDecimal[assetName]), cfg.TokenDecimal["pUSDT"])
 		insuranceDollar := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Mul(insuranceAmount, price),
-			this.cfg.TokenDecimal[assetName]), this.cfg.TokenDecimal["pUSDT"])
-		s = new(big.Int).Add(s, supplyDollar)
-		i = new(big.Int).Add(i, insuranceDollar)
-		supplyApy := utils.ToIntByPrecise(market.SupplyApy, this.cfg.TokenDecimal["flash"])
-		borrowApy := utils.ToIntByPrecise(market.BorrowApy, this.cfg.TokenDecimal["flash"])
-		insuranceApy := utils.ToIntByPrecise(market.InsuranceApy, this.cfg.TokenDecimal["flash"])
-		sa := new(big.Int).Mul(supplyDollar, supplyApy)
-		ia := new(big.Int).Mul(insuranceDollar, insuranceApy)
-		ba := new(big.Int).Mul(borrowDollar, borrowApy)
-		netApy = new(big.Int).Add(netApy, new(big.Int).Sub(new(big.Int).Add(sa, ia), ba))
-
-		claimWingAtMarket, err := this.getClaimWingAtMarket(account, []interface{}{address})
-		if err != nil {
-			return nil, fmt.Errorf("UserFlashPoolOverview, this.getClaimWingAtMarket account %s asset %s error: %s",
-				account.ToBase58(), address.ToHexString(), err)
+			cfg.TokenDecimal[assetName]), cfg.TokenDecimal["pUSDT"])
+		supplyApy := utils.ToIntByPrecise(market.SupplyApy, cfg.TokenDecimal["flash"])
+		borrowApy := utils.ToIntByPrecise(market.BorrowApy, cfg.TokenDecimal["flash"])
+		insuranceApy := utils.ToIntByPrecise(market.InsuranceApy, cfg.TokenDecimal["flash"])
+		supplyRewardApy := utils.ToIntByPrecise(wingApyMap[assetName].SupplyApy, cfg.TokenDecimal["flash"])
+		borrowRewardApy := utils.ToIntByPrecise(wingApyMap[assetName].BorrowApy, cfg.TokenDecimal["flash"])
+		insuranceRewardApy := utils.ToIntByPrecise(wingApyMap[assetName].InsuranceApy, cfg.TokenDecimal["flash"])
+		yield.add(&positionApy{
+			supplyDollar:       supplyDollar,
+			borrowDollar:       borrowDollar,
+			insuranceDollar:    insuranceDollar,
+			supplyApy:          supplyApy,
+			borrowApy:          borrowApy,
+			insuranceApy:       insuranceApy,
+			supplyRewardApy:    supplyRewardApy,
+			borrowRewardApy:    borrowRewardApy,
+			insuranceRewardApy: insuranceRewardApy,
+		})
+
+		supplyWing, borrowWing, insuranceWing, _, err := this.marketWingEarned(account, address, &market,
+			supplyAmount, borrowAmount, insuranceAmount)
+		if err != nil {
+			return nil, fmt.Errorf("UserFlashPoolOverview, this.marketWingEarned error: %s", err)
 		}
 
 		if supplyAmount.Uint64() != 0 {
 			supply := &common.Supply{
-				Name:             this.cfg.AssetMap[address.ToHexString()],
-				Icon:             this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-				SupplyBalance:    utils.ToStringByPrecise(supplyAmount, this.cfg.TokenDecimal[assetName]),
-				Apy:              utils.ToStringByPrecise(supplyApy, this.cfg.TokenDecimal["flash"]),
+				Name:             cfg.AssetMap[address.ToHexString()],
+				Icon:             cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+				SupplyBalance:    utils.ToStringByPrecise(supplyAmount, cfg.TokenDecimal[assetName]),
+				Apy:              utils.ToStringByPrecise(supplyApy, cfg.TokenDecimal["flash"]),
+				RewardApy:        utils.ToStringByPrecise(supplyRewardApy, cfg.TokenDecimal["flash"]),
 				CollateralFactor: market.CollateralFactor,
-				WingEarned:       utils.ToStringByPrecise(claimWingAtMarket, this.cfg.TokenDecimal["WING"]),
+				WingEarned:       utils.ToStringByPrecise(supplyWing, cfg.TokenDecimal["WING"]),
 				IfCollateral:     userAssetBalance.IfCollateral,
 			}
 			userFlashPoolOverview.CurrentSupply = append(userFlashPoolOverview.CurrentSupply, supply)
 		}
 		if borrowAmount.Uint64() != 0 {
 			borrow := &common.Borrow{
-				Name:             this.cfg.AssetMap[address.ToHexString()],
-				Icon:             this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-				BorrowBalance:    utils.ToStringByPrecise(borrowAmount, this.cfg.TokenDecimal[assetName]),
-				Apy:              utils.ToStringByPrecise(borrowApy, this.cfg.TokenDecimal["flash"]),
-				WingEarned:       utils.ToStringByPrecise(claimWingAtMarket, this.cfg.TokenDecimal["WING"]),
+				Name:             cfg.AssetMap[address.ToHexString()],
+				Icon:             cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+				BorrowBalance:    utils.ToStringByPrecise(borrowAmount, cfg.TokenDecimal[assetName]),
+				Apy:              utils.ToStringByPrecise(borrowApy, cfg.TokenDecimal["flash"]),
+				RewardApy:        utils.ToStringByPrecise(borrowRewardApy, cfg.TokenDecimal["flash"]),
+				WingEarned:       utils.ToStringByPrecise(borrowWing, cfg.TokenDecimal["WING"]),
 				CollateralFactor: market.CollateralFactor,
 			}
 			if accountLiquidity.Liquidity.ToBigInt().Uint64() != 0 {
 				borrowLimit := utils.ToIntByPrecise(utils.ToStringByPrecise(new(big.Int).Sub(accountLiquidity.Liquidity.ToBigInt(),
-					accountLiquidity.Shortfall.ToBigInt()), this.cfg.TokenDecimal["oracle"]), this.cfg.TokenDecimal["oracle"]+this.cfg.TokenDecimal["pUSDT"])
+					accountLiquidity.Shortfall.ToBigInt()), cfg.TokenDecimal["oracle"]), cfg.TokenDecimal["oracle"]+cfg.TokenDecimal["pUSDT"])
 				totalLimit := new(big.Int).Add(borrowLimit, b)
 				borrow.Limit = utils.ToStringByPrecise(new(big.Int).Div(new(big.Int).Mul(borrowDollar, new(big.Int).SetUint64(
-					uint64(math.Pow10(int(this.cfg.TokenDecimal["percentage"]))))), totalLimit), this.cfg.TokenDecimal["percentage"])
+					uint64(math.Pow10(int(cfg.TokenDecimal["percentage"]))))), totalLimit), cfg.TokenDecimal["percentage"])
 			}
 			userFlashPoolOverview.CurrentBorrow = append(userFlashPoolOverview.CurrentBorrow, borrow)
 		}
 		if insuranceAmount.Uint64() != 0 {
 			insurance := &common.Insurance{
-				Name:             this.cfg.AssetMap[address.ToHexString()],
-				Icon:             this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-				InsuranceBalance: utils.ToStringByPrecise(insuranceAmount, this.cfg.TokenDecimal[assetName]),
-				Apy:              utils.ToStringByPrecise(insuranceApy, this.cfg.TokenDecimal["flash"]),
-				WingEarned:       utils.ToStringByPrecise(claimWingAtMarket, this.cfg.TokenDecimal["WING"]),
+				Name:             cfg.AssetMap[address.ToHexString()],
+				Icon:             cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+				InsuranceBalance: utils.ToStringByPrecise(insuranceAmount, cfg.TokenDecimal[assetName]),
+				Apy:              utils.ToStringByPrecise(insuranceApy, cfg.TokenDecimal["flash"]),
+				RewardApy:        utils.ToStringByPrecise(insuranceRewardApy, cfg.TokenDecimal["flash"]),
+				WingEarned:       utils.ToStringByPrecise(insuranceWing, cfg.TokenDecimal["WING"]),
 				CollateralFactor: market.CollateralFactor,
 			}
 			userFlashPoolOverview.CurrentInsurance = append(userFlashPoolOverview.CurrentInsurance, insurance)
 		}
 
 		userMarket := &common.UserMarket{
-			Name:      this.cfg.AssetMap[address.ToHexString()],
-			Icon:      this.cfg.IconMap[this.cfg.AssetMap[address.ToHexString()]],
-			SupplyApy: utils.ToStringByPrecise(supplyApy, this.cfg.TokenDecimal["flash"]),
-			BorrowApy: utils.ToStringByPrecise(borrowApy, this.cfg.TokenDecimal["flash"]),
+			Name:      cfg.AssetMap[address.ToHexString()],
+			Icon:      cfg.IconMap[cfg.AssetMap[address.ToHexString()]],
+			SupplyApy: utils.ToStringByPrecise(supplyApy, cfg.TokenDecimal["flash"]),
+			BorrowApy: utils.ToStringByPrecise(borrowApy, cfg.TokenDecimal["flash"]),
 			BorrowLiquidity: utils.ToStringByPrecise(new(big.Int).Sub(utils.ToIntByPrecise(market.TotalSupplyAmount,
-				this.cfg.TokenDecimal[assetName]), utils.ToIntByPrecise(market.TotalBorrowAmount,
-				this.cfg.TokenDecimal[assetName])), this.cfg.TokenDecimal[assetName]),
-			InsuranceApy:     utils.ToStringByPrecise(insuranceApy, this.cfg.TokenDecimal["flash"]),
+				cfg.TokenDecimal[assetName]), utils.ToIntByPrecise(market.TotalBorrowAmount,
+				cfg.TokenDecimal[assetName])), cfg.TokenDecimal[assetName]),
+			InsuranceApy:     utils.ToStringByPrecise(insuranceApy, cfg.TokenDecimal["flash"]),
 			InsuranceAmount:  market.TotalInsuranceAmount,
 			CollateralFactor: market.CollateralFactor,
 			IfCollateral:     userAssetBalance.IfCollateral,
 		}
 		userFlashPoolOverview.AllMarket = append(userFlashPoolOverview.AllMarket, userMarket)
 	}
-	total := new(big.Int).Add(s, i)
-	if total.Uint64() != 0 {
-		userFlashPoolOverview.NetApy = utils.ToStringByPrecise(new(big.Int).Div(netApy, total), this.cfg.TokenDecimal["flash"])
+	if netApy, netApyWithRewards, ok := yield.apys(); ok {
+		userFlashPoolOverview.NetApy = utils.ToStringByPrecise(netApy, cfg.TokenDecimal["flash"])
+		userFlashPoolOverview.NetApyWithRewards = utils.ToStringByPrecise(netApyWithRewards,
+			cfg.TokenDecimal["flash"])
 	}
+	userFlashPoolOverview.YearlyYield = utils.ToStringByPrecise(yield.yearlyYield(),
+		cfg.TokenDecimal["pUSDT"]+cfg.TokenDecimal["flash"])
 	return userFlashPoolOverview, nil
 }
 
 func (this *FlashPoolManager) UserBalanceForStore(accountStr string) error {
+	cfg := this.cfg.Get()
 	account, err := ocommon.AddressFromBase58(accountStr)
 	if err != nil {
 		return fmt.Errorf("UserBalanceForStore, ocommon.AddressFromBase58 error: %s", err)
@@ -638,7 +672,7 @@ func (this *FlashPoolManager) UserBalanceForStore(accountStr string) error {
 		if err != nil {
 			return fmt.Errorf("UserBalanceForStore, this.getInsuranceAmountByAccount error: %s", err)
 		}
-		name := this.cfg.AssetMap[address.ToHexString()]
+		name := cfg.AssetMap[address.ToHexString()]
 		isAssetIn := false
 		for _, a := range assetsIn {
 			if address == a {
@@ -650,10 +684,10 @@ func (this *FlashPoolManager) UserBalanceForStore(accountStr string) error {
 			UserAddress:      accountStr,
 			AssetAddress:     address.ToHexString(),
 			AssetName:        name,
-			Icon:             this.cfg.IconMap[name],
-			SupplyBalance:    utils.ToStringByPrecise(supplyAmount, this.cfg.TokenDecimal[name]),
-			BorrowBalance:    utils.ToStringByPrecise(borrowAmount, this.cfg.TokenDecimal[name]),
-			InsuranceBalance: utils.ToStringByPrecise(insuranceAmount, this.cfg.TokenDecimal[name]),
+			Icon:             cfg.IconMap[name],
+			SupplyBalance:    utils.ToStringByPrecise(supplyAmount, cfg.TokenDecimal[name]),
+			BorrowBalance:    utils.ToStringByPrecise(borrowAmount, cfg.TokenDecimal[name]),
+			InsuranceBalance: utils.ToStringByPrecise(insuranceAmount, cfg.TokenDecimal[name]),
 			IfCollateral:     isAssetIn,
 		}
 		err = this.store.SaveUserAssetBalance(userBalance)
@@ -673,7 +707,108 @@ func (this *FlashPoolManager) ClaimWing(accountStr string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("ClaimWing, this.getClaimWing error: %s", err)
 	}
-	return utils.ToStringByPrecise(amount, this.cfg.TokenDecimal["WING"]), nil
+	return utils.ToStringByPrecise(amount, this.cfg.Get().TokenDecimal["WING"]), nil
+}
+
+func (this *FlashPoolManager) WingEarnings(accountStr string) (*common.WingEarnings, error) {
+	cfg := this.cfg.Get()
+	account, err := ocommon.AddressFromBase58(accountStr)
+	if err != nil {
+		return nil, fmt.Errorf("WingEarnings, ocommon.AddressFromBase58 error: %s", err)
+	}
+	allMarkets, err := this.GetAllMarkets()
+	if err != nil {
+		return nil, fmt.Errorf("WingEarnings, this.GetAllMarkets error: %s", err)
+	}
+	userBalance, err := this.store.LoadUserBalance(accountStr)
+	if err != nil {
+		return nil, fmt.Errorf("WingEarnings, this.store.LoadUserBalance error: %s", err)
+	}
+	wingEarnings := &common.WingEarnings{
+		Markets: make([]*common.MarketWingEarned, 0),
+	}
+	unclaimed := new(big.Int)
+	for _, address := range allMarkets {
+		assetName := cfg.AssetMap[address.ToHexString()]
+		market, err := this.store.LoadFlashMarket(assetName)
+		if err != nil {
+			return nil, fmt.Errorf("WingEarnings, this.store.LoadFlashMarket error: %s", err)
+		}
+		userAssetBalance := store.UserAssetBalance{}
+		for _, v := range userBalance {
+			if v.AssetName == assetName {
+				userAssetBalance = v
+			}
+		}
+		supplyAmount := utils.ToIntByPrecise(userAssetBalance.SupplyBalance, cfg.TokenDecimal[assetName])
+		borrowAmount := utils.ToIntByPrecise(userAssetBalance.BorrowBalance, cfg.TokenDecimal[assetName])
+		insuranceAmount := utils.ToIntByPrecise(userAssetBalance.InsuranceBalance, cfg.TokenDecimal[assetName])
+		supplyWing, borrowWing, insuranceWing, total, err := this.marketWingEarned(account, address, &market,
+			supplyAmount, borrowAmount, insuranceAmount)
+		if err != nil {
+			return nil, fmt.Errorf("WingEarnings, this.marketWingEarned error: %s", err)
+		}
+		unclaimed = new(big.Int).Add(unclaimed, total)
+		if total.Sign() == 0 {
+			continue
+		}
+		wingEarnings.Markets = append(wingEarnings.Markets, &common.MarketWingEarned{
+			Icon:            cfg.IconMap[assetName],
+			Name:            assetName,
+			SupplyEarned:    utils.ToStringByPrecise(supplyWing, cfg.TokenDecimal["WING"]),
+			BorrowEarned:    utils.ToStringByPrecise(borrowWing, cfg.TokenDecimal["WING"]),
+			InsuranceEarned: utils.ToStringByPrecise(insuranceWing, cfg.TokenDecimal["WING"]),
+			Total:           utils.ToStringByPrecise(total, cfg.TokenDecimal["WING"]),
+		})
+	}
+	claimed, err := this.store.LoadWingClaimedAmount(accountStr)
+	if err != nil {
+		return nil, fmt.Errorf("WingEarnings, this.store.LoadWingClaimedAmount error: %s", err)
+	}
+	claimedAmount, ok := new(big.Int).SetString(claimed, 10)
+	if !ok {
+		return nil, fmt.Errorf("WingEarnings, invalid claimed amount %s", claimed)
+	}
+	wingEarnings.SplitMethod = common.WING_SPLIT_BALANCE_SHARE
+	wingEarnings.ClaimedScope = common.WING_CLAIMED_INDEXED
+	wingEarnings.Claimed = utils.ToStringByPrecise(claimedAmount, cfg.TokenDecimal["WING"])
+	wingEarnings.Unclaimed = utils.ToStringByPrecise(unclaimed, cfg.TokenDecimal["WING"])
+	wingEarnings.Earned = utils.ToStringByPrecise(new(big.Int).Add(claimedAmount, unclaimed), cfg.TokenDecimal["WING"])
+	return wingEarnings, nil
+}
+
+func (this *FlashPoolManager) WingClaimHistory(accountStr string, offset, limit uint64) (*common.WingClaimHistory, error) {
+	if _, err := ocommon.AddressFromBase58(accountStr); err != nil {
+		return nil, fmt.Errorf("WingClaimHistory, ocommon.AddressFromBase58 error: %s", err)
+	}
+	if limit == 0 || limit > MaxClaimHistoryLimit {
+		limit = MaxClaimHistoryLimit
+	}
+	claims, err := this.store.LoadWingClaims(accountStr, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("WingClaimHistory, this.store.LoadWingClaims error: %s", err)
+	}
+	count, err := this.store.LoadWingClaimCount(accountStr)
+	if err != nil {
+		return nil, fmt.Errorf("WingClaimHistory, this.store.LoadWingClaimCount error: %s", err)
+	}
+	wingClaimHistory := &common.WingClaimHistory{
+		Total:  count,
+		Claims: make([]*common.WingClaim, 0),
+	}
+	for _, v := range claims {
+		amount, ok := new(big.Int).SetString(v.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("WingClaimHistory, invalid amount %s of claim %s", v.Amount, v.TxHash)
+		}
+		wingClaimHistory.Claims = append(wingClaimHistory.Claims, &common.WingClaim{
+			TxHash:    v.TxHash,
+			Height:    v.Height,
+			Timestamp: v.Timestamp,
+			Amount:    utils.ToStringByPrecise(amount, this.cfg.Get().TokenDecimal["WING"]),
+		})
+	}
+	return wingClaimHistory, nil
 }
 
 func (this *FlashPoolManager) BorrowAddressList() ([]store.UserAssetBalance, error) {
@@ -685,6 +820,7 @@ func (this *FlashPoolManager) BorrowAddressList() ([]store.UserAssetBalance, err
 }
 
 func (this *FlashPoolManager) LiquidationList(accountStr string) ([]*common.Liquidation, error) {
+	cfg := this.cfg.Get()
 	account, err := ocommon.AddressFromBase58(accountStr)
 	if err != nil {
 		return nil, fmt.Errorf("LiquidationList, ocommon.AddressFromBase58 error: %s", err)
@@ -698,27 +834,27 @@ func (this *FlashPoolManager) LiquidationList(accountStr string) ([]*common.Liqu
 	totalCollateralDollar := new(big.Int)
 	collateralAssets := make([]*common.CollateralAsset, 0)
 	for _, v := range userBalance {
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[v.AssetAddress])
+		price, err := this.AssetStoredPrice(cfg.OracleMap[v.AssetAddress])
 		if err != nil {
 			return nil, fmt.Errorf("LiquidationList, this.AssetStoredPrice error: %s", err)
 		}
-		borrowDollar := new(big.Int).Mul(utils.ToIntByPrecise(v.BorrowBalance, this.cfg.TokenDecimal["pETH"]), price)
+		borrowDollar := new(big.Int).Mul(utils.ToIntByPrecise(v.BorrowBalance, cfg.TokenDecimal["pETH"]), price)
 		totalBorrowDollar = new(big.Int).Add(totalBorrowDollar, borrowDollar)
 		if v.IfCollateral && v.SupplyBalance != "0" {
-			supplyDollar := new(big.Int).Mul(utils.ToIntByPrecise(v.SupplyBalance, this.cfg.TokenDecimal["pETH"]), price)
+			supplyDollar := new(big.Int).Mul(utils.ToIntByPrecise(v.SupplyBalance, cfg.TokenDecimal["pETH"]), price)
 			totalCollateralDollar = new(big.Int).Add(totalCollateralDollar, supplyDollar)
 			collateralAsset := &common.CollateralAsset{
 				Icon:    v.Icon,
 				Name:    v.AssetName,
 				Balance: v.SupplyBalance,
-				Dollar:  utils.ToStringByPrecise(supplyDollar, this.cfg.TokenDecimal["pETH"]+this.cfg.TokenDecimal["oracle"]),
+				Dollar:  utils.ToStringByPrecise(supplyDollar, cfg.TokenDecimal["pETH"]+cfg.TokenDecimal["oracle"]),
 			}
 			collateralAssets = append(collateralAssets, collateralAsset)
 		}
 	}
 	for _, v := range userBalance {
 		if v.BorrowBalance != "0" {
-			price, err := this.AssetStoredPrice(this.cfg.OracleMap[v.AssetAddress])
+			price, err := this.AssetStoredPrice(cfg.OracleMap[v.AssetAddress])
 			if err != nil {
 				return nil, fmt.Errorf("LiquidationList, this.AssetStoredPrice error: %s", err)
 			}
@@ -729,22 +865,22 @@ func (this *FlashPoolManager) LiquidationList(accountStr string) ([]*common.Liqu
 				CollateralAssets: collateralAssets,
 			}
 			liquidation.BorrowDollar = utils.ToStringByPrecise(new(big.Int).Mul(utils.ToIntByPrecise(v.BorrowBalance,
-				this.cfg.TokenDecimal[v.AssetName]), price), this.cfg.TokenDecimal[v.AssetName]+this.cfg.TokenDecimal["oracle"])
+				cfg.TokenDecimal[v.AssetName]), price), cfg.TokenDecimal[v.AssetName]+cfg.TokenDecimal["oracle"])
 			accountLiquidity, err := this.getAccountLiquidity(account)
 			if err != nil {
 				return nil, fmt.Errorf("LiquidationList, this.getAccountLiquidity error: %s", err)
 			}
 			liquidity := new(big.Int).Sub(accountLiquidity.Liquidity.ToBigInt(), accountLiquidity.Shortfall.ToBigInt())
-			limit := new(big.Int).Add(utils.ToIntByPrecise(utils.ToStringByPrecise(liquidity, this.cfg.TokenDecimal["oracle"]),
-				this.cfg.TokenDecimal["pETH"]+this.cfg.TokenDecimal["oracle"]), totalBorrowDollar)
+			limit := new(big.Int).Add(utils.ToIntByPrecise(utils.ToStringByPrecise(liquidity, cfg.TokenDecimal["oracle"]),
+				cfg.TokenDecimal["pETH"]+cfg.TokenDecimal["oracle"]), totalBorrowDollar)
 			if limit.Uint64() != 0 {
 				liquidation.BorrowLimitUsed = utils.ToStringByPrecise(new(big.Int).Div(new(big.Int).Mul(totalBorrowDollar,
-					new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["percentage"]))))), limit),
-					this.cfg.TokenDecimal["percentage"])
+					new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["percentage"]))))), limit),
+					cfg.TokenDecimal["percentage"])
 			} else {
 				liquidation.BorrowLimitUsed = "10"
 			}
-			liquidation.CollateralDollar = utils.ToStringByPrecise(totalCollateralDollar, this.cfg.TokenDecimal["pETH"]+this.cfg.TokenDecimal["oracle"])
+			liquidation.CollateralDollar = utils.ToStringByPrecise(totalCollateralDollar, cfg.TokenDecimal["pETH"]+cfg.TokenDecimal["oracle"])
 			liquidationList = append(liquidationList, liquidation)
 		}
 	}
@@ -752,6 +888,7 @@ func (this *FlashPoolManager) LiquidationList(accountStr string) ([]*common.Liqu
 }
 
 func (this *FlashPoolManager) WingApyForStore() error {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return fmt.Errorf("WingApy, this.GetAllMarkets error: %s", err)
@@ -771,34 +908,34 @@ func (this *FlashPoolManager) WingApyForStore() error {
 		if err != nil {
 			return fmt.Errorf("WingApy, this.AssetStoredPrice error: %s", err)
 		}
-		market, err := this.store.LoadFlashMarket(this.cfg.AssetMap[address.ToHexString()])
+		market, err := this.store.LoadFlashMarket(cfg.AssetMap[address.ToHexString()])
 		if err != nil {
 			return fmt.Errorf("WingApy, this.store.LoadFlashMarket error: %s", err)
 		}
-		totalSupplyDollar := utils.ToIntByPrecise(market.TotalSupplyDollar, this.cfg.TokenDecimal["pUSDT"])
-		totalBorrowDollar := utils.ToIntByPrecise(market.TotalBorrowDollar, this.cfg.TokenDecimal["pUSDT"])
-		totalInsuranceDollar := utils.ToIntByPrecise(market.TotalInsuranceDollar, this.cfg.TokenDecimal["pUSDT"])
+		totalSupplyDollar := utils.ToIntByPrecise(market.TotalSupplyDollar, cfg.TokenDecimal["pUSDT"])
+		totalBorrowDollar := utils.ToIntByPrecise(market.TotalBorrowDollar, cfg.TokenDecimal["pUSDT"])
+		totalInsuranceDollar := utils.ToIntByPrecise(market.TotalInsuranceDollar, cfg.TokenDecimal["pUSDT"])
 		var supplyApy, borrowApy, insuranceApy string
 		if totalSupplyDollar.Uint64() != 0 {
 			supplyApy = utils.ToStringByPrecise(new(big.Int).Div(new(big.Int).Div(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(wingSpeeds,
 				wingSBIPortion.SupplyPortion.ToBigInt()), price), new(big.Int).SetUint64(governance.YearSecond)),
-				new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["pUSDT"]))))), totalPortion),
-				totalSupplyDollar), this.cfg.TokenDecimal["oracle"]+this.cfg.TokenDecimal["WING"])
+				new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["pUSDT"]))))), totalPortion),
+				totalSupplyDollar), cfg.TokenDecimal["oracle"]+cfg.TokenDecimal["WING"])
 		}
 		if totalBorrowDollar.Uint64() != 0 {
 			borrowApy = utils.ToStringByPrecise(new(big.Int).Div(new(big.Int).Div(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(wingSpeeds,
 				wingSBIPortion.BorrowPortion.ToBigInt()), price), new(big.Int).SetUint64(governance.YearSecond)),
-				new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["pUSDT"]))))), totalPortion),
-				totalBorrowDollar), this.cfg.TokenDecimal["oracle"]+this.cfg.TokenDecimal["WING"])
+				new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["pUSDT"]))))), totalPortion),
+				totalBorrowDollar), cfg.TokenDecimal["oracle"]+cfg.TokenDecimal["WING"])
 		}
 		if totalInsuranceDollar.Uint64() != 0 {
 			insuranceApy = utils.ToStringByPrecise(new(big.Int).Div(new(big.Int).Div(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(new(big.Int).Mul(wingSpeeds,
 				wingSBIPortion.InsurancePortion.ToBigInt()), price), new(big.Int).SetUint64(governance.YearSecond)),
-				new(big.Int).SetUint64(uint64(math.Pow10(int(this.cfg.TokenDecimal["pUSDT"]))))), totalPortion),
-				totalInsuranceDollar), this.cfg.TokenDecimal["oracle"]+this.cfg.TokenDecimal["WING"])
+				new(big.Int).SetUint64(uint64(math.Pow10(int(cfg.TokenDecimal["pUSDT"]))))), totalPortion),
+				totalInsuranceDollar), cfg.TokenDecimal["oracle"]+cfg.TokenDecimal["WING"])
 		}
 		wingApy := &common.WingApy{
-			AssetName:    this.cfg.AssetMap[address.ToHexString()],
+			AssetName:    cfg.AssetMap[address.ToHexString()],
 			SupplyApy:    supplyApy,
 			BorrowApy:    borrowApy,
 			InsuranceApy: insuranceApy,
@@ -811,6 +948,32 @@ func (this *FlashPoolManager) WingApyForStore() error {
 	return nil
 }
 
+func (this *FlashPoolManager) WingSpeeds() ([]*common.WingSpeed, error) {
+	allMarkets, err := this.GetAllMarkets()
+	if err != nil {
+		return nil, fmt.Errorf("WingSpeeds, this.GetAllMarkets error: %s", err)
+	}
+	wingSpeeds := make([]*common.WingSpeed, 0)
+	for _, address := range allMarkets {
+		wingSpeed, err := this.getWingSpeeds(address)
+		if err != nil {
+			return nil, fmt.Errorf("WingSpeeds, this.getWingSpeeds error: %s", err)
+		}
+		wingSBIPortion, err := this.getWingSBIPortion(address)
+		if err != nil {
+			return nil, fmt.Errorf("WingSpeeds, this.getWingSBIPortion error: %s", err)
+		}
+		wingSpeeds = append(wingSpeeds, &common.WingSpeed{
+			Name:             this.cfg.Get().AssetMap[address.ToHexString()],
+			WingSpeed:        wingSpeed.String(),
+			SupplyPortion:    wingSBIPortion.SupplyPortion.ToBigInt().String(),
+			BorrowPortion:    wingSBIPortion.BorrowPortion.ToBigInt().String(),
+			InsurancePortion: wingSBIPortion.InsurancePortion.ToBigInt().String(),
+		})
+	}
+	return wingSpeeds, nil
+}
+
 func (this *FlashPoolManager) WingApys() ([]common.WingApy, error) {
 	wingApys, err := this.store.LoadWingApys()
 	if err != nil {
@@ -820,6 +983,7 @@ func (this *FlashPoolManager) WingApys() ([]common.WingApy, error) {
 }
 
 func (this *FlashPoolManager) Reserves() (*common.Reserves, error) {
+	cfg := this.cfg.Get()
 	allMarkets, err := this.GetAllMarkets()
 	if err != nil {
 		return nil, fmt.Errorf("TotalReserve, this.GetAllMarkets error: %s", err)
@@ -829,8 +993,8 @@ func (this *FlashPoolManager) Reserves() (*common.Reserves, error) {
 		AssetReserve: make([]*common.Reserve, 0),
 	}
 	for _, address := range allMarkets {
-		name := this.cfg.AssetMap[address.ToHexString()]
-		price, err := this.AssetStoredPrice(this.cfg.OracleMap[address.ToHexString()])
+		name := cfg.AssetMap[address.ToHexString()]
+		price, err := this.AssetStoredPrice(cfg.OracleMap[address.ToHexString()])
 		if err != nil {
 			return nil, fmt.Errorf("TotalReserve, this.AssetStoredPrice error: %s", err)
 		}
@@ -838,21 +1002,21 @@ func (this *FlashPoolManager) Reserves() (*common.Reserves, error) {
 		if err != nil {
 			return nil, fmt.Errorf("TotalReserve, this.getTotalReserves error: %s", err)
 		}
-		reserveBalanceStr := utils.ToStringByPrecise(reserveBalance, this.cfg.TokenDecimal[name])
+		reserveBalanceStr := utils.ToStringByPrecise(reserveBalance, cfg.TokenDecimal[name])
 		reserveDollarStr := utils.ToStringByPrecise(new(big.Int).Mul(price, reserveBalance),
-			this.cfg.TokenDecimal[name]+this.cfg.TokenDecimal["oracle"])
+			cfg.TokenDecimal[name]+cfg.TokenDecimal["oracle"])
 		assetReserve := &common.Reserve{
 			Name:           name,
-			Icon:           this.cfg.IconMap[name],
+			Icon:           cfg.IconMap[name],
 			ReserveFactor:  "0.15",
 			ReserveBalance: reserveBalanceStr,
 			ReserveDollar:  reserveDollarStr,
 		}
 		reserves.AssetReserve = append(reserves.AssetReserve, assetReserve)
 
-		delta := utils.ToIntByPrecise(reserveDollarStr, this.cfg.TokenDecimal["pUSDT"])
+		delta := utils.ToIntByPrecise(reserveDollarStr, cfg.TokenDecimal["pUSDT"])
 		totalReserve = new(big.Int).Add(totalReserve, delta)
 	}
-	reserves.TotalReserve = utils.ToStringByPrecise(totalReserve, this.cfg.TokenDecimal["pUSDT"])
+	reserves.TotalReserve = utils.ToStringByPrecise(totalReserve, cfg.TokenDecimal["pUSDT"])
 	return reserves, nil
 }
@@ -0,0 +1,147 @@
+package flashpool
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+)
+
+// marketRegistry keeps the listed markets with their meta and insurance address in memory, so that
+// manager methods do not pre-exec allMarkets on every call
+type marketRegistry struct {
+	sync.RWMutex
+	refreshLock sync.Mutex
+	loaded      bool
+	markets     []common.Address
+	meta        map[common.Address]*MarketMeta
+	insurance   map[common.Address]common.Address
+	portions    map[common.Address]*WingSBIPortion
+}
+
+func newMarketRegistry() *marketRegistry {
+	return &marketRegistry{
+		markets:   make([]common.Address, 0),
+		meta:      make(map[common.Address]*MarketMeta),
+		insurance: make(map[common.Address]common.Address),
+		portions:  make(map[common.Address]*WingSBIPortion),
+	}
+}
+
+// RefreshMarkets reloads the markets from the flash pool contract and returns the newly listed ones
+func (this *FlashPoolManager) RefreshMarkets() ([]common.Address, error) {
+	this.registry.refreshLock.Lock()
+	defer this.registry.refreshLock.Unlock()
+	allMarkets, err := this.fetchAllMarkets()
+	if err != nil {
+		return nil, fmt.Errorf("RefreshMarkets, this.fetchAllMarkets error: %s", err)
+	}
+	meta := make(map[common.Address]*MarketMeta)
+	insurance := make(map[common.Address]common.Address)
+	portions := make(map[common.Address]*WingSBIPortion)
+	for _, address := range allMarkets {
+		marketMeta, err := this.getMarketMeta(address)
+		if err != nil {
+			return nil, fmt.Errorf("RefreshMarkets, this.getMarketMeta error: %s", err)
+		}
+		meta[address] = marketMeta
+		insuranceAddress, err := this.fetchInsuranceAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("RefreshMarkets, this.fetchInsuranceAddress error: %s", err)
+		}
+		insurance[address] = insuranceAddress
+		portion, err := this.getWingSBIPortion(address)
+		if err != nil {
+			return nil, fmt.Errorf("RefreshMarkets, this.getWingSBIPortion error: %s", err)
+		}
+		portions[address] = portion
+	}
+
+	return this.registry.update(allMarkets, meta, insurance, portions), nil
+}
+
+// GetAllMarkets returns the cached market list, loading it on first use
+func (this *FlashPoolManager) GetAllMarkets() ([]common.Address, error) {
+	if !this.registry.isLoaded() {
+		if _, err := this.RefreshMarkets(); err != nil {
+			return nil, fmt.Errorf("GetAllMarkets, this.RefreshMarkets error: %s", err)
+		}
+	}
+	return this.registry.allMarkets(), nil
+}
+
+func (this *FlashPoolManager) GetInsuranceAddress(contractAddress common.Address) (common.Address, error) {
+	if insuranceAddress, ok := this.registry.insuranceOf(contractAddress); ok {
+		return insuranceAddress, nil
+	}
+	return this.fetchInsuranceAddress(contractAddress)
+}
+
+// wingSBIPortion returns the wing portions of market as of the last refresh of the registry
+func (this *FlashPoolManager) wingSBIPortion(market common.Address) (*WingSBIPortion, error) {
+	if portion, ok := this.registry.portionOf(market); ok {
+		return portion, nil
+	}
+	return this.getWingSBIPortion(market)
+}
+
+func (this *FlashPoolManager) marketMeta(market common.Address) (*MarketMeta, error) {
+	if marketMeta, ok := this.registry.metaOf(market); ok {
+		return marketMeta, nil
+	}
+	return this.getMarketMeta(market)
+}
+
+// update replaces the markets and returns those listed since the previous update, none on the first one
+func (this *marketRegistry) update(markets []common.Address, meta map[common.Address]*MarketMeta,
+	insurance map[common.Address]common.Address, portions map[common.Address]*WingSBIPortion) []common.Address {
+	this.Lock()
+	defer this.Unlock()
+	added := make([]common.Address, 0)
+	for _, address := range markets {
+		if _, ok := this.meta[address]; !ok && this.loaded {
+			added = append(added, address)
+		}
+	}
+	this.markets = markets
+	this.meta = meta
+	this.insurance = insurance
+	this.portions = portions
+	this.loaded = true
+	return added
+}
+
+func (this *marketRegistry) isLoaded() bool {
+	this.RLock()
+	defer this.RUnlock()
+	return this.loaded
+}
+
+func (this *marketRegistry) allMarkets() []common.Address {
+	this.RLock()
+	defer this.RUnlock()
+	markets := make([]common.Address, len(this.markets))
+	copy(markets, this.markets)
+	return markets
+}
+
+func (this *marketRegistry) insuranceOf(market common.Address) (common.Address, bool) {
+	this.RLock()
+	defer this.RUnlock()
+	insuranceAddress, ok := this.insurance[market]
+	return insuranceAddress, ok
+}
+
+func (this *marketRegistry) metaOf(market common.Address) (*MarketMeta, bool) {
+	this.RLock()
+	defer this.RUnlock()
+	marketMeta, ok := this.meta[market]
+	return marketMeta, ok
+}
+
+func (this *marketRegistry) portionOf(market common.Address) (*WingSBIPortion, bool) {
+	this.RLock()
+	defer this.RUnlock()
+	portion, ok := this.portions[market]
+	return portion, ok
+}
@@ -0,0 +1,100 @@
+package flashpool
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+)
+
+func testMarkets(n int) ([]common.Address, map[common.Address]*MarketMeta, map[common.Address]common.Address,
+	map[common.Address]*WingSBIPortion) {
+	markets := make([]common.Address, 0, n)
+	meta := make(map[common.Address]*MarketMeta)
+	insurance := make(map[common.Address]common.Address)
+	portions := make(map[common.Address]*WingSBIPortion)
+	for i := 0; i < n; i++ {
+		var market, insuranceAddress common.Address
+		market[0], insuranceAddress[0] = byte(i+1), byte(i+101)
+		markets = append(markets, market)
+		meta[market] = &MarketMeta{Addr: market, InsuranceAddr: insuranceAddress}
+		insurance[market] = insuranceAddress
+		portions[market] = &WingSBIPortion{SupplyPortion: common.I128FromUint64(uint64(i + 1))}
+	}
+	return markets, meta, insurance, portions
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	registry := newMarketRegistry()
+	if registry.isLoaded() {
+		t.Fatalf("new registry should not be loaded")
+	}
+	markets, meta, insurance, portions := testMarkets(2)
+	if added := registry.update(markets, meta, insurance, portions); len(added) != 0 {
+		t.Errorf("first update should not report added markets: %v", added)
+	}
+	if !registry.isLoaded() || len(registry.allMarkets()) != 2 {
+		t.Errorf("unexpected markets %v", registry.allMarkets())
+	}
+
+	markets, meta, insurance, portions = testMarkets(3)
+	added := registry.update(markets, meta, insurance, portions)
+	if len(added) != 1 || added[0] != markets[2] {
+		t.Errorf("unexpected added markets %v", added)
+	}
+	if added := registry.update(markets, meta, insurance, portions); len(added) != 0 {
+		t.Errorf("unchanged markets reported as added: %v", added)
+	}
+	if insuranceAddress, ok := registry.insuranceOf(markets[2]); !ok || insuranceAddress != insurance[markets[2]] {
+		t.Errorf("unexpected insurance %v %v", insuranceAddress, ok)
+	}
+	if marketMeta, ok := registry.metaOf(markets[1]); !ok || marketMeta != meta[markets[1]] {
+		t.Errorf("unexpected meta %v %v", marketMeta, ok)
+	}
+	if portion, ok := registry.portionOf(markets[1]); !ok || portion != portions[markets[1]] {
+		t.Errorf("unexpected portion %v %v", portion, ok)
+	}
+
+	// a delisted market is dropped
+	markets, meta, insurance, portions = testMarkets(1)
+	registry.update(markets, meta, insurance, portions)
+	if _, ok := registry.metaOf(common.Address{3}); ok || len(registry.allMarkets()) != 1 {
+		t.Errorf("delisted market still in the registry")
+	}
+
+	// the list handed out is a copy
+	list := registry.allMarkets()
+	list[0] = common.Address{}
+	if registry.allMarkets()[0] != markets[0] {
+		t.Errorf("registry changed through a returned list")
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	registry := newMarketRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.update(testMarkets(n + j%3))
+			}
+		}(i + 1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for _, v := range registry.allMarkets() {
+					registry.metaOf(v)
+					registry.insuranceOf(v)
+					registry.portionOf(v)
+				}
+				registry.isLoaded()
+			}
+		}()
+	}
+	wg.Wait()
+	if !registry.isLoaded() {
+		t.Errorf("registry should be loaded")
+	}
+}
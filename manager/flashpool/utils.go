@@ -2,15 +2,32 @@ package flashpool
 
 import (
 	"fmt"
-	"github.com/ontio/ontology/common"
 	"math/big"
+	"time"
+
+	sdkcom "github.com/ontio/ontology-go-sdk/common"
+	"github.com/ontio/ontology/common"
+	wcommon "github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/metrics"
+	"github.com/siovanus/wingServer/utils"
 )
 
+var sideWeightPrecision = new(big.Int).SetUint64(1000000000000000000)
+
+// preExecInvoke pre-executes a contract method, timed in the contract call metrics of the method
+func (this *FlashPoolManager) preExecInvoke(contractAddress common.Address, method string,
+	params []interface{}) (*sdkcom.PreExecResult, error) {
+	start := time.Now()
+	result, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress, method, params)
+	metrics.ObserveContractCall(method, start, err)
+	return result, err
+}
+
 func (this *FlashPoolManager) assetPrice(asset string) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.oracleAddress,
+	preExecResult, err := this.preExecInvoke(this.oracleAddress,
 		"getUnderlyingPrice", []interface{}{asset})
 	if err != nil {
-		return nil, fmt.Errorf("assetPrice, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("assetPrice, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToInteger()
 	if err != nil {
@@ -19,26 +36,26 @@ func (this *FlashPoolManager) assetPrice(asset string) (*big.Int, error) {
 	return r, nil
 }
 
-func (this *FlashPoolManager) GetAllMarkets() ([]common.Address, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress,
+func (this *FlashPoolManager) fetchAllMarkets() ([]common.Address, error) {
+	preExecResult, err := this.preExecInvoke(this.contractAddress,
 		"allMarkets", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getAllMarkets, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("fetchAllMarkets, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
-		return nil, fmt.Errorf("getAllMarkets, preExecResult.Result.ToByteArray error: %s", err)
+		return nil, fmt.Errorf("fetchAllMarkets, preExecResult.Result.ToByteArray error: %s", err)
 	}
 	source := common.NewZeroCopySource(r)
 	allMarkets := make([]common.Address, 0)
 	l, _, irregular, eof := source.NextVarUint()
 	if irregular || eof {
-		return nil, fmt.Errorf("getAllMarkets, source.NextVarUint error")
+		return nil, fmt.Errorf("fetchAllMarkets, source.NextVarUint error")
 	}
 	for i := 0; uint64(i) < l; i++ {
 		addr, eof := source.NextAddress()
 		if eof {
-			return nil, fmt.Errorf("getAllMarkets, source.NextAddress error")
+			return nil, fmt.Errorf("fetchAllMarkets, source.NextAddress error")
 		}
 		allMarkets = append(allMarkets, addr)
 	}
@@ -46,10 +63,10 @@ func (this *FlashPoolManager) GetAllMarkets() ([]common.Address, error) {
 }
 
 func (this *FlashPoolManager) getAssetsIn(account common.Address) ([]common.Address, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress,
+	preExecResult, err := this.preExecInvoke(this.contractAddress,
 		"assetsIn", []interface{}{account})
 	if err != nil {
-		return nil, fmt.Errorf("getAssetsIn, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getAssetsIn, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -72,10 +89,10 @@ func (this *FlashPoolManager) getAssetsIn(account common.Address) ([]common.Addr
 }
 
 func (this *FlashPoolManager) getSupplyAmountByAccount(contractAddress, account common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"balanceOfUnderlying", []interface{}{account})
 	if err != nil {
-		return nil, fmt.Errorf("getSupplyAmountByAccount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getSupplyAmountByAccount, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -90,10 +107,10 @@ func (this *FlashPoolManager) getSupplyAmountByAccount(contractAddress, account
 }
 
 func (this *FlashPoolManager) getBorrowAmountByAccount(contractAddress, account common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"borrowBalanceStored", []interface{}{account})
 	if err != nil {
-		return nil, fmt.Errorf("getBorrowAmountByAccount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getBorrowAmountByAccount, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -108,10 +125,10 @@ func (this *FlashPoolManager) getBorrowAmountByAccount(contractAddress, account
 }
 
 func (this *FlashPoolManager) getInsuranceAmountByAccount(contractAddress, account common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"insuranceAddr", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getInsuranceAmountByAccount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getInsuranceAmountByAccount, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -122,10 +139,10 @@ func (this *FlashPoolManager) getInsuranceAmountByAccount(contractAddress, accou
 		return nil, fmt.Errorf("getInsuranceAmountByAccount, common.AddressParseFromBytes error: %s", err)
 	}
 
-	preExecResult, err = this.sdk.WasmVM.PreExecInvokeWasmVMContract(insuranceAddress,
+	preExecResult, err = this.preExecInvoke(insuranceAddress,
 		"balanceOfUnderlying", []interface{}{account})
 	if err != nil {
-		return nil, fmt.Errorf("getInsuranceAmountByAccount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getInsuranceAmountByAccount, this.preExecInvoke error: %s", err)
 	}
 	r, err = preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -154,10 +171,10 @@ func (this *FlashPoolManager) getSupplyAmount(contractAddress common.Address) (*
 }
 
 func (this *FlashPoolManager) getCash(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"getCash", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getCash, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getCash, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -172,10 +189,10 @@ func (this *FlashPoolManager) getCash(contractAddress common.Address) (*big.Int,
 }
 
 func (this *FlashPoolManager) getBorrowAmount(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"totalBorrows", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getBorrowAmount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getBorrowAmount, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -190,10 +207,10 @@ func (this *FlashPoolManager) getBorrowAmount(contractAddress common.Address) (*
 }
 
 func (this *FlashPoolManager) getTotalReserves(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"totalReserves", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getTotalReserves, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getTotalReserves, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -213,10 +230,10 @@ func (this *FlashPoolManager) getInsuranceAmount(contractAddress common.Address)
 		return nil, fmt.Errorf("getInsuranceAmount, this.getInsuranceAddress error: %s", err)
 	}
 
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(insuranceAddress,
+	preExecResult, err := this.preExecInvoke(insuranceAddress,
 		"getCash", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getInsuranceAmount, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getInsuranceAmount, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -231,10 +248,10 @@ func (this *FlashPoolManager) getInsuranceAmount(contractAddress common.Address)
 }
 
 func (this *FlashPoolManager) getTotalDistribution(assetAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress,
+	preExecResult, err := this.preExecInvoke(this.contractAddress,
 		"wingDistributedNum", []interface{}{assetAddress})
 	if err != nil {
-		return nil, fmt.Errorf("getTotalDistribution, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getTotalDistribution, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -245,7 +262,7 @@ func (this *FlashPoolManager) getTotalDistribution(assetAddress common.Address)
 	if eof {
 		return nil, fmt.Errorf("getTotalDistribution, source.NextI128 error")
 	}
-	if this.cfg.AssetMap[assetAddress.ToHexString()] == "pWBTC" {
+	if this.cfg.Get().AssetMap[assetAddress.ToHexString()] == "pWBTC" {
 		return new(big.Int).Sub(amount.ToBigInt(), GAP), nil
 	} else {
 		return amount.ToBigInt(), nil
@@ -253,10 +270,10 @@ func (this *FlashPoolManager) getTotalDistribution(assetAddress common.Address)
 }
 
 func (this *FlashPoolManager) getReserveFactor(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"reserveFactorMantissa", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getReserveFactor, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getReserveFactor, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -271,10 +288,10 @@ func (this *FlashPoolManager) getReserveFactor(contractAddress common.Address) (
 }
 
 func (this *FlashPoolManager) getSupplyApy(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"supplyRatePerBlock", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getSupplyApy, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getSupplyApy, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -290,10 +307,10 @@ func (this *FlashPoolManager) getSupplyApy(contractAddress common.Address) (*big
 }
 
 func (this *FlashPoolManager) getBorrowRatePerBlock(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"borrowRatePerBlock", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getBorrowRatePerBlock, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getBorrowRatePerBlock, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -309,10 +326,10 @@ func (this *FlashPoolManager) getBorrowRatePerBlock(contractAddress common.Addre
 }
 
 func (this *FlashPoolManager) getBorrowApy(contractAddress common.Address) (*big.Int, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"borrowRatePerBlock", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getBorrowApy, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getBorrowApy, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -327,19 +344,19 @@ func (this *FlashPoolManager) getBorrowApy(contractAddress common.Address) (*big
 	return result, nil
 }
 
-func (this *FlashPoolManager) GetInsuranceAddress(contractAddress common.Address) (common.Address, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress,
+func (this *FlashPoolManager) fetchInsuranceAddress(contractAddress common.Address) (common.Address, error) {
+	preExecResult, err := this.preExecInvoke(contractAddress,
 		"insuranceAddr", []interface{}{})
 	if err != nil {
-		return common.ADDRESS_EMPTY, fmt.Errorf("getInsuranceAddress, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return common.ADDRESS_EMPTY, fmt.Errorf("fetchInsuranceAddress, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
-		return common.ADDRESS_EMPTY, fmt.Errorf("getInsuranceAddress, preExecResult.Result.ToByteArray error: %s", err)
+		return common.ADDRESS_EMPTY, fmt.Errorf("fetchInsuranceAddress, preExecResult.Result.ToByteArray error: %s", err)
 	}
 	insuranceAddress, err := common.AddressParseFromBytes(r)
 	if err != nil {
-		return common.ADDRESS_EMPTY, fmt.Errorf("getInsuranceAddress, common.AddressParseFromBytes error: %s", err)
+		return common.ADDRESS_EMPTY, fmt.Errorf("fetchInsuranceAddress, common.AddressParseFromBytes error: %s", err)
 	}
 	return insuranceAddress, nil
 }
@@ -350,10 +367,10 @@ func (this *FlashPoolManager) GetInsuranceAddress(contractAddress common.Address
 //		return nil, fmt.Errorf("getInsuranceApy, this.getInsuranceAddress error: %s", err)
 //	}
 //
-//	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(insuranceAddress,
+//	preExecResult, err := this.preExecInvoke(insuranceAddress,
 //		"supplyRatePerBlock", []interface{}{})
 //	if err != nil {
-//		return nil, fmt.Errorf("getInsuranceApy, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+//		return nil, fmt.Errorf("getInsuranceApy, this.preExecInvoke error: %s", err)
 //	}
 //	r, err := preExecResult.Result.ToByteArray()
 //	if err != nil {
@@ -424,7 +441,7 @@ func DeserializeMarketMeta(data []byte) (*MarketMeta, error) {
 func (this *FlashPoolManager) getMarketMeta(market common.Address) (*MarketMeta, error) {
 	method := "marketMeta"
 	params := []interface{}{market}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
 		return nil, fmt.Errorf("MarketMeta: %s", err)
 	}
@@ -473,7 +490,7 @@ func DeserializeAccountLiquidity(data []byte) (*AccountLiquidity, error) {
 func (this *FlashPoolManager) getAccountLiquidity(account common.Address) (*AccountLiquidity, error) {
 	method := "getAccountLiquidity"
 	params := []interface{}{account}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
 		return nil, fmt.Errorf("GetAccountLiquidity: %s", err)
 	}
@@ -491,9 +508,9 @@ func (this *FlashPoolManager) getAccountLiquidity(account common.Address) (*Acco
 func (this *FlashPoolManager) getWingAccrued(account common.Address) (*big.Int, error) {
 	method := "wingAccrued"
 	params := []interface{}{account}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("getWingAccrued, this.sdk.WasmVM.PreExecInvokeWasmVMContract: %s", err)
+		return nil, fmt.Errorf("getWingAccrued, this.preExecInvoke error: %s", err)
 	}
 	return res.Result.ToInteger()
 }
@@ -501,9 +518,9 @@ func (this *FlashPoolManager) getWingAccrued(account common.Address) (*big.Int,
 func (this *FlashPoolManager) getClaimWing(holder common.Address) (*big.Int, error) {
 	method := "claimWing"
 	params := []interface{}{holder}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("ClaimWing, this.sdk.WasmVM.PreExecInvokeWasmVMContract: %s", err)
+		return nil, fmt.Errorf("ClaimWing, this.preExecInvoke error: %s", err)
 	}
 	data, err := res.Result.ToByteArray()
 	if err != nil {
@@ -522,9 +539,9 @@ func (this *FlashPoolManager) getClaimWing(holder common.Address) (*big.Int, err
 func (this *FlashPoolManager) getWingSpeeds(contractAddress common.Address) (*big.Int, error) {
 	method := "wingSpeeds"
 	params := []interface{}{contractAddress}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("getWingSpeeds, this.sdk.WasmVM.PreExecInvokeWasmVMContract: %s", err)
+		return nil, fmt.Errorf("getWingSpeeds, this.preExecInvoke error: %s", err)
 	}
 	data, err := res.Result.ToByteArray()
 	if err != nil {
@@ -570,9 +587,9 @@ func DeserializeWingSBIPortion(data []byte) (*WingSBIPortion, error) {
 func (this *FlashPoolManager) getWingSBIPortion(contractAddress common.Address) (*WingSBIPortion, error) {
 	method := "wingSBIPortion"
 	params := []interface{}{contractAddress}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("getWingSBIPortion, this.sdk.WasmVM.PreExecInvokeWasmVMContract: %s", err)
+		return nil, fmt.Errorf("getWingSBIPortion, this.preExecInvoke error: %s", err)
 	}
 	data, err := res.Result.ToByteArray()
 	if err != nil {
@@ -589,9 +606,9 @@ func (this *FlashPoolManager) getWingSBIPortion(contractAddress common.Address)
 func (this *FlashPoolManager) getClaimWingAtMarket(account common.Address, contractAddresses []interface{}) (*big.Int, error) {
 	method := "claimWingAtMarkets"
 	params := []interface{}{account, contractAddresses}
-	res, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress, method, params)
+	res, err := this.preExecInvoke(this.contractAddress, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("getClaimWingAtMarket, this.sdk.WasmVM.PreExecInvokeWasmVMContract: %s", err)
+		return nil, fmt.Errorf("getClaimWingAtMarket, this.preExecInvoke error: %s", err)
 	}
 	data, err := res.Result.ToByteArray()
 	if err != nil {
@@ -606,3 +623,102 @@ func (this *FlashPoolManager) getClaimWingAtMarket(account common.Address, contr
 	}
 	return r.ToBigInt(), nil
 }
+
+// marketWingEarned splits the wing accrued by account at market between supply, borrow and insurance,
+// weighting every side by its wing SBI portion and the share of the side held by account now. The contract
+// only tells the total accrued, so the split is an approximation of WING_SPLIT_BALANCE_SHARE: it is exact
+// only when the balances and the portions did not change since the last claim
+func (this *FlashPoolManager) marketWingEarned(account, contractAddress common.Address, market *wcommon.Market,
+	supplyAmount, borrowAmount, insuranceAmount *big.Int) (*big.Int, *big.Int, *big.Int, *big.Int, error) {
+	cfg := this.cfg.Get()
+	total, err := this.getClaimWingAtMarket(account, []interface{}{contractAddress})
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("marketWingEarned, this.getClaimWingAtMarket account %s asset %s error: %s",
+			account.ToBase58(), contractAddress.ToHexString(), err)
+	}
+	if total.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int), total, nil
+	}
+	wingSBIPortion, err := this.wingSBIPortion(contractAddress)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("marketWingEarned, this.wingSBIPortion error: %s", err)
+	}
+	name := cfg.AssetMap[contractAddress.ToHexString()]
+	supplyWeight := sideWeight(wingSBIPortion.SupplyPortion.ToBigInt(), supplyAmount,
+		utils.ToIntByPrecise(market.TotalSupplyAmount, cfg.TokenDecimal[name]))
+	borrowWeight := sideWeight(wingSBIPortion.BorrowPortion.ToBigInt(), borrowAmount,
+		utils.ToIntByPrecise(market.TotalBorrowAmount, cfg.TokenDecimal[name]))
+	insuranceWeight := sideWeight(wingSBIPortion.InsurancePortion.ToBigInt(), insuranceAmount,
+		utils.ToIntByPrecise(market.TotalInsuranceAmount, cfg.TokenDecimal[name]))
+	supply, borrow, insurance := splitWingEarned(total, supplyWeight, borrowWeight, insuranceWeight)
+	return supply, borrow, insurance, total, nil
+}
+
+// splitWingEarned splits total in proportion to the weights of the sides, the rounding goes to insurance.
+// Nothing is attributed when no side has a weight, as when account left the market
+func splitWingEarned(total, supplyWeight, borrowWeight, insuranceWeight *big.Int) (*big.Int, *big.Int, *big.Int) {
+	totalWeight := new(big.Int).Add(supplyWeight, new(big.Int).Add(borrowWeight, insuranceWeight))
+	if totalWeight.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+	supply := new(big.Int).Div(new(big.Int).Mul(total, supplyWeight), totalWeight)
+	borrow := new(big.Int).Div(new(big.Int).Mul(total, borrowWeight), totalWeight)
+	insurance := new(big.Int).Sub(new(big.Int).Sub(total, supply), borrow)
+	return supply, borrow, insurance
+}
+
+// sideWeight is the portion of a side times the share of the side held by an account of amount
+func sideWeight(portion, amount, totalAmount *big.Int) *big.Int {
+	if totalAmount.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(new(big.Int).Mul(new(big.Int).Mul(portion, amount), sideWeightPrecision), totalAmount)
+}
+
+// positionApy holds the dollars of an account in a market with the precision of pUSDT and the apys of the
+// market with the precision of flash
+type positionApy struct {
+	supplyDollar, borrowDollar, insuranceDollar          *big.Int
+	supplyApy, borrowApy, insuranceApy                   *big.Int
+	supplyRewardApy, borrowRewardApy, insuranceRewardApy *big.Int
+}
+
+// netYield sums the dollar weighted apys of the positions of an account. Wing rewards are earned on every
+// side, so they are added to supply and insurance and reduce the borrow cost
+type netYield struct {
+	supplied    *big.Int
+	yield       *big.Int
+	withRewards *big.Int
+}
+
+func newNetYield() *netYield {
+	return &netYield{supplied: new(big.Int), yield: new(big.Int), withRewards: new(big.Int)}
+}
+
+func (this *netYield) add(position *positionApy) {
+	this.supplied.Add(this.supplied, new(big.Int).Add(position.supplyDollar, position.insuranceDollar))
+	earned := new(big.Int).Add(new(big.Int).Mul(position.supplyDollar, position.supplyApy),
+		new(big.Int).Mul(position.insuranceDollar, position.insuranceApy))
+	paid := new(big.Int).Mul(position.borrowDollar, position.borrowApy)
+	this.yield.Add(this.yield, new(big.Int).Sub(earned, paid))
+	earned = new(big.Int).Add(
+		new(big.Int).Mul(position.supplyDollar, new(big.Int).Add(position.supplyApy, position.supplyRewardApy)),
+		new(big.Int).Mul(position.insuranceDollar, new(big.Int).Add(position.insuranceApy, position.insuranceRewardApy)))
+	paid = new(big.Int).Mul(position.borrowDollar, new(big.Int).Sub(position.borrowApy, position.borrowRewardApy))
+	this.withRewards.Add(this.withRewards, new(big.Int).Sub(earned, paid))
+}
+
+// apys returns the net apy and the net apy with rewards over the supplied and insured dollars, false when
+// nothing is supplied nor insured
+func (this *netYield) apys() (*big.Int, *big.Int, bool) {
+	if this.supplied.Sign() == 0 {
+		return nil, nil, false
+	}
+	return new(big.Int).Div(this.yield, this.supplied), new(big.Int).Div(this.withRewards, this.supplied), true
+}
+
+// yearlyYield returns the dollars earned per year with rewards, with the precision of the dollars times
+// the one of the apys
+func (this *netYield) yearlyYield() *big.Int {
+	return new(big.Int).Set(this.withRewards)
+}
@@ -0,0 +1,113 @@
+package flashpool
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSideWeight(t *testing.T) {
+	cases := []struct {
+		portion, amount, totalAmount int64
+		expected                     *big.Int
+	}{
+		{portion: 5, amount: 10, totalAmount: 0, expected: new(big.Int)},
+		{portion: 5, amount: 0, totalAmount: 100, expected: new(big.Int)},
+		{portion: 0, amount: 10, totalAmount: 100, expected: new(big.Int)},
+		// half of a side of portion 4 weighs 2
+		{portion: 4, amount: 50, totalAmount: 100, expected: new(big.Int).Mul(big.NewInt(2), sideWeightPrecision)},
+	}
+	for _, v := range cases {
+		weight := sideWeight(big.NewInt(v.portion), big.NewInt(v.amount), big.NewInt(v.totalAmount))
+		if weight.Cmp(v.expected) != 0 {
+			t.Errorf("sideWeight(%d, %d, %d) = %s, expected %s", v.portion, v.amount, v.totalAmount, weight, v.expected)
+		}
+	}
+}
+
+func TestSplitWingEarned(t *testing.T) {
+	cases := map[string]struct {
+		total, supplyWeight, borrowWeight, insuranceWeight int64
+		supply, borrow, insurance                          int64
+	}{
+		"supply only": {total: 1000, supplyWeight: 7, supply: 1000},
+		"even": {total: 1000, supplyWeight: 1, borrowWeight: 1, insuranceWeight: 2,
+			supply: 250, borrow: 250, insurance: 500},
+		"rounding": {total: 100, supplyWeight: 1, borrowWeight: 1, insuranceWeight: 1,
+			supply: 33, borrow: 33, insurance: 34},
+		"no position left": {total: 1000},
+	}
+	for name, v := range cases {
+		supply, borrow, insurance := splitWingEarned(big.NewInt(v.total), big.NewInt(v.supplyWeight),
+			big.NewInt(v.borrowWeight), big.NewInt(v.insuranceWeight))
+		if supply.Int64() != v.supply || borrow.Int64() != v.borrow || insurance.Int64() != v.insurance {
+			t.Errorf("%s: unexpected split %s %s %s", name, supply, borrow, insurance)
+		}
+	}
+}
+
+func TestNetYield(t *testing.T) {
+	// dollars and apys without precision, the apys of the results keep the precision of the inputs
+	cases := map[string]struct {
+		positions                 []*positionApy
+		netApy, netApyWithRewards int64
+		supplied                  bool
+		yearlyYield               int64
+	}{
+		"supply only": {
+			positions: []*positionApy{
+				position(1000, 0, 0, 5, 10, 3, 2, 1, 1),
+				position(3000, 0, 0, 1, 10, 3, 1, 1, 1),
+			},
+			netApy: 2, netApyWithRewards: 3, supplied: true, yearlyYield: 1000*7 + 3000*2,
+		},
+		"borrow heavy": {
+			positions: []*positionApy{
+				position(1000, 0, 0, 2, 0, 0, 1, 0, 0),
+				position(0, 5000, 0, 0, 8, 0, 0, 3, 0),
+			},
+			netApy: -38, netApyWithRewards: -22, supplied: true, yearlyYield: 1000*3 - 5000*5,
+		},
+		"insurance": {
+			positions: []*positionApy{position(0, 0, 2000, 0, 0, 4, 0, 0, 1)},
+			netApy:    4, netApyWithRewards: 5, supplied: true, yearlyYield: 2000 * 5,
+		},
+		"zero balance": {
+			positions: []*positionApy{position(0, 0, 0, 5, 10, 3, 2, 1, 1)},
+		},
+		"borrow only": {
+			positions:   []*positionApy{position(0, 100, 0, 0, 10, 0, 0, 4, 0)},
+			yearlyYield: -600,
+		},
+	}
+	for name, v := range cases {
+		yield := newNetYield()
+		for _, p := range v.positions {
+			yield.add(p)
+		}
+		netApy, netApyWithRewards, ok := yield.apys()
+		if ok != v.supplied {
+			t.Errorf("%s: unexpected supplied %v", name, ok)
+		}
+		if ok && (netApy.Int64() != v.netApy || netApyWithRewards.Int64() != v.netApyWithRewards) {
+			t.Errorf("%s: unexpected apys %s %s", name, netApy, netApyWithRewards)
+		}
+		if yearlyYield := yield.yearlyYield(); yearlyYield.Int64() != v.yearlyYield {
+			t.Errorf("%s: unexpected yearly yield %s, expected %d", name, yearlyYield, v.yearlyYield)
+		}
+	}
+}
+
+func position(supplyDollar, borrowDollar, insuranceDollar, supplyApy, borrowApy, insuranceApy, supplyRewardApy,
+	borrowRewardApy, insuranceRewardApy int64) *positionApy {
+	return &positionApy{
+		supplyDollar:       big.NewInt(supplyDollar),
+		borrowDollar:       big.NewInt(borrowDollar),
+		insuranceDollar:    big.NewInt(insuranceDollar),
+		supplyApy:          big.NewInt(supplyApy),
+		borrowApy:          big.NewInt(borrowApy),
+		insuranceApy:       big.NewInt(insuranceApy),
+		supplyRewardApy:    big.NewInt(supplyRewardApy),
+		borrowRewardApy:    big.NewInt(borrowRewardApy),
+		insuranceRewardApy: big.NewInt(insuranceRewardApy),
+	}
+}
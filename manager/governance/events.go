@@ -0,0 +1,154 @@
+package governance
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/utils"
+)
+
+const (
+	KindPropose = "propose"
+	KindVote    = "vote"
+	KindExecute = "execute"
+	KindCancel  = "cancel"
+
+	FieldProposalId  = "proposal_id"
+	FieldProposer    = "proposer"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldStartHeight = "start_height"
+	FieldEndHeight   = "end_height"
+	FieldVoter       = "voter"
+	FieldOption      = "option"
+	FieldWeight      = "weight"
+)
+
+// fields each kind needs to be indexed
+var requiredFields = map[string][]string{
+	KindPropose: {FieldProposalId, FieldProposer},
+	KindVote:    {FieldProposalId, FieldVoter, FieldOption, FieldWeight},
+	KindExecute: {FieldProposalId},
+	KindCancel:  {FieldProposalId},
+}
+
+// proposalEvent holds the fields of a governance notification, those absent from its layout are empty
+type proposalEvent struct {
+	kind        string
+	proposalId  string
+	proposer    string
+	title       string
+	description string
+	startHeight uint32
+	endHeight   uint32
+	voter       string
+	option      string
+	weight      *big.Int
+}
+
+// decodeProposalEvent decodes the states of a governance notification after its name by layout. Neovm
+// contracts notify every value as hex bytes, wasm contracts notify strings, base58 addresses and decimal
+// numbers
+func decodeProposalEvent(layout *config.GovernanceEvent, states []interface{}, neovm bool) (*proposalEvent, error) {
+	required, ok := requiredFields[layout.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %s", layout.Kind)
+	}
+	if len(states) < len(layout.Fields)+1 {
+		return nil, fmt.Errorf("%d states for the %d fields of the layout", len(states)-1, len(layout.Fields))
+	}
+	values := make(map[string]interface{}, len(layout.Fields))
+	for i, field := range layout.Fields {
+		if field != "" {
+			values[field] = states[i+1]
+		}
+	}
+	for _, field := range required {
+		if _, ok := values[field]; !ok {
+			return nil, fmt.Errorf("the layout of %s has no %s", layout.Kind, field)
+		}
+	}
+
+	event := &proposalEvent{kind: layout.Kind}
+	for field, state := range values {
+		var err error
+		switch field {
+		case FieldProposalId:
+			var id *big.Int
+			id, err = utils.ParseAmount(state, neovm)
+			if err == nil {
+				event.proposalId = id.String()
+			}
+		case FieldProposer:
+			event.proposer, err = parseAddress(state, neovm)
+		case FieldTitle:
+			event.title, err = parseText(state, neovm)
+		case FieldDescription:
+			event.description, err = parseText(state, neovm)
+		case FieldStartHeight:
+			event.startHeight, err = parseHeight(state, neovm)
+		case FieldEndHeight:
+			event.endHeight, err = parseHeight(state, neovm)
+		case FieldVoter:
+			event.voter, err = parseAddress(state, neovm)
+		case FieldOption:
+			event.option, err = parseText(state, neovm)
+		case FieldWeight:
+			event.weight, err = utils.ParseAmount(state, neovm)
+		default:
+			err = fmt.Errorf("unknown field")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", field, err)
+		}
+	}
+	return event, nil
+}
+
+// parseText reads a string notified by a neovm contract as hex bytes or by a wasm contract as it is
+func parseText(state interface{}, neovm bool) (string, error) {
+	text := utils.ParseString(state)
+	if !neovm {
+		return text, nil
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex %s", text)
+	}
+	return string(data), nil
+}
+
+// parseAddress returns the base58 form of an address notified by a neovm contract as hex bytes or by a wasm
+// contract in base58
+func parseAddress(state interface{}, neovm bool) (string, error) {
+	text := utils.ParseString(state)
+	if !neovm {
+		if _, err := common.AddressFromBase58(text); err != nil {
+			return "", fmt.Errorf("invalid address %s", text)
+		}
+		return text, nil
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex %s", text)
+	}
+	address, err := common.AddressParseFromBytes(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s", text)
+	}
+	return address.ToBase58(), nil
+}
+
+func parseHeight(state interface{}, neovm bool) (uint32, error) {
+	height, err := utils.ParseAmount(state, neovm)
+	if err != nil {
+		return 0, err
+	}
+	if !height.IsUint64() || height.Uint64() > uint64(^uint32(0)) {
+		return 0, fmt.Errorf("invalid height %s", height)
+	}
+	return uint32(height.Uint64()), nil
+}
@@ -0,0 +1,104 @@
+package governance
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/config"
+)
+
+const testVoter = "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+
+var (
+	proposeLayout = &config.GovernanceEvent{Kind: KindPropose, Fields: []string{FieldProposalId, FieldProposer,
+		FieldTitle, "", FieldStartHeight, FieldEndHeight}}
+	voteLayout = &config.GovernanceEvent{Kind: KindVote, Fields: []string{FieldProposalId, FieldVoter,
+		FieldOption, FieldWeight}}
+)
+
+// neovmStates encodes values as a neovm contract notifies them, every value as hex bytes
+func neovmStates(t *testing.T, values ...interface{}) []interface{} {
+	states := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		switch value := v.(type) {
+		case int64:
+			states = append(states, hex.EncodeToString(common.BigIntToNeoBytes(big.NewInt(value))))
+		case common.Address:
+			states = append(states, hex.EncodeToString(value[:]))
+		case string:
+			states = append(states, hex.EncodeToString([]byte(value)))
+		default:
+			t.Fatalf("unsupported value %T", v)
+		}
+	}
+	return states
+}
+
+func TestDecodeProposalEventWasm(t *testing.T) {
+	states := []interface{}{"proposal", "12", testVoter, "raise pUSDC portion", "ignored", "1000", "2000"}
+	event, err := decodeProposalEvent(proposeLayout, states, false)
+	if err != nil {
+		t.Fatalf("decodeProposalEvent error: %s", err)
+	}
+	if event.kind != KindPropose || event.proposalId != "12" || event.proposer != testVoter ||
+		event.title != "raise pUSDC portion" || event.description != "" || event.startHeight != 1000 ||
+		event.endHeight != 2000 {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	states = []interface{}{"vote", "12", testVoter, "yes", "123456789012345678901"}
+	event, err = decodeProposalEvent(voteLayout, states, false)
+	if err != nil {
+		t.Fatalf("decodeProposalEvent error: %s", err)
+	}
+	if event.voter != testVoter || event.option != "yes" || event.weight.String() != "123456789012345678901" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestDecodeProposalEventNeovm(t *testing.T) {
+	voter, err := common.AddressFromBase58(testVoter)
+	if err != nil {
+		t.Fatalf("common.AddressFromBase58 error: %s", err)
+	}
+	states := neovmStates(t, "vote", int64(300), voter, "no", int64(5000000000))
+	event, err := decodeProposalEvent(voteLayout, states, true)
+	if err != nil {
+		t.Fatalf("decodeProposalEvent error: %s", err)
+	}
+	if event.proposalId != "300" || event.voter != testVoter || event.option != "no" ||
+		event.weight.Int64() != 5000000000 {
+		t.Errorf("unexpected event %+v", event)
+	}
+	name, err := parseText(states[0], true)
+	if err != nil || name != "vote" {
+		t.Errorf("unexpected name %s %v", name, err)
+	}
+	// read as wasm, the hex amounts are no decimals
+	if _, err := decodeProposalEvent(voteLayout, states, false); err == nil {
+		t.Errorf("neovm states decoded as wasm")
+	}
+}
+
+func TestDecodeProposalEventInvalid(t *testing.T) {
+	cases := map[string]struct {
+		layout *config.GovernanceEvent
+		states []interface{}
+	}{
+		"short":         {voteLayout, []interface{}{"vote", "12", testVoter, "yes"}},
+		"bad address":   {voteLayout, []interface{}{"vote", "12", "voter", "yes", "1"}},
+		"bad height":    {proposeLayout, []interface{}{"proposal", "12", testVoter, "t", "d", "1", "4294967296"}},
+		"unknown kind":  {&config.GovernanceEvent{Kind: "veto"}, []interface{}{"veto"}},
+		"missing field": {&config.GovernanceEvent{Kind: KindExecute}, []interface{}{"execute", "12"}},
+		"unknown field": {&config.GovernanceEvent{Kind: KindCancel, Fields: []string{FieldProposalId, "reason"}},
+			[]interface{}{"cancel", "12", "spam"}},
+		"no proposal id": {voteLayout, []interface{}{"vote", "", testVoter, "yes", "1"}},
+	}
+	for name, v := range cases {
+		if event, err := decodeProposalEvent(v.layout, v.states, false); err == nil {
+			t.Errorf("%s: expected an error, got %+v", name, event)
+		}
+	}
+}
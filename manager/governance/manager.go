@@ -6,11 +6,19 @@ import (
 	ocommon "github.com/ontio/ontology/common"
 	"github.com/siovanus/wingServer/config"
 	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
 	"github.com/siovanus/wingServer/utils"
+	"math"
 	"math/big"
+	"sort"
 	"time"
 )
 
+const (
+	MaxProposalLimit = 100
+)
+
 const (
 	Total      = 10000000000000000
 	Total80    = 8000000
@@ -25,80 +33,231 @@ var DistributeTime = []uint64{3 * DaySecond, 5 * DaySecond, 5 * DaySecond, 5 * D
 	YearSecond, YearSecond, YearSecond, YearSecond, YearSecond, 4256064}
 
 type GovernanceManager struct {
-	cfg             *config.Config
+	cfg             *config.Holder
 	contractAddress ocommon.Address
 	wingAddress     string
 	sdk             *sdk.OntologySdk
+	store           *store.Client
 }
 
-func NewGovernanceManager(contractAddress ocommon.Address, wingAddress string, sdk *sdk.OntologySdk, cfg *config.Config) *GovernanceManager {
+func NewGovernanceManager(contractAddress ocommon.Address, wingAddress string, sdk *sdk.OntologySdk,
+	store *store.Client, cfg *config.Holder) *GovernanceManager {
 	manager := &GovernanceManager{
 		cfg:             cfg,
 		contractAddress: contractAddress,
 		wingAddress:     wingAddress,
 		sdk:             sdk,
+		store:           store,
 	}
 
 	return manager
 }
 
 func (this *GovernanceManager) GovBannerOverview() (*common.GovBannerOverview, error) {
-	gap := uint64(time.Now().Unix()) - GenesisTime
-	length := len(DailyDistibute)
-	epoch := []uint64{0}
-	for i := 1; i < length+1; i++ {
-		epoch = append(epoch, epoch[i-1]+DistributeTime[i-1])
-	}
-	if gap > epoch[length] {
-		gap = epoch[length]
-	}
-	index := 0
-	for i := 0; i < len(epoch); i++ {
-		if gap >= epoch[i] {
-			index = i
-		}
-	}
-	var distributed uint64 = 0
-	for j := 0; j < index; j++ {
-		distributed += DailyDistibute[j] * DistributeTime[j]
-	}
-	distributed += (gap - epoch[index]) * DailyDistibute[index]
+	distributed, _ := getDistributed(uint64(time.Now().Unix()))
 
 	balance, err := this.getBalanceOf("AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo")
 	if err != nil {
 		return nil, fmt.Errorf("GovBannerOverview, this.getBalanceOf error: %s", err)
 	}
-	remain80 := Total80*100 - distributed
+	remain80 := getRemain80(distributed)
 	return &common.GovBannerOverview{
-		Remain20: utils.ToStringByPrecise(new(big.Int).SetUint64(balance), this.cfg.TokenDecimal["WING"]),
+		Remain20: utils.ToStringByPrecise(new(big.Int).SetUint64(balance), this.cfg.Get().TokenDecimal["WING"]),
 		Remain80: utils.ToStringByPrecise(new(big.Int).SetUint64(remain80), 2),
 	}, nil
 }
 
 func (this *GovernanceManager) GovBanner() (*common.GovBanner, error) {
-	gap := uint64(time.Now().Unix()) - GenesisTime
-	length := len(DailyDistibute)
-	epoch := []uint64{0}
-	for i := 1; i < length+1; i++ {
-		epoch = append(epoch, epoch[i-1]+DistributeTime[i-1])
-	}
-	if gap > epoch[length] {
-		gap = epoch[length]
-	}
-	index := 0
-	for i := 0; i < len(epoch); i++ {
-		if gap >= epoch[i] {
-			index = i
-		}
-	}
-	var distributed uint64 = 0
-	for j := 0; j < index; j++ {
-		distributed += DailyDistibute[j] * DistributeTime[j]
-	}
-	distributed += (gap - epoch[index]) * DailyDistibute[index]
+	distributed, rate := getDistributed(uint64(time.Now().Unix()))
 
 	return &common.GovBanner{
-		Daily:       utils.ToStringByPrecise(new(big.Int).SetUint64(DailyDistibute[index]*DaySecond), 2),
+		Daily:       utils.ToStringByPrecise(new(big.Int).SetUint64(rate*DaySecond), 2),
 		Distributed: utils.ToStringByPrecise(new(big.Int).SetUint64(distributed), 2),
 	}, nil
 }
+
+func (this *GovernanceManager) EmissionProjection(interval string,
+	wingSpeeds []*common.WingSpeed) (*common.EmissionProjection, error) {
+	weights, err := parseWingSpeeds(wingSpeeds)
+	if err != nil {
+		return nil, fmt.Errorf("EmissionProjection, parseWingSpeeds error: %s", err)
+	}
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == common.PROJECTION_INTERVAL_MONTH {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	endTime := getEndTime()
+	projection := &common.EmissionProjection{
+		Interval:    interval,
+		EndTime:     endTime,
+		TotalAmount: utils.ToStringByPrecise(new(big.Int).SetUint64(Total80*100), 2),
+		Projection:  make([]*common.EmissionPoint, 0),
+	}
+	for t := start; ; {
+		timestamp := uint64(t.Unix())
+		if timestamp > endTime {
+			timestamp = endTime
+		}
+		distributed, rate := getDistributed(timestamp)
+		point := &common.EmissionPoint{
+			Timestamp:   timestamp,
+			Distributed: utils.ToStringByPrecise(new(big.Int).SetUint64(distributed), 2),
+			DailyRate:   utils.ToStringByPrecise(new(big.Int).SetUint64(rate*DaySecond), 2),
+			Remaining:   utils.ToStringByPrecise(new(big.Int).SetUint64(getRemain80(distributed)), 2),
+		}
+		if len(weights) != 0 {
+			point.Markets = splitDaily(rate*DaySecond, weights)
+		}
+		projection.Projection = append(projection.Projection, point)
+		if timestamp == endTime {
+			break
+		}
+		if interval == common.PROJECTION_INTERVAL_MONTH {
+			t = t.AddDate(0, 1, 0)
+		} else {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return projection, nil
+}
+
+// ProposalEventForStore indexes a notification of the governance contract by the layout configured for its
+// name, neovm tells whether the contract notifies as neovm. Notifications without a layout are logged
+func (this *GovernanceManager) ProposalEventForStore(txHash string, height uint32, states []interface{},
+	neovm bool) error {
+	name, err := parseText(states[0], neovm)
+	if err != nil {
+		return fmt.Errorf("ProposalEventForStore, parseText name in tx %s error: %s", txHash, err)
+	}
+	var layout *config.GovernanceEvent
+	if governance := this.cfg.Get().Governance; governance != nil {
+		layout = governance.Events[name]
+	}
+	if layout == nil {
+		log.Warnf("ProposalEventForStore, no layout for governance event %s in tx %s", name, txHash)
+		return nil
+	}
+	event, err := decodeProposalEvent(layout, states, neovm)
+	if err != nil {
+		return fmt.Errorf("ProposalEventForStore, decodeProposalEvent %s in tx %s error: %s", name, txHash, err)
+	}
+	switch event.kind {
+	case KindPropose:
+		govProposal := &store.GovProposal{
+			ProposalId:   event.proposalId,
+			Proposer:     event.proposer,
+			Title:        event.title,
+			Description:  event.description,
+			StartHeight:  event.startHeight,
+			EndHeight:    event.endHeight,
+			Status:       common.PROPOSAL_STATUS_ACTIVE,
+			CreateHeight: height,
+			CreateTxHash: txHash,
+			UpdateHeight: height,
+			UpdateTxHash: txHash,
+		}
+		err = this.store.SaveGovProposal(govProposal)
+		if err != nil {
+			return fmt.Errorf("ProposalEventForStore, this.store.SaveGovProposal error: %s", err)
+		}
+	case KindVote:
+		govVote := &store.GovVote{
+			ProposalId: event.proposalId,
+			Voter:      event.voter,
+			Option:     event.option,
+			Weight:     event.weight.String(),
+			Height:     height,
+			TxHash:     txHash,
+		}
+		err = this.store.SaveGovVote(govVote)
+		if err != nil {
+			return fmt.Errorf("ProposalEventForStore, this.store.SaveGovVote error: %s", err)
+		}
+	case KindExecute, KindCancel:
+		status := common.PROPOSAL_STATUS_EXECUTED
+		if event.kind == KindCancel {
+			status = common.PROPOSAL_STATUS_CANCELED
+		}
+		err = this.store.UpdateGovProposalStatus(event.proposalId, status, height, txHash)
+		if err != nil {
+			return fmt.Errorf("ProposalEventForStore, this.store.UpdateGovProposalStatus error: %s", err)
+		}
+	}
+	return nil
+}
+
+func (this *GovernanceManager) GovProposals(status string, offset, limit uint64) (*common.GovProposals, error) {
+	if limit == 0 || limit > MaxProposalLimit {
+		limit = MaxProposalLimit
+	}
+	proposals, count, err := this.store.LoadGovProposals(status, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GovProposals, this.store.LoadGovProposals error: %s", err)
+	}
+	govProposals := &common.GovProposals{
+		Total:     count,
+		Proposals: make([]*common.GovProposal, 0),
+	}
+	for i := range proposals {
+		govProposals.Proposals = append(govProposals.Proposals, toGovProposal(&proposals[i]))
+	}
+	return govProposals, nil
+}
+
+func (this *GovernanceManager) GovProposalDetail(proposalId string) (*common.GovProposalDetail, error) {
+	cfg := this.cfg.Get()
+	proposal, err := this.store.LoadGovProposal(proposalId)
+	if err != nil {
+		return nil, fmt.Errorf("GovProposalDetail, this.store.LoadGovProposal error: %s", err)
+	}
+	votes, err := this.store.LoadGovVotes(proposalId)
+	if err != nil {
+		return nil, fmt.Errorf("GovProposalDetail, this.store.LoadGovVotes error: %s", err)
+	}
+	govProposalDetail := &common.GovProposalDetail{
+		Proposal: toGovProposal(&proposal),
+		Tallies:  make([]*common.VoteTally, 0),
+		Votes:    make([]*common.GovVote, 0),
+	}
+	tallies := make(map[string]*big.Int)
+	voteCount := make(map[string]uint64)
+	options := make([]string, 0)
+	total := new(big.Int)
+	for _, v := range votes {
+		weight, ok := new(big.Int).SetString(v.Weight, 10)
+		if !ok {
+			return nil, fmt.Errorf("GovProposalDetail, invalid vote weight %s of %s", v.Weight, v.Voter)
+		}
+		if _, ok := tallies[v.Option]; !ok {
+			tallies[v.Option] = new(big.Int)
+			options = append(options, v.Option)
+		}
+		tallies[v.Option] = new(big.Int).Add(tallies[v.Option], weight)
+		voteCount[v.Option]++
+		total = new(big.Int).Add(total, weight)
+		govProposalDetail.Votes = append(govProposalDetail.Votes, &common.GovVote{
+			Voter:  v.Voter,
+			Option: v.Option,
+			Weight: utils.ToStringByPrecise(weight, cfg.TokenDecimal["WING"]),
+			Height: v.Height,
+			TxHash: v.TxHash,
+		})
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		share := new(big.Int)
+		if total.Sign() != 0 {
+			share = new(big.Int).Div(new(big.Int).Mul(tallies[option], new(big.Int).SetUint64(
+				uint64(math.Pow10(int(cfg.TokenDecimal["percentage"]))))), total)
+		}
+		govProposalDetail.Tallies = append(govProposalDetail.Tallies, &common.VoteTally{
+			Option:    option,
+			VoteCount: voteCount[option],
+			Weight:    utils.ToStringByPrecise(tallies[option], cfg.TokenDecimal["WING"]),
+			Share:     utils.ToStringByPrecise(share, cfg.TokenDecimal["percentage"]),
+		})
+	}
+	govProposalDetail.TotalWeight = utils.ToStringByPrecise(total, cfg.TokenDecimal["WING"])
+	return govProposalDetail, nil
+}
@@ -60,3 +60,22 @@ func TestRemain80(t *testing.T) {
 	remain80 := Total80*100 - distributed
 	fmt.Println(utils.ToStringByPrecise(new(big.Int).SetUint64(remain80), 2))
 }
+
+func TestEmissionSchedule(t *testing.T) {
+	end := getEndTime()
+	total, rate := getDistributed(end)
+	if rate != 0 {
+		t.Fatalf("rate at schedule end should be 0, got %d", rate)
+	}
+	if getRemain80(total) != 0 {
+		t.Fatalf("remain80 at schedule end should be 0, got %d", getRemain80(total))
+	}
+	distributed, rate := getDistributed(GenesisTime + DaySecond)
+	if rate != DailyDistibute[0] || distributed != DailyDistibute[0]*DaySecond {
+		t.Fatalf("unexpected distribution after one day: %d, rate %d", distributed, rate)
+	}
+	before, _ := getDistributed(end - DaySecond)
+	if before >= total {
+		t.Fatalf("distribution should keep growing until schedule end")
+	}
+}
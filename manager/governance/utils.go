@@ -2,10 +2,42 @@ package governance
 
 import (
 	"fmt"
-	"github.com/ontio/ontology/common"
 	"math/big"
+	"time"
+
+	sdkcom "github.com/ontio/ontology-go-sdk/common"
+	"github.com/ontio/ontology/common"
+	wcommon "github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/metrics"
+	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/utils"
 )
 
+// preExecInvoke pre-executes a contract method, timed in the contract call metrics of the method
+func (this *GovernanceManager) preExecInvoke(contractAddress common.Address, method string,
+	params []interface{}) (*sdkcom.PreExecResult, error) {
+	start := time.Now()
+	result, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(contractAddress, method, params)
+	metrics.ObserveContractCall(method, start, err)
+	return result, err
+}
+
+func toGovProposal(proposal *store.GovProposal) *wcommon.GovProposal {
+	return &wcommon.GovProposal{
+		ProposalId:   proposal.ProposalId,
+		Proposer:     proposal.Proposer,
+		Title:        proposal.Title,
+		Description:  proposal.Description,
+		StartHeight:  proposal.StartHeight,
+		EndHeight:    proposal.EndHeight,
+		Status:       proposal.Status,
+		CreateHeight: proposal.CreateHeight,
+		CreateTxHash: proposal.CreateTxHash,
+		UpdateHeight: proposal.UpdateHeight,
+		UpdateTxHash: proposal.UpdateTxHash,
+	}
+}
+
 // get wing total supply
 func (this *GovernanceManager) getWingTotalSupply() (*big.Int, error) {
 	r, err := this.sdk.GetStorage(this.wingAddress, []byte("TotalSupply"))
@@ -53,10 +85,10 @@ func (this *Pool) Deserialization(source *common.ZeroCopySource) error {
 }
 
 func (this *GovernanceManager) getAllPools() ([]*Pool, error) {
-	preExecResult, err := this.sdk.WasmVM.PreExecInvokeWasmVMContract(this.contractAddress,
+	preExecResult, err := this.preExecInvoke(this.contractAddress,
 		"get_product_pools", []interface{}{})
 	if err != nil {
-		return nil, fmt.Errorf("getAllPool, this.sdk.WasmVM.PreExecInvokeWasmVMContract error: %s", err)
+		return nil, fmt.Errorf("getAllPool, this.preExecInvoke error: %s", err)
 	}
 	r, err := preExecResult.Result.ToByteArray()
 	if err != nil {
@@ -78,3 +110,104 @@ func (this *GovernanceManager) getAllPools() ([]*Pool, error) {
 	}
 	return allPools, nil
 }
+
+// getDistributed returns the WING distributed by the schedule at timestamp and
+// the distribution rate per second at that moment, both with precision 2
+func getDistributed(timestamp uint64) (uint64, uint64) {
+	var gap uint64 = 0
+	if timestamp > GenesisTime {
+		gap = timestamp - GenesisTime
+	}
+	length := len(DailyDistibute)
+	epoch := []uint64{0}
+	for i := 1; i < length+1; i++ {
+		epoch = append(epoch, epoch[i-1]+DistributeTime[i-1])
+	}
+	var distributed uint64 = 0
+	for j := 0; j < length; j++ {
+		if gap < epoch[j+1] {
+			distributed += (gap - epoch[j]) * DailyDistibute[j]
+			return distributed, DailyDistibute[j]
+		}
+		distributed += DailyDistibute[j] * DistributeTime[j]
+	}
+	return distributed, 0
+}
+
+// getRemain80 returns the part of the 80% distribution not released yet, with precision 2
+func getRemain80(distributed uint64) uint64 {
+	if distributed >= Total80*100 {
+		return 0
+	}
+	return Total80*100 - distributed
+}
+
+// getEndTime returns the timestamp at which the distribution schedule ends
+func getEndTime() uint64 {
+	end := GenesisTime
+	for _, v := range DistributeTime {
+		end += v
+	}
+	return end
+}
+
+type marketWeight struct {
+	name             string
+	speed            *big.Int
+	supplyPortion    *big.Int
+	borrowPortion    *big.Int
+	insurancePortion *big.Int
+}
+
+func parseWingSpeeds(wingSpeeds []*wcommon.WingSpeed) ([]*marketWeight, error) {
+	weights := make([]*marketWeight, 0, len(wingSpeeds))
+	for _, v := range wingSpeeds {
+		weight := &marketWeight{name: v.Name}
+		var ok bool
+		if weight.speed, ok = new(big.Int).SetString(v.WingSpeed, 10); !ok {
+			return nil, fmt.Errorf("parseWingSpeeds, invalid wing speed %s of %s", v.WingSpeed, v.Name)
+		}
+		if weight.supplyPortion, ok = new(big.Int).SetString(v.SupplyPortion, 10); !ok {
+			return nil, fmt.Errorf("parseWingSpeeds, invalid supply portion %s of %s", v.SupplyPortion, v.Name)
+		}
+		if weight.borrowPortion, ok = new(big.Int).SetString(v.BorrowPortion, 10); !ok {
+			return nil, fmt.Errorf("parseWingSpeeds, invalid borrow portion %s of %s", v.BorrowPortion, v.Name)
+		}
+		if weight.insurancePortion, ok = new(big.Int).SetString(v.InsurancePortion, 10); !ok {
+			return nil, fmt.Errorf("parseWingSpeeds, invalid insurance portion %s of %s", v.InsurancePortion, v.Name)
+		}
+		weights = append(weights, weight)
+	}
+	return weights, nil
+}
+
+// splitDaily splits the daily distribution (precision 2) between markets in proportion of
+// their current wing speed, then between supply, borrow and insurance by wing SBI portion
+func splitDaily(daily uint64, weights []*marketWeight) []*wcommon.MarketEmission {
+	totalSpeed := new(big.Int)
+	for _, v := range weights {
+		totalSpeed = new(big.Int).Add(totalSpeed, v.speed)
+	}
+	result := make([]*wcommon.MarketEmission, 0, len(weights))
+	for _, v := range weights {
+		marketDaily := new(big.Int)
+		if totalSpeed.Sign() != 0 {
+			marketDaily = new(big.Int).Div(new(big.Int).Mul(new(big.Int).SetUint64(daily), v.speed), totalSpeed)
+		}
+		totalPortion := new(big.Int).Add(v.supplyPortion, new(big.Int).Add(v.borrowPortion, v.insurancePortion))
+		supplyDaily, borrowDaily, insuranceDaily := new(big.Int), new(big.Int), new(big.Int)
+		if totalPortion.Sign() != 0 {
+			supplyDaily = new(big.Int).Div(new(big.Int).Mul(marketDaily, v.supplyPortion), totalPortion)
+			borrowDaily = new(big.Int).Div(new(big.Int).Mul(marketDaily, v.borrowPortion), totalPortion)
+			insuranceDaily = new(big.Int).Div(new(big.Int).Mul(marketDaily, v.insurancePortion), totalPortion)
+		}
+		result = append(result, &wcommon.MarketEmission{
+			Name:           v.name,
+			Daily:          utils.ToStringByPrecise(marketDaily, 2),
+			SupplyDaily:    utils.ToStringByPrecise(supplyDaily, 2),
+			BorrowDaily:    utils.ToStringByPrecise(borrowDaily, 2),
+			InsuranceDaily: utils.ToStringByPrecise(insuranceDaily, 2),
+		})
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/log"
+)
+
+// AdminRoute is an admin endpoint to mount on a server together with its method
+type AdminRoute struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+}
+
+// AdminRoutes returns the webhook admin endpoints, to mount on the admin server which authorizes them
+func (this *WebhookManager) AdminRoutes() []*AdminRoute {
+	return []*AdminRoute{
+		{Method: http.MethodGet, Path: common.ADMINWEBHOOKS, Handler: this.handleList},
+		{Method: http.MethodPost, Path: common.ADMINWEBHOOKS, Handler: this.handleRegister},
+		{Method: http.MethodDelete, Path: common.ADMINWEBHOOK, Handler: this.handleRemove},
+		{Method: http.MethodGet, Path: common.ADMINWEBHOOKDEADLETTERS, Handler: this.handleDeadLetters},
+		{Method: http.MethodPost, Path: common.ADMINWEBHOOKDEADLETTER, Handler: this.handleRedeliver},
+	}
+}
+
+// handleList lists the webhooks without their secret, which is only returned when registering
+func (this *WebhookManager) handleList(w http.ResponseWriter, r *http.Request) {
+	webhooks := this.Webhooks()
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	restful.WriteResponse(w, http.StatusOK, restful.SUCCESS, "", webhooks)
+}
+
+func (this *WebhookManager) handleRegister(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	req := &common.WebhookRequest{}
+	if err == nil {
+		err = json.Unmarshal(body, req)
+	}
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.ILLEGAL_DATAFORMAT, err.Error(), nil)
+		return
+	}
+	webhook, err := this.Register(req)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, err.Error(), nil)
+		return
+	}
+	log.Infof("webhook %d registered, trigger: %s, url: %s", webhook.Id, webhook.Trigger, webhook.Url)
+	restful.WriteResponse(w, http.StatusCreated, restful.SUCCESS, "", webhook)
+}
+
+func (this *WebhookManager) handleRemove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(restful.Param(r, "id"), 10, 64)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, err.Error(), nil)
+		return
+	}
+	ok, err := this.Remove(id)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusInternalServerError, restful.INTERNAL_ERROR, err.Error(), nil)
+		return
+	}
+	if !ok {
+		restful.WriteResponse(w, http.StatusNotFound, restful.INVALID_PARAMS, "webhook not found", nil)
+		return
+	}
+	log.Infof("webhook %d removed", id)
+	restful.WriteResponse(w, http.StatusOK, restful.SUCCESS, "", nil)
+}
+
+func (this *WebhookManager) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
+	deadLetters, err := this.DeadLetters(limit)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusInternalServerError, restful.INTERNAL_ERROR, err.Error(), nil)
+		return
+	}
+	restful.WriteResponse(w, http.StatusOK, restful.SUCCESS, "", deadLetters)
+}
+
+func (this *WebhookManager) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(restful.Param(r, "id"), 10, 64)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, err.Error(), nil)
+		return
+	}
+	err = this.Redeliver(id)
+	if err != nil {
+		restful.WriteResponse(w, http.StatusBadRequest, restful.INVALID_PARAMS, err.Error(), nil)
+		return
+	}
+	restful.WriteResponse(w, http.StatusAccepted, restful.SUCCESS, "", nil)
+}
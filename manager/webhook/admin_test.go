@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/http/restful"
+	"github.com/siovanus/wingServer/store"
+)
+
+func serveAdmin(manager *WebhookManager, method, path, body string) (*httptest.ResponseRecorder, *common.Response) {
+	router := restful.NewRouter()
+	for _, v := range manager.AdminRoutes() {
+		router.Handle(v.Method, v.Path, v.Handler)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	resp := &common.Response{}
+	json.Unmarshal(w.Body.Bytes(), resp)
+	return w, resp
+}
+
+func TestAdminList(t *testing.T) {
+	manager := newTestManager(t, nil, store.Webhook{Id: 1, Url: "https://example.com/hook", Secret: "secret",
+		Trigger: common.WEBHOOK_TRIGGER_LIQUIDATION})
+	w, _ := serveAdmin(manager, http.MethodGet, common.ADMINWEBHOOKS, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret") || !strings.Contains(w.Body.String(), "https://example.com/hook") {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+	if manager.Webhooks()[0].Secret != "secret" {
+		t.Errorf("listing cleared the secret of the webhook")
+	}
+}
+
+func TestAdminRegister(t *testing.T) {
+	manager := newTestManager(t, nil)
+	w, resp := serveAdmin(manager, http.MethodPost, common.ADMINWEBHOOKS,
+		`{"Url":"https://example.com/hook","Trigger":"borrow_limit","Account":"`+testAccount+`","Threshold":"0.8"}`)
+	if w.Code != http.StatusCreated || resp.Error != restful.SUCCESS {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	// the generated secret is returned once, at registration
+	webhook := resp.Result.(map[string]interface{})
+	if secret, _ := webhook["Secret"].(string); len(secret) != 64 || len(manager.Webhooks()) != 1 {
+		t.Errorf("unexpected webhook %v", webhook)
+	}
+
+	for _, body := range []string{
+		`{`,
+		`{"Url":"ftp://example.com","Trigger":"liquidation"}`,
+		`{"Url":"https://example.com/hook","Trigger":"borrow_limit","Account":"` + testAccount + `"}`,
+		`{"Url":"https://example.com/hook","Trigger":"price_move","Threshold":"0.05"}`,
+		`{"Url":"https://example.com/hook","Trigger":"unknown"}`,
+	} {
+		if w, _ := serveAdmin(manager, http.MethodPost, common.ADMINWEBHOOKS, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d", body, w.Code)
+		}
+	}
+}
+
+func TestAdminRemove(t *testing.T) {
+	manager := newTestManager(t, nil, store.Webhook{Id: 1, Trigger: common.WEBHOOK_TRIGGER_LIQUIDATION})
+	for _, v := range []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/admin/webhooks/a", http.StatusBadRequest},
+		{"/api/v1/admin/webhooks/2", http.StatusNotFound},
+		{"/api/v1/admin/webhooks/1", http.StatusOK},
+		{"/api/v1/admin/webhooks/1", http.StatusNotFound},
+	} {
+		if w, _ := serveAdmin(manager, http.MethodDelete, v.path, ""); w.Code != v.status {
+			t.Errorf("%s: status %d, expected %d", v.path, w.Code, v.status)
+		}
+	}
+	if len(manager.Webhooks()) != 0 {
+		t.Errorf("webhook not removed")
+	}
+}
+
+func TestAdminRedeliver(t *testing.T) {
+	manager := newTestManager(t, nil, store.Webhook{Id: 1, Url: "https://example.com/hook", Secret: "secret",
+		Trigger: common.WEBHOOK_TRIGGER_LIQUIDATION})
+	manager.store.(*fakeStore).deadLetters = []store.WebhookDeadLetter{
+		{Id: 1, WebhookId: 1, DeliveryId: "d1", Payload: "{}"},
+		{Id: 2, WebhookId: 2, DeliveryId: "d2", Payload: "{}"},
+	}
+	w, _ := serveAdmin(manager, http.MethodPost, "/api/v1/admin/webhookdeadletters/1", "")
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status %d", w.Code)
+	}
+	select {
+	case delivery := <-manager.dispatcher.queue:
+		if delivery.Id != "d1" || delivery.Secret != "secret" {
+			t.Errorf("unexpected delivery %+v", delivery)
+		}
+	default:
+		t.Errorf("dead letter not queued")
+	}
+	// the webhook of the second is removed, and the third does not exist
+	for _, path := range []string{"/api/v1/admin/webhookdeadletters/2", "/api/v1/admin/webhookdeadletters/3"} {
+		if w, _ := serveAdmin(manager, http.MethodPost, path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/log"
+)
+
+const (
+	HeaderSignature = "X-Wing-Signature"
+	HeaderTimestamp = "X-Wing-Timestamp"
+	HeaderDelivery  = "X-Wing-Delivery"
+
+	queueSize = 1024
+)
+
+type Delivery struct {
+	Id        string
+	WebhookId uint64
+	Url       string
+	Secret    string
+	Payload   []byte
+	Attempts  uint32
+	LastError string
+}
+
+// Dispatcher posts deliveries from a queue, failed deliveries are retried with exponential backoff
+// and handed to deadLetter once maxAttempts is reached
+type Dispatcher struct {
+	client      *http.Client
+	maxAttempts uint32
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	deadLetter  func(*Delivery)
+	queue       chan *Delivery
+	quit        chan struct{}
+	wg          sync.WaitGroup
+	// retries waiting for their backoff, cancelled by Stop
+	lock    sync.Mutex
+	timers  map[*time.Timer]bool
+	stopped bool
+}
+
+func NewDispatcher(timeout time.Duration, maxAttempts uint32, backoff time.Duration, deadLetter func(*Delivery)) *Dispatcher {
+	return &Dispatcher{
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  backoff << 10,
+		deadLetter:  deadLetter,
+		queue:       make(chan *Delivery, queueSize),
+		quit:        make(chan struct{}),
+		timers:      make(map[*time.Timer]bool),
+	}
+}
+
+func (this *Dispatcher) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		this.wg.Add(1)
+		go this.work()
+	}
+}
+
+// Stop waits for deliveries in flight, queued deliveries and pending retries are dropped
+func (this *Dispatcher) Stop() {
+	this.lock.Lock()
+	this.stopped = true
+	for timer := range this.timers {
+		timer.Stop()
+	}
+	this.timers = nil
+	this.lock.Unlock()
+	close(this.quit)
+	this.wg.Wait()
+}
+
+func (this *Dispatcher) Enqueue(delivery *Delivery) {
+	select {
+	case this.queue <- delivery:
+	default:
+		delivery.LastError = "delivery queue is full"
+		this.deadLetter(delivery)
+	}
+}
+
+func (this *Dispatcher) work() {
+	defer this.wg.Done()
+	for {
+		select {
+		case <-this.quit:
+			return
+		case delivery := <-this.queue:
+			err := this.post(delivery)
+			if err == nil {
+				continue
+			}
+			delivery.Attempts++
+			delivery.LastError = err.Error()
+			if delivery.Attempts >= this.maxAttempts {
+				log.Errorf("Dispatcher.work, delivery %s to %s failed after %d attempts: %s", delivery.Id,
+					delivery.Url, delivery.Attempts, err)
+				this.deadLetter(delivery)
+				continue
+			}
+			log.Warnf("Dispatcher.work, delivery %s to %s failed, retry: %s", delivery.Id, delivery.Url, err)
+			this.retry(delivery)
+		}
+	}
+}
+
+// retry queues delivery again after its backoff, unless the dispatcher is stopped in the meantime
+func (this *Dispatcher) retry(delivery *Delivery) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.stopped {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(this.retryDelay(delivery.Attempts), func() {
+		this.lock.Lock()
+		pending := this.timers[timer]
+		delete(this.timers, timer)
+		this.lock.Unlock()
+		if pending {
+			this.Enqueue(delivery)
+		}
+	})
+	this.timers[timer] = true
+}
+
+func (this *Dispatcher) retryDelay(attempts uint32) time.Duration {
+	delay := this.backoff << (attempts - 1)
+	if delay > this.maxBackoff || delay <= 0 {
+		delay = this.maxBackoff
+	}
+	return delay
+}
+
+func (this *Dispatcher) post(delivery *Delivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("post, http.NewRequest error: %s", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post, this.client.Do error: %s", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("post, receiver responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature a receiver should find in X-Wing-Signature, a hex HMAC-SHA256 keyed
+// by the webhook secret over the X-Wing-Timestamp header, a dot and the raw body
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcherDelivery(t *testing.T) {
+	payload := []byte(`{"Trigger":"liquidation"}`)
+	received := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != string(payload) {
+			t.Errorf("unexpected body %s", body)
+		}
+		if r.Header.Get(HeaderSignature) != Sign("secret", r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("invalid signature %s", r.Header.Get(HeaderSignature))
+		}
+		if r.Header.Get(HeaderDelivery) != "1" {
+			t.Errorf("unexpected delivery id %s", r.Header.Get(HeaderDelivery))
+		}
+		received <- true
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(time.Second, 3, time.Millisecond, func(d *Delivery) {
+		t.Errorf("delivery %s dead lettered: %s", d.Id, d.LastError)
+	})
+	dispatcher.Start(1)
+	defer dispatcher.Stop()
+	dispatcher.Enqueue(&Delivery{Id: "1", Url: server.URL, Secret: "secret", Payload: payload})
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not received")
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	var calls int32
+	received := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received <- true
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(time.Second, 3, time.Millisecond, func(d *Delivery) {
+		t.Errorf("delivery %s dead lettered: %s", d.Id, d.LastError)
+	})
+	dispatcher.Start(1)
+	defer dispatcher.Stop()
+	dispatcher.Enqueue(&Delivery{Id: "1", Url: server.URL, Payload: []byte("{}")})
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not retried")
+	}
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	deadLetters := make(chan *Delivery, 1)
+	dispatcher := NewDispatcher(time.Second, 3, time.Millisecond, func(d *Delivery) {
+		deadLetters <- d
+	})
+	dispatcher.Start(2)
+	defer dispatcher.Stop()
+	dispatcher.Enqueue(&Delivery{Id: "1", Url: server.URL, Payload: []byte("{}")})
+	select {
+	case d := <-deadLetters:
+		if d.Attempts != 3 || atomic.LoadInt32(&calls) != 3 {
+			t.Errorf("dead lettered after %d attempts and %d calls", d.Attempts, calls)
+		}
+		if d.LastError == "" {
+			t.Error("dead letter misses last error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not dead lettered")
+	}
+}
+
+func TestDispatcherStopCancelsRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(time.Second, 3, 200*time.Millisecond, func(d *Delivery) {
+		t.Errorf("delivery %s dead lettered: %s", d.Id, d.LastError)
+	})
+	dispatcher.Start(1)
+	dispatcher.Enqueue(&Delivery{Id: "1", Url: server.URL, Payload: []byte("{}")})
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&calls) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery not posted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// the retry waits for its backoff when the dispatcher stops
+	for {
+		dispatcher.lock.Lock()
+		pending := len(dispatcher.timers)
+		dispatcher.lock.Unlock()
+		if pending == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("retry not scheduled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	dispatcher.Stop()
+	time.Sleep(400 * time.Millisecond)
+	if len(dispatcher.queue) != 0 || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("retried after stop, %d queued, %d calls", len(dispatcher.queue), calls)
+	}
+}
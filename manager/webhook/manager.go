@@ -0,0 +1,391 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ocommon "github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	DefaultWorkers      = 4
+	DefaultMaxAttempts  = 6
+	DefaultRetryBackoff = 2
+	DefaultTimeout      = 10
+
+	MaxDeadLetterLimit = 100
+)
+
+// LiquidationSource computes the borrow limit used of an account, implemented by the flash pool manager
+type LiquidationSource interface {
+	LiquidationList(account string) ([]*common.Liquidation, error)
+}
+
+// Store is implemented by store.Client
+type Store interface {
+	LoadWebhooks() ([]store.Webhook, error)
+	SaveWebhook(webhook *store.Webhook) error
+	DeleteWebhook(id uint64) (bool, error)
+	LoadWebhookDeadLetters(limit uint64) ([]store.WebhookDeadLetter, error)
+	LoadWebhookDeadLetter(id uint64) (store.WebhookDeadLetter, error)
+	SaveWebhookDeadLetter(deadLetter *store.WebhookDeadLetter) error
+	DeleteWebhookDeadLetter(id uint64) error
+}
+
+type WebhookManager struct {
+	sync.RWMutex
+	cfg        *config.Holder
+	store      Store
+	source     LiquidationSource
+	dispatcher *Dispatcher
+	webhooks   []store.Webhook
+	// trigger state per webhook: reference price of price_move, whether borrow_limit is above threshold
+	referencePrices map[uint64]float64
+	aboveThreshold  map[uint64]bool
+}
+
+func NewWebhookManager(source LiquidationSource, store Store, cfg *config.Holder) *WebhookManager {
+	webhookConfig := config.WebhookConfig{}
+	if cfg.Get().Webhook != nil {
+		webhookConfig = *cfg.Get().Webhook
+	}
+	if webhookConfig.Workers == 0 {
+		webhookConfig.Workers = DefaultWorkers
+	}
+	if webhookConfig.MaxAttempts == 0 {
+		webhookConfig.MaxAttempts = DefaultMaxAttempts
+	}
+	if webhookConfig.RetryBackoff == 0 {
+		webhookConfig.RetryBackoff = DefaultRetryBackoff
+	}
+	if webhookConfig.Timeout == 0 {
+		webhookConfig.Timeout = DefaultTimeout
+	}
+	webhooks, err := store.LoadWebhooks()
+	if err != nil {
+		log.Errorf("NewWebhookManager, store.LoadWebhooks error: %s", err)
+		return nil
+	}
+	manager := &WebhookManager{
+		cfg:             cfg,
+		store:           store,
+		source:          source,
+		webhooks:        webhooks,
+		referencePrices: make(map[uint64]float64),
+		aboveThreshold:  make(map[uint64]bool),
+	}
+	manager.dispatcher = NewDispatcher(time.Duration(webhookConfig.Timeout)*time.Second, webhookConfig.MaxAttempts,
+		time.Duration(webhookConfig.RetryBackoff)*time.Second, manager.saveDeadLetter)
+	manager.dispatcher.Start(int(webhookConfig.Workers))
+	return manager
+}
+
+func (this *WebhookManager) Close() {
+	this.dispatcher.Stop()
+}
+
+func (this *WebhookManager) Webhooks() []store.Webhook {
+	this.RLock()
+	defer this.RUnlock()
+	webhooks := make([]store.Webhook, len(this.webhooks))
+	copy(webhooks, this.webhooks)
+	return webhooks
+}
+
+func (this *WebhookManager) Register(req *common.WebhookRequest) (*store.Webhook, error) {
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Register, invalid url %s", req.Url)
+	}
+	webhook := &store.Webhook{
+		Url:        req.Url,
+		Secret:     req.Secret,
+		Trigger:    req.Trigger,
+		Account:    req.Account,
+		Asset:      req.Asset,
+		Threshold:  req.Threshold,
+		CreateTime: uint64(time.Now().Unix()),
+	}
+	switch req.Trigger {
+	case common.WEBHOOK_TRIGGER_BORROW_LIMIT:
+		if _, err := ocommon.AddressFromBase58(req.Account); err != nil {
+			return nil, fmt.Errorf("Register, invalid account %s", req.Account)
+		}
+		if _, err := parseThreshold(req.Threshold); err != nil {
+			return nil, err
+		}
+	case common.WEBHOOK_TRIGGER_PRICE_MOVE:
+		if req.Asset == "" {
+			return nil, fmt.Errorf("Register, asset is required by %s", req.Trigger)
+		}
+		if _, err := parseThreshold(req.Threshold); err != nil {
+			return nil, err
+		}
+	case common.WEBHOOK_TRIGGER_LIQUIDATION:
+	default:
+		return nil, fmt.Errorf("Register, unknown trigger %s", req.Trigger)
+	}
+	if webhook.Secret == "" {
+		webhook.Secret, err = randomHex(32)
+		if err != nil {
+			return nil, fmt.Errorf("Register, randomHex error: %s", err)
+		}
+	}
+	err = this.store.SaveWebhook(webhook)
+	if err != nil {
+		return nil, fmt.Errorf("Register, this.store.SaveWebhook error: %s", err)
+	}
+	this.Lock()
+	this.webhooks = append(this.webhooks, *webhook)
+	this.Unlock()
+	return webhook, nil
+}
+
+func (this *WebhookManager) Remove(id uint64) (bool, error) {
+	ok, err := this.store.DeleteWebhook(id)
+	if err != nil {
+		return false, fmt.Errorf("Remove, this.store.DeleteWebhook error: %s", err)
+	}
+	this.Lock()
+	defer this.Unlock()
+	for i, v := range this.webhooks {
+		if v.Id == id {
+			this.webhooks = append(this.webhooks[:i], this.webhooks[i+1:]...)
+			break
+		}
+	}
+	delete(this.referencePrices, id)
+	delete(this.aboveThreshold, id)
+	return ok, nil
+}
+
+func (this *WebhookManager) DeadLetters(limit uint64) ([]store.WebhookDeadLetter, error) {
+	if limit == 0 || limit > MaxDeadLetterLimit {
+		limit = MaxDeadLetterLimit
+	}
+	deadLetters, err := this.store.LoadWebhookDeadLetters(limit)
+	if err != nil {
+		return nil, fmt.Errorf("DeadLetters, this.store.LoadWebhookDeadLetters error: %s", err)
+	}
+	return deadLetters, nil
+}
+
+// Redeliver queues a dead letter again, it is dead lettered anew if delivery keeps failing
+func (this *WebhookManager) Redeliver(id uint64) error {
+	deadLetter, err := this.store.LoadWebhookDeadLetter(id)
+	if err != nil {
+		return fmt.Errorf("Redeliver, this.store.LoadWebhookDeadLetter error: %s", err)
+	}
+	var webhook *store.Webhook
+	for _, v := range this.Webhooks() {
+		if v.Id == deadLetter.WebhookId {
+			webhook = &v
+			break
+		}
+	}
+	if webhook == nil {
+		return fmt.Errorf("Redeliver, webhook %d is removed", deadLetter.WebhookId)
+	}
+	err = this.store.DeleteWebhookDeadLetter(id)
+	if err != nil {
+		return fmt.Errorf("Redeliver, this.store.DeleteWebhookDeadLetter error: %s", err)
+	}
+	this.dispatcher.Enqueue(&Delivery{
+		Id:        deadLetter.DeliveryId,
+		WebhookId: webhook.Id,
+		Url:       webhook.Url,
+		Secret:    webhook.Secret,
+		Payload:   []byte(deadLetter.Payload),
+	})
+	return nil
+}
+
+// Notify evaluates the webhook triggers against data written by the service
+func (this *WebhookManager) Notify(topic string, data interface{}) {
+	switch topic {
+	case common.TOPIC_ACCOUNT:
+		if update, ok := data.(*common.AccountUpdate); ok {
+			this.checkBorrowLimit(update.Address)
+		}
+	case common.TOPIC_PRICE:
+		if prices, ok := data.([]*common.Price); ok {
+			this.checkPriceMove(prices)
+		}
+	case common.TOPIC_EVENT:
+		if events, ok := data.([]*store.ProtocolEvent); ok {
+			this.checkLiquidation(events)
+		}
+	}
+}
+
+func (this *WebhookManager) checkBorrowLimit(account string) {
+	var webhooks []store.Webhook
+	for _, v := range this.Webhooks() {
+		if v.Trigger == common.WEBHOOK_TRIGGER_BORROW_LIMIT && v.Account == account {
+			webhooks = append(webhooks, v)
+		}
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+	liquidationList, err := this.source.LiquidationList(account)
+	if err != nil {
+		log.Errorf("checkBorrowLimit, this.source.LiquidationList error: %s", err)
+		return
+	}
+	used := "0"
+	if len(liquidationList) != 0 {
+		used = liquidationList[0].BorrowLimitUsed
+	}
+	usedValue, err := strconv.ParseFloat(used, 64)
+	if err != nil {
+		log.Errorf("checkBorrowLimit, strconv.ParseFloat error: %s", err)
+		return
+	}
+	for _, v := range webhooks {
+		threshold, _ := parseThreshold(v.Threshold)
+		above := usedValue >= threshold
+		this.Lock()
+		crossed := above && !this.aboveThreshold[v.Id]
+		this.aboveThreshold[v.Id] = above
+		this.Unlock()
+		// fire once when crossing the threshold, not on every refresh while above it
+		if crossed {
+			this.fire(&v, &common.BorrowLimitAlert{
+				Address:         account,
+				BorrowLimitUsed: used,
+				Threshold:       v.Threshold,
+			})
+		}
+	}
+}
+
+func (this *WebhookManager) checkPriceMove(prices []*common.Price) {
+	for _, v := range this.Webhooks() {
+		if v.Trigger != common.WEBHOOK_TRIGGER_PRICE_MOVE {
+			continue
+		}
+		for _, p := range prices {
+			if !strings.EqualFold(p.Name, v.Asset) {
+				continue
+			}
+			price, err := strconv.ParseFloat(p.Price, 64)
+			if err != nil || price == 0 {
+				continue
+			}
+			this.Lock()
+			reference, ok := this.referencePrices[v.Id]
+			if !ok {
+				this.referencePrices[v.Id] = price
+				this.Unlock()
+				continue
+			}
+			threshold, _ := parseThreshold(v.Threshold)
+			change := (price - reference) / reference
+			moved := math.Abs(change) >= threshold
+			if moved {
+				this.referencePrices[v.Id] = price
+			}
+			this.Unlock()
+			if moved {
+				this.fire(&v, &common.PriceMoveAlert{
+					Asset:          p.Name,
+					Price:          p.Price,
+					ReferencePrice: strconv.FormatFloat(reference, 'f', -1, 64),
+					Change:         strconv.FormatFloat(change, 'f', 4, 64),
+					Threshold:      v.Threshold,
+				})
+			}
+		}
+	}
+}
+
+func (this *WebhookManager) checkLiquidation(events []*store.ProtocolEvent) {
+	for _, v := range this.Webhooks() {
+		if v.Trigger != common.WEBHOOK_TRIGGER_LIQUIDATION {
+			continue
+		}
+		for _, e := range events {
+			if e.Type != common.EVENT_TYPE_LIQUIDATION {
+				continue
+			}
+			if v.Asset != "" && !strings.EqualFold(v.Asset, e.Asset) {
+				continue
+			}
+			if v.Account != "" && v.Account != e.Account {
+				continue
+			}
+			this.fire(&v, e)
+		}
+	}
+}
+
+func (this *WebhookManager) fire(webhook *store.Webhook, data interface{}) {
+	deliveryId, err := randomHex(16)
+	if err != nil {
+		log.Errorf("fire, randomHex error: %s", err)
+		return
+	}
+	payload, err := json.Marshal(&common.WebhookPayload{
+		DeliveryId: deliveryId,
+		WebhookId:  webhook.Id,
+		Trigger:    webhook.Trigger,
+		Timestamp:  uint64(time.Now().Unix()),
+		Data:       data,
+	})
+	if err != nil {
+		log.Errorf("fire, json.Marshal error: %s", err)
+		return
+	}
+	this.dispatcher.Enqueue(&Delivery{
+		Id:        deliveryId,
+		WebhookId: webhook.Id,
+		Url:       webhook.Url,
+		Secret:    webhook.Secret,
+		Payload:   payload,
+	})
+}
+
+func (this *WebhookManager) saveDeadLetter(delivery *Delivery) {
+	err := this.store.SaveWebhookDeadLetter(&store.WebhookDeadLetter{
+		WebhookId:  delivery.WebhookId,
+		DeliveryId: delivery.Id,
+		Url:        delivery.Url,
+		Payload:    string(delivery.Payload),
+		Attempts:   delivery.Attempts,
+		LastError:  delivery.LastError,
+		CreateTime: uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		log.Errorf("saveDeadLetter, this.store.SaveWebhookDeadLetter error: %s", err)
+	}
+}
+
+// parseThreshold reads a fraction, 0.8 for 80% borrow limit used or 0.05 for a 5% price move
+func parseThreshold(threshold string) (float64, error) {
+	value, err := strconv.ParseFloat(threshold, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("parseThreshold, invalid threshold %s", threshold)
+	}
+	return value, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+const testAccount = "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+
+type fakeSource struct {
+	borrowLimitUsed string
+}
+
+func (this *fakeSource) LiquidationList(account string) ([]*common.Liquidation, error) {
+	return []*common.Liquidation{{BorrowLimitUsed: this.borrowLimitUsed}}, nil
+}
+
+type fakeStore struct {
+	webhooks    []store.Webhook
+	deadLetters []store.WebhookDeadLetter
+}
+
+func (this *fakeStore) LoadWebhooks() ([]store.Webhook, error) {
+	return this.webhooks, nil
+}
+
+func (this *fakeStore) SaveWebhook(webhook *store.Webhook) error {
+	webhook.Id = uint64(len(this.webhooks) + 1)
+	this.webhooks = append(this.webhooks, *webhook)
+	return nil
+}
+
+func (this *fakeStore) DeleteWebhook(id uint64) (bool, error) {
+	for i, v := range this.webhooks {
+		if v.Id == id {
+			this.webhooks = append(this.webhooks[:i], this.webhooks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *fakeStore) LoadWebhookDeadLetters(limit uint64) ([]store.WebhookDeadLetter, error) {
+	return this.deadLetters, nil
+}
+
+func (this *fakeStore) LoadWebhookDeadLetter(id uint64) (store.WebhookDeadLetter, error) {
+	for _, v := range this.deadLetters {
+		if v.Id == id {
+			return v, nil
+		}
+	}
+	return store.WebhookDeadLetter{}, fmt.Errorf("record not found")
+}
+
+func (this *fakeStore) SaveWebhookDeadLetter(deadLetter *store.WebhookDeadLetter) error {
+	this.deadLetters = append(this.deadLetters, *deadLetter)
+	return nil
+}
+
+func (this *fakeStore) DeleteWebhookDeadLetter(id uint64) error {
+	return nil
+}
+
+// newTestManager returns a manager whose dispatcher is not started, fired deliveries stay in its queue
+func newTestManager(t *testing.T, source LiquidationSource, webhooks ...store.Webhook) *WebhookManager {
+	manager := &WebhookManager{
+		cfg:             config.NewHolder(&config.Config{}),
+		store:           &fakeStore{webhooks: append([]store.Webhook{}, webhooks...)},
+		source:          source,
+		webhooks:        webhooks,
+		referencePrices: make(map[uint64]float64),
+		aboveThreshold:  make(map[uint64]bool),
+	}
+	manager.dispatcher = NewDispatcher(time.Second, 1, time.Second, func(d *Delivery) {
+		t.Errorf("delivery %s dead lettered: %s", d.Id, d.LastError)
+	})
+	return manager
+}
+
+// fired returns the data of the deliveries queued since the last call
+func fired(t *testing.T, manager *WebhookManager) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	for {
+		select {
+		case delivery := <-manager.dispatcher.queue:
+			payload := &common.WebhookPayload{}
+			if err := json.Unmarshal(delivery.Payload, payload); err != nil {
+				t.Fatalf("json.Unmarshal error: %s", err)
+			}
+			result = append(result, payload.Data.(map[string]interface{}))
+		default:
+			return result
+		}
+	}
+}
+
+func TestCheckBorrowLimit(t *testing.T) {
+	source := &fakeSource{}
+	manager := newTestManager(t, source, store.Webhook{Id: 1, Trigger: common.WEBHOOK_TRIGGER_BORROW_LIMIT,
+		Account: testAccount, Threshold: "0.8"})
+	// fires when crossing the threshold upwards only, once per crossing
+	for _, v := range []struct {
+		used  string
+		fires bool
+	}{
+		{"0.5", false},
+		{"0.85", true},
+		{"0.9", false},
+		{"0.8", false},
+		{"0.7", false},
+		{"0.8", true},
+	} {
+		source.borrowLimitUsed = v.used
+		manager.checkBorrowLimit(testAccount)
+		data := fired(t, manager)
+		if (len(data) == 1) != v.fires || len(data) > 1 {
+			t.Fatalf("borrow limit used %s: fired %d", v.used, len(data))
+		}
+		if v.fires && (data[0]["BorrowLimitUsed"] != v.used || data[0]["Address"] != testAccount) {
+			t.Errorf("unexpected alert %v", data[0])
+		}
+	}
+	manager.checkBorrowLimit("AQf4Mzu1YJrhz9f3aRkkwSm9n3qhXGSh4p")
+	if data := fired(t, manager); len(data) != 0 {
+		t.Errorf("fired for another account: %v", data)
+	}
+}
+
+func TestCheckPriceMove(t *testing.T) {
+	manager := newTestManager(t, nil, store.Webhook{Id: 1, Trigger: common.WEBHOOK_TRIGGER_PRICE_MOVE,
+		Asset: "pUSDC", Threshold: "0.05"})
+	// the first price is the reference, which is reset to the price of every alert
+	for _, v := range []struct {
+		price     string
+		reference string
+	}{
+		{"1", ""},
+		{"1.04", ""},
+		{"1.06", "1"},
+		{"1.08", ""},
+		{"1", "1.06"},
+		{"0.96", ""},
+		{"0.95", "1"},
+	} {
+		manager.checkPriceMove([]*common.Price{{Name: "pusdc", Price: v.price}, {Name: "pWBTC", Price: "2"}})
+		data := fired(t, manager)
+		if (len(data) == 1) != (v.reference != "") || len(data) > 1 {
+			t.Fatalf("price %s: fired %d", v.price, len(data))
+		}
+		if v.reference != "" && (data[0]["Price"] != v.price || data[0]["ReferencePrice"] != v.reference) {
+			t.Errorf("unexpected alert %v", data[0])
+		}
+	}
+}
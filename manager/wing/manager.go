@@ -0,0 +1,146 @@
+package wing
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/utils"
+)
+
+const (
+	DefaultHolderLimit = 100
+	MaxHolderLimit     = 1000
+)
+
+// Chain is implemented by the ontology sdk
+type Chain interface {
+	GetStorage(contractAddress string, key []byte) ([]byte, error)
+	GetCurrentBlockHeight() (uint32, error)
+}
+
+// Store is implemented by store.Client
+type Store interface {
+	LoadWingHolders(limit uint64) ([]store.WingHolder, error)
+	LoadWingHolderCount() (uint64, error)
+	SaveWingHolder(wingHolder *store.WingHolder) error
+}
+
+type WingManager struct {
+	cfg         *config.Holder
+	wingAddress string
+	sdk         Chain
+	store       Store
+}
+
+func NewWingManager(wingAddress string, sdk Chain, store Store, cfg *config.Holder) *WingManager {
+	manager := &WingManager{
+		cfg:         cfg,
+		wingAddress: wingAddress,
+		sdk:         sdk,
+		store:       store,
+	}
+
+	return manager
+}
+
+func (this *WingManager) WingSupply() (*common.WingSupply, error) {
+	cfg := this.cfg.Get()
+	totalSupply, err := this.getWingTotalSupply()
+	if err != nil {
+		return nil, fmt.Errorf("WingSupply, this.getWingTotalSupply error: %s", err)
+	}
+	locked := new(big.Int)
+	lockedAddress := make([]*common.WingHolder, 0)
+	for _, address := range cfg.WingLockAddress {
+		balance, err := this.getBalanceOf(address)
+		if err != nil {
+			return nil, fmt.Errorf("WingSupply, this.getBalanceOf %s error: %s", address, err)
+		}
+		locked = new(big.Int).Add(locked, balance)
+		lockedAddress = append(lockedAddress, &common.WingHolder{
+			Address: address,
+			Balance: utils.ToStringByPrecise(balance, cfg.TokenDecimal["WING"]),
+			Share: utils.ToStringByPrecise(share(balance, totalSupply, cfg.TokenDecimal["percentage"]),
+				cfg.TokenDecimal["percentage"]),
+		})
+	}
+	circulating := new(big.Int).Sub(totalSupply, locked)
+	if circulating.Sign() < 0 {
+		circulating = new(big.Int)
+	}
+	return &common.WingSupply{
+		TotalSupply:       utils.ToStringByPrecise(totalSupply, cfg.TokenDecimal["WING"]),
+		CirculatingSupply: utils.ToStringByPrecise(circulating, cfg.TokenDecimal["WING"]),
+		LockedSupply:      utils.ToStringByPrecise(locked, cfg.TokenDecimal["WING"]),
+		LockedAddress:     lockedAddress,
+	}, nil
+}
+
+func (this *WingManager) WingHolders(limit uint64) (*common.WingHolders, error) {
+	cfg := this.cfg.Get()
+	if limit == 0 {
+		limit = DefaultHolderLimit
+	}
+	if limit > MaxHolderLimit {
+		limit = MaxHolderLimit
+	}
+	totalSupply, err := this.getWingTotalSupply()
+	if err != nil {
+		return nil, fmt.Errorf("WingHolders, this.getWingTotalSupply error: %s", err)
+	}
+	holders, err := this.store.LoadWingHolders(limit)
+	if err != nil {
+		return nil, fmt.Errorf("WingHolders, this.store.LoadWingHolders error: %s", err)
+	}
+	count, err := this.store.LoadWingHolderCount()
+	if err != nil {
+		return nil, fmt.Errorf("WingHolders, this.store.LoadWingHolderCount error: %s", err)
+	}
+	wingHolders := &common.WingHolders{
+		HolderCount: count,
+		Holders:     make([]*common.WingHolder, 0),
+	}
+	for i, v := range holders {
+		balance, ok := new(big.Int).SetString(v.Balance, 10)
+		if !ok {
+			return nil, fmt.Errorf("WingHolders, invalid balance %s of %s", v.Balance, v.Address)
+		}
+		wingHolders.Holders = append(wingHolders.Holders, &common.WingHolder{
+			Rank:    uint64(i + 1),
+			Address: v.Address,
+			Balance: utils.ToStringByPrecise(balance, cfg.TokenDecimal["WING"]),
+			Share: utils.ToStringByPrecise(share(balance, totalSupply, cfg.TokenDecimal["percentage"]),
+				cfg.TokenDecimal["percentage"]),
+		})
+	}
+	return wingHolders, nil
+}
+
+// HolderBalanceForStore stores the balance of account after a transfer at height. The balance is read at the
+// chain tip, so it is stored with the tip height read before it, and an older read never replaces a newer one
+func (this *WingManager) HolderBalanceForStore(account string, height uint32) error {
+	currentHeight, err := this.sdk.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("HolderBalanceForStore, this.sdk.GetCurrentBlockHeight error: %s", err)
+	}
+	if currentHeight > height {
+		height = currentHeight
+	}
+	balance, err := this.getBalanceOf(account)
+	if err != nil {
+		return fmt.Errorf("HolderBalanceForStore, this.getBalanceOf error: %s", err)
+	}
+	wingHolder := &store.WingHolder{
+		Address: account,
+		Balance: balance.String(),
+		Height:  height,
+	}
+	err = this.store.SaveWingHolder(wingHolder)
+	if err != nil {
+		return fmt.Errorf("HolderBalanceForStore, this.store.SaveWingHolder error: %s", err)
+	}
+	return nil
+}
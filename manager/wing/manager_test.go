@@ -0,0 +1,113 @@
+package wing
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/store"
+)
+
+type fakeChain struct {
+	height  uint32
+	storage map[string][]byte
+}
+
+func (this *fakeChain) GetStorage(contractAddress string, key []byte) ([]byte, error) {
+	return this.storage[string(key)], nil
+}
+
+func (this *fakeChain) GetCurrentBlockHeight() (uint32, error) {
+	return this.height, nil
+}
+
+func (this *fakeChain) setBalance(t *testing.T, account string, balance *big.Int) {
+	address, err := common.AddressFromBase58(account)
+	if err != nil {
+		t.Fatalf("common.AddressFromBase58 error: %s", err)
+	}
+	this.storage[string(append([]byte{0x01}, address[:]...))] = common.BigIntToNeoBytes(balance)
+}
+
+type fakeStore struct {
+	holders []store.WingHolder
+	saved   []*store.WingHolder
+}
+
+func (this *fakeStore) LoadWingHolders(limit uint64) ([]store.WingHolder, error) {
+	return this.holders, nil
+}
+
+func (this *fakeStore) LoadWingHolderCount() (uint64, error) {
+	return uint64(len(this.holders)), nil
+}
+
+func (this *fakeStore) SaveWingHolder(wingHolder *store.WingHolder) error {
+	this.saved = append(this.saved, wingHolder)
+	return nil
+}
+
+const testAccount = "AUKZ3KL1FRRhgcijH6DBdBtswUdtmqL8Wo"
+
+func newTestManager(chain *fakeChain, store *fakeStore) *WingManager {
+	cfg := &config.Config{TokenDecimal: map[string]uint64{"WING": 9, "percentage": 4}}
+	return NewWingManager("wing", chain, store, config.NewHolder(cfg))
+}
+
+func TestHolderBalanceForStore(t *testing.T) {
+	chain := &fakeChain{height: 120, storage: make(map[string][]byte)}
+	// above the uint64 range
+	balance, _ := new(big.Int).SetString("123456789012345678901234", 10)
+	chain.setBalance(t, testAccount, balance)
+	fakeStore := &fakeStore{}
+	err := newTestManager(chain, fakeStore).HolderBalanceForStore(testAccount, 100)
+	if err != nil {
+		t.Fatalf("HolderBalanceForStore error: %s", err)
+	}
+	if len(fakeStore.saved) != 1 {
+		t.Fatalf("unexpected saved holders %v", fakeStore.saved)
+	}
+	saved := fakeStore.saved[0]
+	if saved.Address != testAccount || saved.Balance != balance.String() || saved.Height != 120 {
+		t.Errorf("unexpected saved holder %+v, the balance is read at the tip height", saved)
+	}
+}
+
+func TestWingHolders(t *testing.T) {
+	chain := &fakeChain{storage: map[string][]byte{
+		"TotalSupply": common.BigIntToNeoBytes(big.NewInt(10000000000000000)),
+	}}
+	fakeStore := &fakeStore{holders: []store.WingHolder{
+		{Address: testAccount, Balance: "2500000000000000"},
+		{Address: "AbtTQJYKfQxq4UdygDsbLVjE8uRrJ2H3tP", Balance: "1000000000"},
+	}}
+	holders, err := newTestManager(chain, fakeStore).WingHolders(0)
+	if err != nil {
+		t.Fatalf("WingHolders error: %s", err)
+	}
+	if holders.HolderCount != 2 || len(holders.Holders) != 2 {
+		t.Fatalf("unexpected holders %+v", holders)
+	}
+	first := holders.Holders[0]
+	if first.Rank != 1 || first.Balance != "2500000" || first.Share != "0.25" {
+		t.Errorf("unexpected holder %+v", first)
+	}
+	if second := holders.Holders[1]; second.Rank != 2 || second.Balance != "1" || second.Share != "0" {
+		t.Errorf("unexpected holder %+v", second)
+	}
+
+	fakeStore.holders = []store.WingHolder{{Address: testAccount, Balance: "1e9"}}
+	if _, err := newTestManager(chain, fakeStore).WingHolders(0); err == nil {
+		t.Errorf("a malformed balance should be an error")
+	}
+}
+
+func TestShare(t *testing.T) {
+	if v := share(big.NewInt(1), big.NewInt(0), 4); v.Sign() != 0 {
+		t.Errorf("share of an empty total should be zero, got %s", v)
+	}
+	if v := share(big.NewInt(1), big.NewInt(3), 4); v.Int64() != 3333 {
+		t.Errorf("unexpected share %s", v)
+	}
+}
@@ -0,0 +1,38 @@
+package wing
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ontio/ontology/common"
+)
+
+// get wing total supply
+func (this *WingManager) getWingTotalSupply() (*big.Int, error) {
+	r, err := this.sdk.GetStorage(this.wingAddress, []byte("TotalSupply"))
+	if err != nil {
+		return nil, fmt.Errorf("getWingTotalSupply, this.sdk.GetStorage error: %s", err)
+	}
+	return common.BigIntFromNeoBytes(r), nil
+}
+
+func (this *WingManager) getBalanceOf(accountStr string) (*big.Int, error) {
+	account, err := common.AddressFromBase58(accountStr)
+	if err != nil {
+		return nil, fmt.Errorf("getBalanceOf, common.AddressFromBase58 error: %s", err)
+	}
+	r, err := this.sdk.GetStorage(this.wingAddress, append([]byte{0x01}, account[:]...))
+	if err != nil {
+		return nil, fmt.Errorf("getBalanceOf, this.sdk.GetStorage error: %s", err)
+	}
+	return common.BigIntFromNeoBytes(r), nil
+}
+
+// share of amount in total, with precision percentage
+func share(amount, total *big.Int, percentage uint64) *big.Int {
+	if total.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(10),
+		new(big.Int).SetUint64(percentage), nil)), total)
+}
@@ -0,0 +1,225 @@
+// Package metrics holds the prometheus collectors of the server, served in the text format by Handler
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wing"
+
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, as registered like /api/v2/markets/:asset, method and status.",
+	}, []string{"route", "method", "status"})
+	HttpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	RpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "JSON-RPC requests to the node by rpc method, like getstorage, and result.",
+	}, []string{"method", "result"})
+	RpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "JSON-RPC request latency by rpc method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	ContractCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "contract_calls_total",
+		Help:      "Pre-executed contract methods, like getUnderlyingPrice, by result.",
+	}, []string{"contract_method", "result"})
+	ContractCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "contract_call_duration_seconds",
+		Help:      "Pre-executed contract method latency, the rpc round trip included.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"contract_method"})
+
+	IndexerHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexer_height",
+		Help:      "Last block parsed by the indexer.",
+	})
+	IndexerTip = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexer_tip",
+		Help:      "Current block height of the node.",
+	})
+	IndexerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexer_lag_blocks",
+		Help:      "Blocks the indexer is behind the node.",
+	})
+	Leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 while this replica holds the writer lease, or runs without leader election.",
+	})
+	QueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Background writes of the indexer still running.",
+	})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of the runs of the background jobs.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"job"})
+	JobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_failures_total",
+		Help:      "Failed runs of the background jobs.",
+	}, []string{"job"})
+	JobRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_restarts_total",
+		Help:      "Restarts of the supervised jobs after an error or a panic.",
+	}, []string{"job"})
+	JobLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of the background jobs.",
+	}, []string{"job"})
+
+	DbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database statement latency by operation.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation"})
+	DbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Failed database statements by operation, missing records excluded.",
+	}, []string{"operation"})
+
+	Tvl = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tvl_dollars",
+		Help:      "Supplied and insured value of all flash pool markets.",
+	})
+	TotalBorrow = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "borrow_dollars",
+		Help:      "Borrowed value of all flash pool markets.",
+	})
+	MarketSupply = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "market_supply_dollars",
+		Help:      "Supplied value of a flash pool market.",
+	}, []string{"market"})
+	MarketBorrow = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "market_borrow_dollars",
+		Help:      "Borrowed value of a flash pool market.",
+	}, []string{"market"})
+	MarketInsurance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "market_insurance_dollars",
+		Help:      "Insured value of a flash pool market.",
+	}, []string{"market"})
+	MarketSupplyApy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "market_supply_apy",
+		Help:      "Supply APY of a flash pool market, as a ratio.",
+	}, []string{"market"})
+	MarketBorrowApy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "market_borrow_apy",
+		Help:      "Borrow APY of a flash pool market, as a ratio.",
+	}, []string{"market"})
+)
+
+func init() {
+	prometheus.MustRegister(HttpRequests, HttpDuration, RpcRequests, RpcDuration, ContractCalls,
+		ContractCallDuration, IndexerHeight, IndexerTip, IndexerLag, Leader, QueueDepth, JobDuration,
+		JobFailures, JobRestarts, JobLastSuccess, DbDuration, DbErrors, Tvl, TotalBorrow, MarketSupply,
+		MarketBorrow, MarketInsurance, MarketSupplyApy, MarketBorrowApy)
+}
+
+// Handler serves the collectors of the default registry, the go runtime and process ones included
+func Handler(w http.ResponseWriter, r *http.Request) {
+	promhttp.Handler().ServeHTTP(w, r)
+}
+
+func ObserveHttp(route string, method string, status int, start time.Time) {
+	HttpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	HttpDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+}
+
+func ObserveRpc(method string, start time.Time, err error) {
+	observeRpcResult(method, start, err != nil)
+}
+
+func observeRpcResult(method string, start time.Time, failed bool) {
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+	RpcRequests.WithLabelValues(method, result).Inc()
+	RpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func ObserveContractCall(method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	ContractCalls.WithLabelValues(method, result).Inc()
+	ContractCallDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func ObserveJob(job string, start time.Time, err error) {
+	JobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if err != nil {
+		JobFailures.WithLabelValues(job).Inc()
+	} else {
+		JobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+}
+
+var indexer struct {
+	sync.Mutex
+	height uint32
+	tip    uint32
+}
+
+func SetIndexerHeight(height uint32) {
+	indexer.Lock()
+	defer indexer.Unlock()
+	indexer.height = height
+	setIndexer()
+}
+
+func SetIndexerTip(tip uint32) {
+	indexer.Lock()
+	defer indexer.Unlock()
+	indexer.tip = tip
+	setIndexer()
+}
+
+func setIndexer() {
+	IndexerHeight.Set(float64(indexer.height))
+	IndexerTip.Set(float64(indexer.tip))
+	var lag float64
+	if indexer.tip > indexer.height {
+		lag = float64(indexer.tip - indexer.height)
+	}
+	IndexerLag.Set(lag)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RpcTransport times the json rpc requests to the node by rpc method, set on the http client of the sdk it
+// observes every call of the sdk
+type RpcTransport struct {
+	Base http.RoundTripper
+}
+
+// NewRpcHttpClient returns an http client with the defaults of the sdk rpc client and an RpcTransport
+func NewRpcHttpClient() *http.Client {
+	return &http.Client{
+		Transport: &RpcTransport{
+			Base: &http.Transport{
+				MaxIdleConnsPerHost:   5,
+				IdleConnTimeout:       300 * time.Second,
+				ResponseHeaderTimeout: 300 * time.Second,
+			},
+		},
+		Timeout: 300 * time.Second,
+	}
+}
+
+func (this *RpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	method := rpcMethod(req)
+	resp, err := this.Base.RoundTrip(req)
+	if err != nil {
+		ObserveRpc(method, start, err)
+		return nil, err
+	}
+	// the node answers the failed calls with an error code in a 200 response
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		ObserveRpc(method, start, err)
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	rpcRsp := &struct {
+		Error int64 `json:"error"`
+	}{}
+	failed := resp.StatusCode != http.StatusOK || json.Unmarshal(data, rpcRsp) != nil || rpcRsp.Error != 0
+	observeRpcResult(method, start, failed)
+	return resp, nil
+}
+
+// rpcMethod reads the method of a json rpc request from a copy of its body
+func rpcMethod(req *http.Request) string {
+	if req.GetBody == nil {
+		return "unknown"
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "unknown"
+	}
+	defer body.Close()
+	rpcReq := &struct {
+		Method string `json:"method"`
+	}{}
+	if err := json.NewDecoder(body).Decode(rpcReq); err != nil || rpcReq.Method == "" {
+		return "unknown"
+	}
+	return rpcReq.Method
+}
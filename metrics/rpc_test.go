@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRpcTransport(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["method"] == "getstorage" {
+			w.Write([]byte(`{"desc":"INVALID PARAMS","error":42002,"result":""}`))
+			return
+		}
+		w.Write([]byte(`{"desc":"SUCCESS","error":0,"result":100}`))
+	}))
+	defer node.Close()
+	client := &http.Client{Transport: &RpcTransport{Base: http.DefaultTransport}}
+	ok := testutil.ToFloat64(RpcRequests.WithLabelValues("getblockcount", "ok"))
+	failed := testutil.ToFloat64(RpcRequests.WithLabelValues("getstorage", "error"))
+
+	for _, method := range []string{"getblockcount", "getstorage"} {
+		resp, err := client.Post(node.URL, "application/json",
+			bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":"1","method":"`+method+`","params":[]}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !bytes.Contains(body, []byte(`"result"`)) {
+			t.Errorf("%s: response body not passed on: %q", method, body)
+		}
+	}
+	if v := testutil.ToFloat64(RpcRequests.WithLabelValues("getblockcount", "ok")) - ok; v != 1 {
+		t.Errorf("unexpected ok count %v", v)
+	}
+	if v := testutil.ToFloat64(RpcRequests.WithLabelValues("getstorage", "error")) - failed; v != 1 {
+		t.Errorf("unexpected error count %v", v)
+	}
+}
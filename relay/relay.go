@@ -0,0 +1,160 @@
+// Package relay fans the notifications of the indexer out to the replicas which do not index, through the
+// database: the indexer stores them and the other replicas poll them and notify their streams and caches
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+	"github.com/siovanus/wingServer/supervisor"
+)
+
+const (
+	POLL_INTERVAL = time.Second
+	BATCH_SIZE    = 500
+	// notifications are only needed until the replicas polled them
+	RETENTION = time.Hour
+
+	JOB_PRUNE      = "prune_notifications"
+	JOB_PRUNE_SPEC = "@hourly"
+)
+
+// Store is implemented by store.Client
+type Store interface {
+	SaveNotification(notification *store.Notification) error
+	LoadNotifications(after uint64, limit int) ([]store.Notification, error)
+	LastNotificationId() (uint64, error)
+	PruneNotifications(before time.Time) (int64, error)
+}
+
+// Notifier is implemented by the streams and the response cache
+type Notifier interface {
+	Notify(topic string, data interface{})
+}
+
+type Relay struct {
+	store   Store
+	leading func() bool
+	// notifications are saved one at a time so that their ids are committed in order
+	lock      sync.Mutex
+	notifiers []Notifier
+	now       func() time.Time
+}
+
+// NewRelay creates a relay, leading tells whether this replica indexes and notifies its notifiers itself
+func NewRelay(store Store, leading func() bool) *Relay {
+	return &Relay{store: store, leading: leading, now: time.Now}
+}
+
+// AddNotifier adds a notifier told about the notifications of another replica, before Run
+func (this *Relay) AddNotifier(notifier Notifier) {
+	this.notifiers = append(this.notifiers, notifier)
+}
+
+// Notify stores a notification of the indexer for the other replicas
+func (this *Relay) Notify(topic string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("Relay.Notify, json.Marshal %s error: %s", topic, err)
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	err = this.store.SaveNotification(&store.Notification{Topic: topic, Data: string(encoded)})
+	if err != nil {
+		log.Errorf("Relay.Notify, this.store.SaveNotification %s error: %s", topic, err)
+	}
+}
+
+// Run polls the notifications stored after its start until ctx is done and passes them to the notifiers,
+// except while this replica leads
+func (this *Relay) Run(ctx context.Context) error {
+	cursor, err := this.store.LastNotificationId()
+	if err != nil {
+		return fmt.Errorf("Relay.Run, this.store.LastNotificationId error: %s", err)
+	}
+	for supervisor.Sleep(ctx, POLL_INTERVAL) {
+		if this.leading() {
+			last, err := this.store.LastNotificationId()
+			if err != nil {
+				log.Errorf("Relay.Run, this.store.LastNotificationId error: %s", err)
+				continue
+			}
+			cursor = last
+			continue
+		}
+		cursor = this.poll(cursor)
+	}
+	return nil
+}
+
+// poll notifies the notifications after cursor and returns the new cursor
+func (this *Relay) poll(cursor uint64) uint64 {
+	for {
+		notifications, err := this.store.LoadNotifications(cursor, BATCH_SIZE)
+		if err != nil {
+			log.Errorf("Relay.poll, this.store.LoadNotifications error: %s", err)
+			return cursor
+		}
+		for _, v := range notifications {
+			cursor = v.ID
+			data, err := decode(v.Topic, []byte(v.Data))
+			if err != nil {
+				log.Warnf("Relay.poll, notification %d: %s", v.ID, err)
+				continue
+			}
+			for _, notifier := range this.notifiers {
+				notifier.Notify(v.Topic, data)
+			}
+		}
+		if len(notifications) < BATCH_SIZE {
+			return cursor
+		}
+	}
+}
+
+// Prune removes the notifications older than RETENTION, run by the scheduler
+func (this *Relay) Prune(ctx context.Context) error {
+	pruned, err := this.store.PruneNotifications(this.now().Add(-RETENTION))
+	if err != nil {
+		return fmt.Errorf("Relay.Prune, this.store.PruneNotifications error: %s", err)
+	}
+	log.FromContext(ctx).Debugf("relay: %d notifications removed", pruned)
+	return nil
+}
+
+// decode returns the data of a notification as the type notified by the service for its topic
+func decode(topic string, data []byte) (interface{}, error) {
+	var v interface{}
+	switch topic {
+	case common.TOPIC_EVENT:
+		v = &[]*store.ProtocolEvent{}
+	case common.TOPIC_PRICE:
+		v = &[]*common.Price{}
+	case common.TOPIC_ACCOUNT:
+		v = &common.AccountUpdate{}
+	case common.TOPIC_MARKET:
+		v = &common.FlashPoolAllMarket{}
+	case common.TOPIC_FLASHPOOL:
+		v = &store.FlashPoolDetail{}
+	default:
+		return nil, fmt.Errorf("unknown topic %s", topic)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal %s error: %s", topic, err)
+	}
+	// the service notifies the lists as slices
+	switch list := v.(type) {
+	case *[]*store.ProtocolEvent:
+		return *list, nil
+	case *[]*common.Price:
+		return *list, nil
+	}
+	return v, nil
+}
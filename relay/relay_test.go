@@ -0,0 +1,97 @@
+package relay
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/store"
+)
+
+type fakeStore struct {
+	sync.Mutex
+	notifications []store.Notification
+}
+
+func (this *fakeStore) SaveNotification(notification *store.Notification) error {
+	this.Lock()
+	defer this.Unlock()
+	notification.ID = uint64(len(this.notifications) + 1)
+	notification.CreatedAt = time.Now()
+	this.notifications = append(this.notifications, *notification)
+	return nil
+}
+
+func (this *fakeStore) LoadNotifications(after uint64, limit int) ([]store.Notification, error) {
+	this.Lock()
+	defer this.Unlock()
+	result := make([]store.Notification, 0)
+	for _, v := range this.notifications {
+		if v.ID > after && len(result) < limit {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (this *fakeStore) LastNotificationId() (uint64, error) {
+	this.Lock()
+	defer this.Unlock()
+	return uint64(len(this.notifications)), nil
+}
+
+func (this *fakeStore) PruneNotifications(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type notification struct {
+	topic string
+	data  interface{}
+}
+
+type fakeNotifier chan notification
+
+func (this fakeNotifier) Notify(topic string, data interface{}) {
+	this <- notification{topic, data}
+}
+
+func TestRelay(t *testing.T) {
+	db := &fakeStore{}
+	leader := NewRelay(db, func() bool { return true })
+	// published before the follower started, not relayed
+	leader.Notify(common.TOPIC_PRICE, []*common.Price{{Name: "pUSDT", Price: "1"}})
+
+	follower := NewRelay(db, func() bool { return false })
+	notifier := make(fakeNotifier, 10)
+	follower.AddNotifier(notifier)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go follower.Run(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	events := []*store.ProtocolEvent{{Height: 10, LogIndex: 1, Type: "supply", Asset: "pUSDT", Amount: "5"}}
+	update := &common.AccountUpdate{Address: "AXk", Positions: []*common.Position{{Name: "pUSDT"}}}
+	leader.Notify(common.TOPIC_EVENT, events)
+	leader.Notify(common.TOPIC_ACCOUNT, update)
+
+	expected := []notification{{common.TOPIC_EVENT, events}, {common.TOPIC_ACCOUNT, update}}
+	for _, v := range expected {
+		select {
+		case n := <-notifier:
+			if n.topic != v.topic || !reflect.DeepEqual(n.data, v.data) {
+				t.Errorf("unexpected notification %s %+v, expected %s %+v", n.topic, n.data, v.topic, v.data)
+			}
+		case <-time.After(3 * POLL_INTERVAL):
+			t.Fatalf("%s was not relayed", v.topic)
+		}
+	}
+}
+
+func TestDecodeUnknownTopic(t *testing.T) {
+	if _, err := decode("unknown", []byte("{}")); err == nil {
+		t.Errorf("unknown topic should fail")
+	}
+}
@@ -0,0 +1,321 @@
+// Package scheduler runs registered jobs on cron or interval specs evaluated in UTC. A job never overlaps
+// with itself, scheduled runs are delayed by a random jitter and every run is stored in the job history
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/http/common"
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/store"
+)
+
+const (
+	TRIGGER_SCHEDULE = "schedule"
+	TRIGGER_MANUAL   = "manual"
+	TRIGGER_START    = "start"
+
+	// JOB_PRUNE_HISTORY removes the runs beyond the history retention of the config
+	JOB_PRUNE_HISTORY      = "prune_job_runs"
+	JOB_PRUNE_HISTORY_SPEC = "@daily"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrRunning    = errors.New("job is already running")
+	ErrNotRunning = errors.New("scheduler is not running on this replica")
+)
+
+// Func is the work of a job, ctx is done when the scheduler stops
+type Func func(ctx context.Context) error
+
+// History stores the runs of the jobs, implemented by store.Client
+type History interface {
+	SaveJobRun(jobRun *store.JobRun) error
+	LoadJobRuns(job string, limit int) ([]store.JobRun, error)
+	PruneJobRuns(before uint64) (int64, error)
+}
+
+type job struct {
+	name       string
+	spec       string
+	schedule   cron.Schedule
+	jitter     time.Duration
+	disabled   bool
+	runOnStart bool
+	run        Func
+
+	running int32
+	trigger chan struct{}
+
+	lock    sync.Mutex
+	next    time.Time
+	lastRun *store.JobRun
+}
+
+type Scheduler struct {
+	cfg     *config.SchedulerConfig
+	history History
+
+	lock    sync.RWMutex
+	jobs    map[string]*job
+	running int32
+	now     func() time.Time
+}
+
+// NewScheduler creates a scheduler whose job specs are overridden by cfg, which may be nil
+func NewScheduler(cfg *config.SchedulerConfig, history History) *Scheduler {
+	return &Scheduler{
+		cfg:     cfg,
+		history: history,
+		jobs:    make(map[string]*job),
+		now:     time.Now,
+	}
+}
+
+// ParseSpec parses a cron expression of five fields or a descriptor like @daily or @every 30s, the time
+// zone is always UTC so TZ prefixes are refused
+func ParseSpec(spec string) (cron.Schedule, error) {
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		return nil, fmt.Errorf("ParseSpec, spec %q: time zones are not supported, specs are in UTC", spec)
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("ParseSpec, spec %q: %s", spec, err)
+	}
+	return schedule, nil
+}
+
+// Register adds a job running on spec unless the config of the job overrides it. Jobs have to be
+// registered before Run
+func (this *Scheduler) Register(name string, spec string, run Func) error {
+	j := &job{name: name, spec: spec, run: run, trigger: make(chan struct{}, 1)}
+	if this.cfg != nil {
+		if jobCfg, ok := this.cfg.Jobs[name]; ok && jobCfg != nil {
+			if jobCfg.Spec != "" {
+				j.spec = jobCfg.Spec
+			}
+			j.jitter = time.Duration(jobCfg.Jitter) * time.Second
+			j.disabled = jobCfg.Disabled
+			j.runOnStart = jobCfg.RunOnStart
+		}
+	}
+	schedule, err := ParseSpec(j.spec)
+	if err != nil {
+		return fmt.Errorf("Register, job %s: %s", name, err)
+	}
+	j.schedule = schedule
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, ok := this.jobs[name]; ok {
+		return fmt.Errorf("Register, job %s is already registered", name)
+	}
+	this.jobs[name] = j
+	return nil
+}
+
+// RegisterHistoryPruning adds the job removing the runs older than the history retention
+func (this *Scheduler) RegisterHistoryPruning() error {
+	days := uint64(config.DEFAULT_JOB_HISTORY_DAYS)
+	if this.cfg != nil && this.cfg.HistoryDays != 0 {
+		days = this.cfg.HistoryDays
+	}
+	return this.Register(JOB_PRUNE_HISTORY, JOB_PRUNE_HISTORY_SPEC, func(ctx context.Context) error {
+		if this.history == nil {
+			return nil
+		}
+		before := this.now().Add(-time.Duration(days) * 24 * time.Hour)
+		pruned, err := this.history.PruneJobRuns(uint64(before.Unix()))
+		if err != nil {
+			return fmt.Errorf("this.history.PruneJobRuns error: %s", err)
+		}
+		log.FromContext(ctx).Infof("scheduler: %d job runs older than %d days removed", pruned, days)
+		return nil
+	})
+}
+
+// Run schedules the jobs until ctx is done and then waits for the running ones
+func (this *Scheduler) Run(ctx context.Context) error {
+	this.lock.RLock()
+	jobs := make([]*job, 0, len(this.jobs))
+	for _, v := range this.jobs {
+		jobs = append(jobs, v)
+	}
+	this.lock.RUnlock()
+
+	atomic.StoreInt32(&this.running, 1)
+	defer atomic.StoreInt32(&this.running, 0)
+	var wg sync.WaitGroup
+	for _, v := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			this.loop(ctx, j)
+		}(v)
+	}
+	wg.Wait()
+	return nil
+}
+
+// loop runs the runs of one job one after the other, so a job never overlaps with itself. Scheduled runs
+// falling due while the job runs are recorded as skipped
+func (this *Scheduler) loop(ctx context.Context, j *job) {
+	if j.runOnStart {
+		this.start(ctx, j, TRIGGER_START)
+	}
+	for {
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !j.disabled {
+			now := this.now().UTC()
+			next := j.schedule.Next(now)
+			if j.jitter > 0 {
+				next = next.Add(time.Duration(rand.Int63n(int64(j.jitter))))
+			}
+			j.lock.Lock()
+			j.next = next
+			j.lock.Unlock()
+			timer = time.NewTimer(next.Sub(now))
+			due = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-due:
+			this.start(ctx, j, TRIGGER_SCHEDULE)
+		case <-j.trigger:
+			if timer != nil {
+				timer.Stop()
+			}
+			this.start(ctx, j, TRIGGER_MANUAL)
+		}
+	}
+}
+
+// start runs j and records the run, followed by a skipped run if the schedule fell due meanwhile
+func (this *Scheduler) start(ctx context.Context, j *job, trigger string) {
+	start := this.now()
+	jobRun := &store.JobRun{Job: j.name, Trigger: trigger, StartTime: uint64(start.Unix())}
+	atomic.StoreInt32(&j.running, 1)
+	defer atomic.StoreInt32(&j.running, 0)
+	jobLog := log.WithFields(log.Fields{log.FIELD_ACTION: j.name})
+	jobLog.Infof("scheduler: job %s started, trigger %s", j.name, trigger)
+	err := run(log.NewContext(ctx, jobLog), j.run)
+	jobRun.Duration = this.now().Sub(start).Seconds()
+	if err != nil {
+		jobRun.Error = err.Error()
+		jobLog.Errorf("scheduler: job %s failed after %.3fs: %s", j.name, jobRun.Duration, err)
+	} else {
+		jobLog.Infof("scheduler: job %s done in %.3fs", j.name, jobRun.Duration)
+	}
+	this.save(j, jobRun)
+
+	if missed := j.schedule.Next(start.UTC()); !j.disabled && missed.Before(this.now()) {
+		jobLog.Warnf("scheduler: job %s was still running at %s, scheduled run skipped", j.name, missed)
+		this.save(j, &store.JobRun{Job: j.name, Trigger: TRIGGER_SCHEDULE, StartTime: uint64(missed.Unix()),
+			Skipped: true})
+	}
+}
+
+func (this *Scheduler) save(j *job, jobRun *store.JobRun) {
+	if !jobRun.Skipped {
+		j.lock.Lock()
+		j.lastRun = jobRun
+		j.lock.Unlock()
+	}
+	if this.history == nil {
+		return
+	}
+	if err := this.history.SaveJobRun(jobRun); err != nil {
+		log.WithFields(log.Fields{log.FIELD_ACTION: j.name}).Errorf("scheduler: job %s, this.history.SaveJobRun error: %s", j.name, err)
+	}
+}
+
+func run(ctx context.Context, f Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return f(ctx)
+}
+
+// Trigger runs a job now, outside of its schedule. The run is asynchronous, its outcome is in the history
+func (this *Scheduler) Trigger(name string) error {
+	this.lock.RLock()
+	j, ok := this.jobs[name]
+	this.lock.RUnlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if atomic.LoadInt32(&this.running) == 0 {
+		return ErrNotRunning
+	}
+	if atomic.LoadInt32(&j.running) == 1 {
+		return ErrRunning
+	}
+	select {
+	case j.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrRunning
+	}
+}
+
+// Jobs lists the registered jobs sorted by name
+func (this *Scheduler) Jobs() []*common.ScheduledJob {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	jobs := make([]*common.ScheduledJob, 0, len(this.jobs))
+	for _, v := range this.jobs {
+		scheduled := &common.ScheduledJob{
+			Name:     v.name,
+			Spec:     v.spec,
+			Jitter:   uint64(v.jitter.Seconds()),
+			Disabled: v.disabled,
+			Running:  atomic.LoadInt32(&v.running) == 1,
+		}
+		v.lock.Lock()
+		if !v.next.IsZero() && !v.disabled {
+			scheduled.NextRun = uint64(v.next.Unix())
+		}
+		if v.lastRun != nil {
+			scheduled.LastRun = v.lastRun.StartTime
+			scheduled.LastDuration = v.lastRun.Duration
+			scheduled.LastError = v.lastRun.Error
+		}
+		v.lock.Unlock()
+		jobs = append(jobs, scheduled)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Runs returns the last runs of a job, newest first
+func (this *Scheduler) Runs(name string, limit int) ([]store.JobRun, error) {
+	this.lock.RLock()
+	_, ok := this.jobs[name]
+	this.lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	if this.history == nil {
+		return []store.JobRun{}, nil
+	}
+	return this.history.LoadJobRuns(name, limit)
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siovanus/wingServer/config"
+	"github.com/siovanus/wingServer/store"
+)
+
+type fakeHistory struct {
+	sync.Mutex
+	runs []store.JobRun
+}
+
+func (this *fakeHistory) SaveJobRun(jobRun *store.JobRun) error {
+	this.Lock()
+	defer this.Unlock()
+	this.runs = append(this.runs, *jobRun)
+	return nil
+}
+
+func (this *fakeHistory) LoadJobRuns(job string, limit int) ([]store.JobRun, error) {
+	this.Lock()
+	defer this.Unlock()
+	return append([]store.JobRun{}, this.runs...), nil
+}
+
+func (this *fakeHistory) PruneJobRuns(before uint64) (int64, error) {
+	this.Lock()
+	defer this.Unlock()
+	kept := make([]store.JobRun, 0, len(this.runs))
+	for _, v := range this.runs {
+		if v.StartTime >= before {
+			kept = append(kept, v)
+		}
+	}
+	pruned := int64(len(this.runs) - len(kept))
+	this.runs = kept
+	return pruned, nil
+}
+
+func TestParseSpec(t *testing.T) {
+	schedule, err := ParseSpec("0 0 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := schedule.Next(time.Date(2020, 10, 1, 15, 30, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2020, 10, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next run %s", next)
+	}
+	if _, err := ParseSpec("@every 30s"); err != nil {
+		t.Error(err)
+	}
+	for _, spec := range []string{"CRON_TZ=Asia/Shanghai 0 0 * * *", "0 0 * *", "@sometimes"} {
+		if _, err := ParseSpec(spec); err == nil {
+			t.Errorf("spec %q accepted", spec)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	cfg := &config.SchedulerConfig{Jobs: map[string]*config.JobConfig{
+		"snapshot": {Spec: "@hourly", Jitter: 10, Disabled: true},
+	}}
+	s := NewScheduler(cfg, nil)
+	noop := func(ctx context.Context) error { return nil }
+	if err := s.Register("snapshot", "@daily", noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("snapshot", "@daily", noop); err == nil {
+		t.Error("job registered twice")
+	}
+	if err := s.Register("broken", "every day", noop); err == nil {
+		t.Error("invalid spec accepted")
+	}
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].Spec != "@hourly" || jobs[0].Jitter != 10 || !jobs[0].Disabled {
+		t.Errorf("config not applied: %+v", jobs[0])
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	history := &fakeHistory{}
+	cfg := &config.SchedulerConfig{Jobs: map[string]*config.JobConfig{"job": {Disabled: true}}}
+	s := NewScheduler(cfg, history)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	s.Register("job", "@daily", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		return errors.New("failed")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	if err := s.Trigger("unknown"); err != ErrUnknownJob {
+		t.Errorf("unexpected error %v", err)
+	}
+	err := s.Trigger("job")
+	for i := 0; err == ErrNotRunning && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+		err = s.Trigger("job")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := s.Trigger("job"); err != ErrRunning {
+		t.Errorf("overlapping trigger answered %v", err)
+	}
+	close(release)
+	cancel()
+	<-done
+
+	if err := s.Trigger("job"); err != ErrNotRunning {
+		t.Errorf("trigger of a stopped scheduler answered %v", err)
+	}
+
+	runs, _ := s.Runs("job", 10)
+	if len(runs) != 1 || runs[0].Trigger != TRIGGER_MANUAL || runs[0].Error != "failed" || runs[0].Skipped {
+		t.Errorf("unexpected history %+v", runs)
+	}
+	if jobs := s.Jobs(); jobs[0].LastError != "failed" || jobs[0].Running {
+		t.Errorf("unexpected job %+v", jobs[0])
+	}
+}
+
+func TestSkippedRun(t *testing.T) {
+	history := &fakeHistory{}
+	s := NewScheduler(nil, history)
+	start := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	s.now = func() time.Time {
+		calls++
+		if calls == 1 {
+			return start
+		}
+		return start.Add(90 * time.Second)
+	}
+	s.Register("slow", "@every 1m", func(ctx context.Context) error { return nil })
+	s.start(context.Background(), s.jobs["slow"], TRIGGER_SCHEDULE)
+
+	if len(history.runs) != 2 || history.runs[0].Duration != 90 || !history.runs[1].Skipped ||
+		history.runs[1].StartTime != uint64(start.Add(time.Minute).Unix()) {
+		t.Errorf("unexpected history %+v", history.runs)
+	}
+}
+
+func TestHistoryPruning(t *testing.T) {
+	now := time.Date(2020, 10, 10, 0, 0, 0, 0, time.UTC)
+	history := &fakeHistory{runs: []store.JobRun{
+		{Job: "job", StartTime: uint64(now.Add(-3 * 24 * time.Hour).Unix())},
+		{Job: "job", StartTime: uint64(now.Add(-24 * time.Hour).Unix())},
+	}}
+	s := NewScheduler(&config.SchedulerConfig{HistoryDays: 2}, history)
+	s.now = func() time.Time { return now }
+	if err := s.RegisterHistoryPruning(); err != nil {
+		t.Fatal(err)
+	}
+	s.start(context.Background(), s.jobs[JOB_PRUNE_HISTORY], TRIGGER_MANUAL)
+
+	if len(history.runs) != 2 || history.runs[0].Job != "job" || history.runs[1].Job != JOB_PRUNE_HISTORY ||
+		history.runs[1].Error != "" {
+		t.Errorf("unexpected history %+v", history.runs)
+	}
+}
@@ -3,9 +3,13 @@ package store
 
 import (
 	"bytes"
+	"database/sql"
 	"database/sql/driver"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/siovanus/wingServer/http/common"
@@ -16,6 +20,9 @@ const (
 	sqlDialect = "postgres"
 )
 
+// ErrLeaseLost is returned by the writes fenced by a lease which the writer no longer holds
+var ErrLeaseLost = errors.New("lease lost")
+
 // SQLStringArray is a string array stored in the database as comma separated values.
 type SQLStringArray []string
 
@@ -70,12 +77,18 @@ func ConnectToDb(uri string) (*Client, error) {
 	if err = migrations.Migrate(db); err != nil {
 		return nil, fmt.Errorf("newDBStore#Migrate: %s", err)
 	}
+	registerMetrics(db)
 	store := &Client{
 		db: db.Set("gorm:auto_preload", true),
 	}
 	return store, nil
 }
 
+// Ping checks the connection to the database.
+func (client Client) Ping() error {
+	return client.db.DB().Ping()
+}
+
 // Close will close the connection to the database.
 func (client Client) Close() error {
 	return client.db.Close()
@@ -117,9 +130,11 @@ func (client Client) SaveFlashPoolMarket(flashPoolMarket *FlashPoolMarket) error
 	return client.db.Create(flashPoolMarket).Error
 }
 
+// Price is saved by the price feed, which sets UpdatedAt
 type Price struct {
-	Name  string `gorm:"primary_key"`
-	Price string
+	Name      string `gorm:"primary_key"`
+	Price     string
+	UpdatedAt time.Time
 }
 
 func (client Client) LoadPrice(name string) (Price, error) {
@@ -132,6 +147,25 @@ func (client Client) SavePrice(Price *Price) error {
 	return client.db.Save(Price).Error
 }
 
+// PricePoint is the price of an asset read from the oracle after the update in TxHash at Height
+type PricePoint struct {
+	Name   string `gorm:"primary_key"`
+	Height uint32 `gorm:"primary_key;auto_increment:false"`
+	TxHash string
+	Price  string
+}
+
+// LoadPricePoints returns the latest price points of an asset, newest first
+func (client Client) LoadPricePoints(name string, limit uint64) ([]PricePoint, error) {
+	pricePoints := make([]PricePoint, 0)
+	err := client.db.Where("name = ?", name).Order("height desc").Limit(limit).Find(&pricePoints).Error
+	return pricePoints, err
+}
+
+func (client Client) SavePricePoint(pricePoint *PricePoint) error {
+	return client.db.Save(pricePoint).Error
+}
+
 type TrackHeight struct {
 	Name   string `gorm:"primary_key"`
 	Height uint32
@@ -151,6 +185,23 @@ func (client Client) SaveTrackHeight(height uint32) error {
 	return client.db.Save(trackHeight).Error
 }
 
+// SaveTrackHeightFenced saves the track height only while holder holds the unexpired lease name. The lease
+// is locked until the height is saved, so that no other replica takes it over in between
+func (client Client) SaveTrackHeightFenced(height uint32, name string, holder string) error {
+	return client.db.Transaction(func(tx *gorm.DB) error {
+		var held string
+		err := tx.Raw("SELECT holder FROM leases WHERE name = ? AND holder = ? AND expires_at > now() FOR SHARE",
+			name, holder).Row().Scan(&held)
+		if err == sql.ErrNoRows {
+			return ErrLeaseLost
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Save(&TrackHeight{Name: "TrackHeight", Height: height}).Error
+	})
+}
+
 type UserAssetBalance struct {
 	UserAddress      string `gorm:"primary_key"`
 	AssetName        string `gorm:"primary_key"`
@@ -209,3 +260,341 @@ func (client Client) LoadWingApy(assetName string) (common.WingApy, error) {
 func (client Client) SaveWingApy(wingApy *common.WingApy) error {
 	return client.db.Save(wingApy).Error
 }
+
+type WingHolder struct {
+	Address string `gorm:"primary_key"`
+	Balance string `gorm:"type:numeric"`
+	Height  uint32
+}
+
+func (client Client) LoadWingHolders(limit uint64) ([]WingHolder, error) {
+	wingHolders := make([]WingHolder, 0)
+	err := client.db.Where("balance > ?", 0).Order("balance desc").Limit(limit).Find(&wingHolders).Error
+	return wingHolders, err
+}
+
+func (client Client) LoadWingHolderCount() (uint64, error) {
+	var count uint64
+	err := client.db.Model(&WingHolder{}).Where("balance > ?", 0).Count(&count).Error
+	return count, err
+}
+
+// SaveWingHolder stores the balance of a holder unless a balance read at the same or a later height is stored
+func (client Client) SaveWingHolder(wingHolder *WingHolder) error {
+	return client.db.Exec("INSERT INTO wing_holders (address, balance, height) VALUES (?, ?, ?) "+
+		"ON CONFLICT (address) DO UPDATE SET balance = excluded.balance, height = excluded.height "+
+		"WHERE wing_holders.height < excluded.height",
+		wingHolder.Address, wingHolder.Balance, wingHolder.Height).Error
+}
+
+type WingTransfer struct {
+	TxHash      string `gorm:"primary_key"`
+	EventIndex  uint32 `gorm:"primary_key;auto_increment:false"`
+	Height      uint32
+	FromAddress string
+	ToAddress   string
+	Amount      string
+}
+
+func (client Client) SaveWingTransfer(wingTransfer *WingTransfer) error {
+	return client.db.Save(wingTransfer).Error
+}
+
+type WingClaim struct {
+	TxHash      string `gorm:"primary_key"`
+	EventIndex  uint32 `gorm:"primary_key;auto_increment:false"`
+	UserAddress string
+	Height      uint32
+	Timestamp   uint32
+	Amount      string `gorm:"type:numeric"`
+}
+
+func (client Client) LoadWingClaims(userAddress string, offset, limit uint64) ([]WingClaim, error) {
+	wingClaims := make([]WingClaim, 0)
+	err := client.db.Where("user_address = ?", userAddress).Order("height desc").Offset(offset).
+		Limit(limit).Find(&wingClaims).Error
+	return wingClaims, err
+}
+
+func (client Client) LoadWingClaimCount(userAddress string) (uint64, error) {
+	var count uint64
+	err := client.db.Model(&WingClaim{}).Where("user_address = ?", userAddress).Count(&count).Error
+	return count, err
+}
+
+func (client Client) LoadWingClaimedAmount(userAddress string) (string, error) {
+	var amount string
+	err := client.db.Model(&WingClaim{}).Where("user_address = ?", userAddress).
+		Select("coalesce(sum(amount), 0)").Row().Scan(&amount)
+	return amount, err
+}
+
+func (client Client) SaveWingClaim(wingClaim *WingClaim) error {
+	return client.db.Save(wingClaim).Error
+}
+
+type GovProposal struct {
+	ProposalId   string `gorm:"primary_key"`
+	Proposer     string
+	Title        string
+	Description  string
+	StartHeight  uint32
+	EndHeight    uint32
+	Status       string
+	CreateHeight uint32
+	CreateTxHash string
+	UpdateHeight uint32
+	UpdateTxHash string
+}
+
+func (client Client) LoadGovProposals(status string, offset, limit uint64) ([]GovProposal, uint64, error) {
+	govProposals := make([]GovProposal, 0)
+	var count uint64
+	db := client.db.Model(&GovProposal{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Count(&count).Error
+	if err != nil {
+		return govProposals, count, err
+	}
+	err = db.Order("create_height desc").Offset(offset).Limit(limit).Find(&govProposals).Error
+	return govProposals, count, err
+}
+
+func (client Client) LoadGovProposal(proposalId string) (GovProposal, error) {
+	var govProposal GovProposal
+	err := client.db.Where("proposal_id = ?", proposalId).First(&govProposal).Error
+	return govProposal, err
+}
+
+func (client Client) SaveGovProposal(govProposal *GovProposal) error {
+	return client.db.Save(govProposal).Error
+}
+
+func (client Client) UpdateGovProposalStatus(proposalId, status string, height uint32, txHash string) error {
+	return client.db.Model(&GovProposal{}).Where("proposal_id = ?", proposalId).Updates(map[string]interface{}{
+		"status":         status,
+		"update_height":  height,
+		"update_tx_hash": txHash,
+	}).Error
+}
+
+type GovVote struct {
+	ProposalId string `gorm:"primary_key"`
+	Voter      string `gorm:"primary_key"`
+	Option     string
+	Weight     string
+	Height     uint32
+	TxHash     string
+}
+
+func (client Client) LoadGovVotes(proposalId string) ([]GovVote, error) {
+	govVotes := make([]GovVote, 0)
+	err := client.db.Where("proposal_id = ?", proposalId).Order("height asc").Find(&govVotes).Error
+	return govVotes, err
+}
+
+func (client Client) SaveGovVote(govVote *GovVote) error {
+	return client.db.Save(govVote).Error
+}
+
+type ProtocolEvent struct {
+	Height       uint32 `gorm:"primary_key;auto_increment:false"`
+	LogIndex     uint32 `gorm:"primary_key;auto_increment:false"`
+	TxHash       string
+	Type         string
+	Asset        string
+	Account      string
+	Counterparty string
+	Amount       string
+}
+
+// LoadProtocolEvents returns events strictly after the given height and log index in chain order
+func (client Client) LoadProtocolEvents(height, logIndex uint32, limit uint64) ([]ProtocolEvent, error) {
+	protocolEvents := make([]ProtocolEvent, 0)
+	err := client.db.Where("height > ? or (height = ? and log_index > ?)", height, height, logIndex).
+		Order("height asc, log_index asc").Limit(limit).Find(&protocolEvents).Error
+	return protocolEvents, err
+}
+
+func (client Client) SaveProtocolEvent(protocolEvent *ProtocolEvent) error {
+	return client.db.Save(protocolEvent).Error
+}
+
+type Webhook struct {
+	Id         uint64 `gorm:"primary_key"`
+	Url        string
+	Secret     string
+	Trigger    string
+	Account    string
+	Asset      string
+	Threshold  string
+	CreateTime uint64
+}
+
+func (client Client) LoadWebhooks() ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	err := client.db.Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (client Client) SaveWebhook(webhook *Webhook) error {
+	return client.db.Save(webhook).Error
+}
+
+func (client Client) DeleteWebhook(id uint64) (bool, error) {
+	db := client.db.Where("id = ?", id).Delete(&Webhook{})
+	return db.RowsAffected != 0, db.Error
+}
+
+type WebhookDeadLetter struct {
+	Id         uint64 `gorm:"primary_key"`
+	WebhookId  uint64
+	DeliveryId string
+	Url        string
+	Payload    string
+	Attempts   uint32
+	LastError  string
+	CreateTime uint64
+}
+
+func (client Client) LoadWebhookDeadLetters(limit uint64) ([]WebhookDeadLetter, error) {
+	deadLetters := make([]WebhookDeadLetter, 0)
+	err := client.db.Order("id desc").Limit(limit).Find(&deadLetters).Error
+	return deadLetters, err
+}
+
+func (client Client) LoadWebhookDeadLetter(id uint64) (WebhookDeadLetter, error) {
+	var deadLetter WebhookDeadLetter
+	err := client.db.Where("id = ?", id).First(&deadLetter).Error
+	return deadLetter, err
+}
+
+func (client Client) SaveWebhookDeadLetter(deadLetter *WebhookDeadLetter) error {
+	return client.db.Save(deadLetter).Error
+}
+
+func (client Client) DeleteWebhookDeadLetter(id uint64) error {
+	return client.db.Where("id = ?", id).Delete(&WebhookDeadLetter{}).Error
+}
+
+// ApiKey is an api key of the restful server, only the sha256 hash of the key is stored
+type ApiKey struct {
+	Hash       string `gorm:"primary_key"`
+	Name       string
+	CreateTime uint64
+}
+
+func (client Client) LoadApiKey(hash string) (ApiKey, error) {
+	var apiKey ApiKey
+	err := client.db.Where("hash = ?", hash).First(&apiKey).Error
+	return apiKey, err
+}
+
+func (client Client) LoadApiKeys() ([]ApiKey, error) {
+	apiKeys := make([]ApiKey, 0)
+	err := client.db.Order("create_time asc").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (client Client) SaveApiKey(apiKey *ApiKey) error {
+	return client.db.Create(apiKey).Error
+}
+
+func (client Client) DeleteApiKey(name string) (bool, error) {
+	db := client.db.Where("name = ?", name).Delete(&ApiKey{})
+	return db.RowsAffected != 0, db.Error
+}
+
+// JobRun is a run of a scheduler job, the start time is in unix seconds and the duration in seconds.
+// Skipped runs were due while the previous one was still running
+type JobRun struct {
+	ID        uint64
+	Job       string
+	Trigger   string
+	StartTime uint64
+	Duration  float64
+	Error     string
+	Skipped   bool
+}
+
+func (client Client) SaveJobRun(jobRun *JobRun) error {
+	return client.db.Create(jobRun).Error
+}
+
+// PruneJobRuns removes the runs started before the unix time before, it returns the number of runs removed
+func (client Client) PruneJobRuns(before uint64) (int64, error) {
+	db := client.db.Where("start_time < ?", before).Delete(&JobRun{})
+	return db.RowsAffected, db.Error
+}
+
+// LoadJobRuns returns the last runs of job, newest first
+func (client Client) LoadJobRuns(job string, limit int) ([]JobRun, error) {
+	jobRuns := make([]JobRun, 0)
+	err := client.db.Where("job = ?", job).Order("id desc").Limit(limit).Find(&jobRuns).Error
+	return jobRuns, err
+}
+
+// Lease is held by the leader of the replicas until it expires, times are those of the database so that
+// the clocks of the replicas do not matter
+type Lease struct {
+	Name      string `gorm:"primary_key"`
+	Holder    string
+	ExpiresAt time.Time
+}
+
+// AcquireLease takes or renews the lease name for duration, it reports whether holder holds it
+func (client Client) AcquireLease(name string, holder string, duration time.Duration) (bool, error) {
+	db := client.db.Exec(`INSERT INTO leases (name, holder, expires_at)
+VALUES (?, ?, now() + ? * interval '1 millisecond')
+ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+WHERE leases.holder = excluded.holder OR leases.expires_at < now()`, name, holder, duration.Milliseconds())
+	return db.RowsAffected != 0, db.Error
+}
+
+// ReleaseLease gives the lease up if holder holds it, so that another replica takes it at once
+func (client Client) ReleaseLease(name string, holder string) error {
+	return client.db.Where("name = ? AND holder = ?", name, holder).Delete(&Lease{}).Error
+}
+
+func (client Client) LoadLease(name string) (Lease, error) {
+	var lease Lease
+	err := client.db.Where("name = ?", name).First(&lease).Error
+	return lease, err
+}
+
+// Notification is a notification of the indexer relayed to the other replicas, data is its json encoding
+type Notification struct {
+	ID        uint64
+	Topic     string
+	Data      string
+	CreatedAt time.Time
+}
+
+func (client Client) SaveNotification(notification *Notification) error {
+	return client.db.Create(notification).Error
+}
+
+// LoadNotifications returns the notifications after the id after, oldest first
+func (client Client) LoadNotifications(after uint64, limit int) ([]Notification, error) {
+	notifications := make([]Notification, 0)
+	err := client.db.Where("id > ?", after).Order("id asc").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (client Client) LastNotificationId() (uint64, error) {
+	var id uint64
+	err := client.db.Model(&Notification{}).Select("coalesce(max(id), 0)").Row().Scan(&id)
+	return id, err
+}
+
+// PruneNotifications removes the notifications created before before, it returns the number removed
+func (client Client) PruneNotifications(before time.Time) (int64, error) {
+	db := client.db.Where("created_at < ?", before).Delete(&Notification{})
+	return db.RowsAffected, db.Error
+}
+
+func IsRecordNotFound(err error) bool {
+	return gorm.IsRecordNotFoundError(err)
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/siovanus/wingServer/metrics"
+)
+
+const metricsStartKey = "metrics:start"
+
+// registerMetrics times every statement of db in the database latency histogram of its operation
+func registerMetrics(db *gorm.DB) {
+	callback := db.Callback()
+	callback.Create().Before("gorm:begin_transaction").Register("metrics:before_create", startTimer)
+	callback.Create().Register("metrics:after_create", observe("create"))
+	callback.Update().Before("gorm:begin_transaction").Register("metrics:before_update", startTimer)
+	callback.Update().Register("metrics:after_update", observe("update"))
+	callback.Delete().Before("gorm:begin_transaction").Register("metrics:before_delete", startTimer)
+	callback.Delete().Register("metrics:after_delete", observe("delete"))
+	callback.Query().Before("gorm:query").Register("metrics:before_query", startTimer)
+	callback.Query().Register("metrics:after_query", observe("query"))
+	callback.RowQuery().Before("gorm:row_query").Register("metrics:before_row_query", startTimer)
+	callback.RowQuery().Register("metrics:after_row_query", observe("row_query"))
+}
+
+func startTimer(scope *gorm.Scope) {
+	scope.InstanceSet(metricsStartKey, time.Now())
+}
+
+func observe(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		v, ok := scope.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		metrics.DbDuration.WithLabelValues(operation).Observe(time.Since(v.(time.Time)).Seconds())
+		if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+			metrics.DbErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
@@ -4,6 +4,19 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 	"github.com/siovanus/wingServer/store/migrations/migration0"
+	"github.com/siovanus/wingServer/store/migrations/migration1"
+	"github.com/siovanus/wingServer/store/migrations/migration10"
+	"github.com/siovanus/wingServer/store/migrations/migration11"
+	"github.com/siovanus/wingServer/store/migrations/migration12"
+	"github.com/siovanus/wingServer/store/migrations/migration13"
+	"github.com/siovanus/wingServer/store/migrations/migration2"
+	"github.com/siovanus/wingServer/store/migrations/migration3"
+	"github.com/siovanus/wingServer/store/migrations/migration4"
+	"github.com/siovanus/wingServer/store/migrations/migration5"
+	"github.com/siovanus/wingServer/store/migrations/migration6"
+	"github.com/siovanus/wingServer/store/migrations/migration7"
+	"github.com/siovanus/wingServer/store/migrations/migration8"
+	"github.com/siovanus/wingServer/store/migrations/migration9"
 	"gopkg.in/gormigrate.v1"
 )
 
@@ -18,6 +31,58 @@ func Migrate(db *gorm.DB) error {
 			ID:      "0",
 			Migrate: migration0.Migrate,
 		},
+		{
+			ID:      "1",
+			Migrate: migration1.Migrate,
+		},
+		{
+			ID:      "2",
+			Migrate: migration2.Migrate,
+		},
+		{
+			ID:      "3",
+			Migrate: migration3.Migrate,
+		},
+		{
+			ID:      "4",
+			Migrate: migration4.Migrate,
+		},
+		{
+			ID:      "5",
+			Migrate: migration5.Migrate,
+		},
+		{
+			ID:      "6",
+			Migrate: migration6.Migrate,
+		},
+		{
+			ID:      "7",
+			Migrate: migration7.Migrate,
+		},
+		{
+			ID:      "8",
+			Migrate: migration8.Migrate,
+		},
+		{
+			ID:      "9",
+			Migrate: migration9.Migrate,
+		},
+		{
+			ID:      "10",
+			Migrate: migration10.Migrate,
+		},
+		{
+			ID:      "11",
+			Migrate: migration11.Migrate,
+		},
+		{
+			ID:      "12",
+			Migrate: migration12.Migrate,
+		},
+		{
+			ID:      "13",
+			Migrate: migration13.Migrate,
+		},
 	}
 
 	m := gormigrate.New(db, &options, migrations)
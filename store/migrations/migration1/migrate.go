@@ -0,0 +1,36 @@
+package migration1
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type WingHolder struct {
+	Address string `gorm:"primary_key"`
+	Balance uint64 `gorm:"index"`
+	Height  uint32
+}
+
+type WingTransfer struct {
+	TxHash      string `gorm:"primary_key"`
+	EventIndex  uint32 `gorm:"primary_key;auto_increment:false"`
+	Height      uint32 `gorm:"index"`
+	FromAddress string `gorm:"index"`
+	ToAddress   string `gorm:"index"`
+	Amount      string
+}
+
+// Migrate adds the wing token tables
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(WingHolder{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate WingHolder")
+	}
+
+	err = tx.AutoMigrate(WingTransfer{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate WingTransfer")
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package migration10
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type Notification struct {
+	ID        uint64
+	Topic     string
+	Data      string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// Migrate adds the notifications of the indexer relayed to the other replicas
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(Notification{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate Notification")
+	}
+
+	return nil
+}
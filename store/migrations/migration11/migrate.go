@@ -0,0 +1,16 @@
+package migration11
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Migrate stores the wing holder balances as decimals, they do not fit in a bigint
+func Migrate(tx *gorm.DB) error {
+	err := tx.Exec("ALTER TABLE wing_holders ALTER COLUMN balance TYPE numeric USING balance::numeric").Error
+	if err != nil {
+		return errors.Wrap(err, "failed to alter wing_holders balance")
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package migration12
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Migrate stores the wing claim amounts as decimals, they do not fit in a bigint
+func Migrate(tx *gorm.DB) error {
+	err := tx.Exec("ALTER TABLE wing_claims ALTER COLUMN amount TYPE numeric USING amount::numeric").Error
+	if err != nil {
+		return errors.Wrap(err, "failed to alter wing_claims amount")
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package migration13
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type PricePoint struct {
+	Name   string `gorm:"primary_key"`
+	Height uint32 `gorm:"primary_key;auto_increment:false"`
+	TxHash string
+	Price  string
+}
+
+// Migrate stores the prices read from the oracle after each of its updates, the price protocol events
+// decoded from the oracle notification are dropped
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(PricePoint{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate PricePoint")
+	}
+	err = tx.Exec("DELETE FROM protocol_events WHERE type = 'price'").Error
+	if err != nil {
+		return errors.Wrap(err, "failed to delete price protocol events")
+	}
+
+	return nil
+}
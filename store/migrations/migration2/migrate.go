@@ -0,0 +1,25 @@
+package migration2
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type WingClaim struct {
+	TxHash      string `gorm:"primary_key"`
+	EventIndex  uint32 `gorm:"primary_key;auto_increment:false"`
+	UserAddress string `gorm:"index"`
+	Height      uint32
+	Timestamp   uint32
+	Amount      uint64
+}
+
+// Migrate adds the wing claim history table
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(WingClaim{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate WingClaim")
+	}
+
+	return nil
+}
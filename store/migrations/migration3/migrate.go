@@ -0,0 +1,44 @@
+package migration3
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type GovProposal struct {
+	ProposalId   string `gorm:"primary_key"`
+	Proposer     string
+	Title        string
+	Description  string
+	StartHeight  uint32
+	EndHeight    uint32
+	Status       string `gorm:"index"`
+	CreateHeight uint32
+	CreateTxHash string
+	UpdateHeight uint32
+	UpdateTxHash string
+}
+
+type GovVote struct {
+	ProposalId string `gorm:"primary_key"`
+	Voter      string `gorm:"primary_key"`
+	Option     string
+	Weight     string
+	Height     uint32
+	TxHash     string
+}
+
+// Migrate adds the governance proposal and vote tables
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(GovProposal{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate GovProposal")
+	}
+
+	err = tx.AutoMigrate(GovVote{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate GovVote")
+	}
+
+	return nil
+}
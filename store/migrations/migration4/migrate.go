@@ -0,0 +1,27 @@
+package migration4
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type ProtocolEvent struct {
+	Height       uint32 `gorm:"primary_key;auto_increment:false"`
+	LogIndex     uint32 `gorm:"primary_key;auto_increment:false"`
+	TxHash       string
+	Type         string `gorm:"index"`
+	Asset        string
+	Account      string `gorm:"index"`
+	Counterparty string
+	Amount       string
+}
+
+// Migrate adds the decoded protocol event table
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(ProtocolEvent{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate ProtocolEvent")
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package migration5
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type Webhook struct {
+	Id         uint64 `gorm:"primary_key"`
+	Url        string
+	Secret     string
+	Trigger    string
+	Account    string
+	Asset      string
+	Threshold  string
+	CreateTime uint64
+}
+
+type WebhookDeadLetter struct {
+	Id         uint64 `gorm:"primary_key"`
+	WebhookId  uint64 `gorm:"index"`
+	DeliveryId string
+	Url        string
+	Payload    string `gorm:"type:text"`
+	Attempts   uint32
+	LastError  string `gorm:"type:text"`
+	CreateTime uint64
+}
+
+// Migrate adds the webhook registration and dead-letter tables
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(Webhook{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate Webhook")
+	}
+
+	err = tx.AutoMigrate(WebhookDeadLetter{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate WebhookDeadLetter")
+	}
+
+	return nil
+}
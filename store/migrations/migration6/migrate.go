@@ -0,0 +1,22 @@
+package migration6
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type ApiKey struct {
+	Hash       string `gorm:"primary_key"`
+	Name       string `gorm:"unique_index"`
+	CreateTime uint64
+}
+
+// Migrate adds the api key table
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(ApiKey{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate ApiKey")
+	}
+
+	return nil
+}
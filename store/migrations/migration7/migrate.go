@@ -0,0 +1,26 @@
+package migration7
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type JobRun struct {
+	ID        uint64
+	Job       string `gorm:"index"`
+	Trigger   string
+	StartTime uint64
+	Duration  float64
+	Error     string
+	Skipped   bool
+}
+
+// Migrate adds the job run history of the scheduler
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(JobRun{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate JobRun")
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package migration8
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type Lease struct {
+	Name      string `gorm:"primary_key"`
+	Holder    string
+	ExpiresAt time.Time
+}
+
+// Migrate adds the lease table of the leader election
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(Lease{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate Lease")
+	}
+
+	return nil
+}
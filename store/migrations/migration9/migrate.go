@@ -0,0 +1,24 @@
+package migration9
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type Price struct {
+	Name      string `gorm:"primary_key"`
+	Price     string
+	UpdatedAt time.Time
+}
+
+// Migrate adds the time of the last update of each price, which tells whether the prices are fresh
+func Migrate(tx *gorm.DB) error {
+	err := tx.AutoMigrate(Price{}).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to auto migrate Price")
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package supervisor runs the background jobs of the server under one context, restarting failed jobs
+// with an exponential backoff until it is stopped
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/siovanus/wingServer/log"
+	"github.com/siovanus/wingServer/metrics"
+)
+
+const (
+	MIN_BACKOFF = time.Second
+	MAX_BACKOFF = time.Minute
+)
+
+// Job runs until ctx is done. Returning nil ends the job, returning an error or panicking restarts it
+type Job func(ctx context.Context) error
+
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func NewSupervisor(parent context.Context) *Supervisor {
+	ctx, cancel := context.WithCancel(parent)
+	return &Supervisor{
+		ctx:        ctx,
+		cancel:     cancel,
+		minBackoff: MIN_BACKOFF,
+		maxBackoff: MAX_BACKOFF,
+	}
+}
+
+// Context is done once Stop is called
+func (this *Supervisor) Context() context.Context {
+	return this.ctx
+}
+
+// Go starts job, the backoff doubles after each failure and is reset once the job ran longer than the
+// maximum backoff
+func (this *Supervisor) Go(name string, job Job) {
+	this.wg.Add(1)
+	go func() {
+		defer this.wg.Done()
+		jobLog := log.WithFields(log.Fields{log.FIELD_ACTION: name})
+		ctx := log.NewContext(this.ctx, jobLog)
+		backoff := this.minBackoff
+		for {
+			start := time.Now()
+			err := run(ctx, job)
+			if this.ctx.Err() != nil {
+				jobLog.Infof("supervisor: job %s stopped", name)
+				return
+			}
+			if err == nil {
+				jobLog.Infof("supervisor: job %s done", name)
+				return
+			}
+			if time.Since(start) > this.maxBackoff {
+				backoff = this.minBackoff
+			}
+			metrics.JobRestarts.WithLabelValues(name).Inc()
+			jobLog.Errorf("supervisor: job %s failed, restart in %s: %s", name, backoff, err)
+			select {
+			case <-this.ctx.Done():
+				jobLog.Infof("supervisor: job %s stopped", name)
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > this.maxBackoff {
+				backoff = this.maxBackoff
+			}
+		}
+	}()
+}
+
+func run(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return job(ctx)
+}
+
+// Stop cancels the context of the jobs and waits at most timeout for them to return, it reports
+// whether all of them did
+func (this *Supervisor) Stop(timeout time.Duration) bool {
+	this.cancel()
+	return WaitTimeout(&this.wg, timeout)
+}
+
+// WaitTimeout waits for wg at most timeout, it reports whether wg was done
+func WaitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Sleep waits for d or until ctx is done, it reports whether the full duration passed
+func Sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
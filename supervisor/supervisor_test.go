@@ -0,0 +1,68 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSupervisor() *Supervisor {
+	s := NewSupervisor(context.Background())
+	s.minBackoff = time.Millisecond
+	s.maxBackoff = 4 * time.Millisecond
+	return s
+}
+
+func TestRestart(t *testing.T) {
+	s := newTestSupervisor()
+	var runs int32
+	done := make(chan struct{})
+	s.Go("test", func(ctx context.Context) error {
+		switch atomic.AddInt32(&runs, 1) {
+		case 1:
+			return errors.New("failed")
+		case 2:
+			panic("broken")
+		default:
+			close(done)
+			return nil
+		}
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("job not restarted, %d runs", atomic.LoadInt32(&runs))
+	}
+	if !s.Stop(time.Second) {
+		t.Fatal("job not stopped")
+	}
+	if runs := atomic.LoadInt32(&runs); runs != 3 {
+		t.Errorf("job ran %d times, expected 3", runs)
+	}
+}
+
+func TestStop(t *testing.T) {
+	s := newTestSupervisor()
+	started := make(chan struct{})
+	s.Go("loop", func(ctx context.Context) error {
+		close(started)
+		for Sleep(ctx, time.Hour) {
+		}
+		return nil
+	})
+	<-started
+	if !s.Stop(time.Second) {
+		t.Fatal("job not stopped")
+	}
+
+	s = newTestSupervisor()
+	s.Go("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	if s.Stop(10 * time.Millisecond) {
+		t.Error("stuck job reported as stopped")
+	}
+}
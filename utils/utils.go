@@ -1,11 +1,13 @@
 package utils
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"strings"
 
+	ocommon "github.com/ontio/ontology/common"
 	"github.com/siovanus/wingServer/http/common"
 )
 
@@ -113,3 +115,39 @@ func ToIntByPrecise(str string, precise uint64) *big.Int {
 
 	return result
 }
+
+// ParseAmount parses a number from notify states. Wasm contracts notify numbers as decimal strings through
+// the cross vm codec and neovm contracts as hex encoded byte arrays, neovm tells which vm notified
+func ParseAmount(state interface{}, neovm bool) (*big.Int, error) {
+	switch v := state.(type) {
+	case string:
+		if !neovm {
+			amount, ok := new(big.Int).SetString(v, 10)
+			if !ok {
+				return nil, fmt.Errorf("ParseAmount, invalid amount %s", v)
+			}
+			return amount, nil
+		}
+		data, err := hex.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("ParseAmount, invalid amount %s", v)
+		}
+		return ocommon.BigIntFromNeoBytes(data), nil
+	case float64:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	default:
+		return nil, fmt.Errorf("ParseAmount, unsupported amount type %T", state)
+	}
+}
+
+// ParseString returns notify state as string, numbers are formatted in decimal
+func ParseString(state interface{}) string {
+	switch v := state.(type) {
+	case string:
+		return v
+	case float64:
+		return new(big.Float).SetFloat64(v).Text('f', -1)
+	default:
+		return fmt.Sprint(state)
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		state    interface{}
+		neovm    bool
+		expected string
+	}{
+		{"10", false, "10"},
+		{"10", true, "16"},
+		{"e803", true, "1000"},
+		{float64(42), false, "42"},
+	}
+	for _, v := range cases {
+		amount, err := ParseAmount(v.state, v.neovm)
+		if err != nil || amount.String() != v.expected {
+			t.Errorf("ParseAmount(%v, %v) = %v, %v, expected %s", v.state, v.neovm, amount, err, v.expected)
+		}
+	}
+	if _, err := ParseAmount("e803", false); err == nil {
+		t.Errorf("hex amount of a wasm contract should fail")
+	}
+}